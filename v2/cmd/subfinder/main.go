@@ -1,6 +1,11 @@
 package main
 
 import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
 	"github.com/projectdiscovery/subfinder/v2/pkg/runner"
 	// Attempts to increase the OS file descriptors - Fail silently
 	_ "github.com/projectdiscovery/fdmax/autofdmax"
@@ -16,7 +21,19 @@ func main() {
 		gologger.Fatal().Msgf("Could not create runner: %s\n", err)
 	}
 
-	err = newRunner.RunEnumeration()
+	if options.CheckKeys {
+		if err := newRunner.CheckKeys(context.Background()); err != nil {
+			gologger.Fatal().Msgf("Could not validate keys: %s\n", err)
+		}
+		return
+	}
+
+	// An interrupted run cancels the sources, the budget usage and the resume
+	// state are saved before exiting
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err = newRunner.RunEnumerationWithCtx(ctx)
 	if err != nil {
 		gologger.Fatal().Msgf("Could not run enumeration: %s\n", err)
 	}
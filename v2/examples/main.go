@@ -18,6 +18,7 @@ func main() {
 		// callback function executed after each unique subdomain is found
 		// },
 		// ProviderConfig: "your_provider_config.yaml",
+		// CustomSources: []subscraping.Source{&yourSource{}}, // sources added to the built-in ones, selected and keyed like them
 		// and other config related options
 	}
 
@@ -0,0 +1,75 @@
+package passive
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
+)
+
+// CircuitBreaker disables the sources failing on consecutive domains for
+// the rest of a multi-domain run. A source failing with an authentication
+// error is disabled right away. It is safe for concurrent use.
+type CircuitBreaker struct {
+	threshold int
+
+	mutex    sync.Mutex
+	failures map[string]int
+	tripped  map[string]string
+}
+
+// NewCircuitBreaker creates a circuit breaker disabling a source after
+// threshold consecutive failed domains
+func NewCircuitBreaker(threshold int) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: threshold,
+		failures:  make(map[string]int),
+		tripped:   make(map[string]string),
+	}
+}
+
+// Tripped returns the reason a source was disabled, if it was
+func (c *CircuitBreaker) Tripped(source string) (string, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	reason, ok := c.tripped[source]
+	return reason, ok
+}
+
+// record updates the state of a source with the outcome of its run on a domain
+// and returns the reason the source was disabled, if this run disabled it.
+// A run failed when it returned errors but no results.
+func (c *CircuitBreaker) record(source string, results int, errs []error) string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, ok := c.tripped[source]; ok {
+		return ""
+	}
+	if results > 0 || len(errs) == 0 {
+		c.failures[source] = 0
+		return ""
+	}
+	c.failures[source]++
+
+	var reason string
+	for _, err := range errs {
+		var statusErr *subscraping.StatusCodeError
+		if errors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusUnauthorized || statusErr.StatusCode == http.StatusForbidden) {
+			reason = fmt.Sprintf("authentication failed with status code %d", statusErr.StatusCode)
+			break
+		}
+	}
+	if reason == "" && c.threshold > 0 && c.failures[source] >= c.threshold {
+		reason = fmt.Sprintf("failed on %d consecutive domains, last error: %s", c.failures[source], errs[len(errs)-1])
+	}
+	if reason != "" {
+		c.tripped[source] = reason
+		gologger.Warning().Msgf("Disabling source %s for the remaining domains: %s\n", source, reason)
+	}
+	return reason
+}
@@ -0,0 +1,56 @@
+package passive
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/projectdiscovery/ratelimit"
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
+)
+
+// CheckKeys checks the API keys of the sources able to, keyed by source
+// name. Sources without keys are left out. Only the cassette and transport
+// enumeration options are taken into account.
+func CheckKeys(ctx context.Context, proxy string, timeout int, options ...EnumerateOption) (map[string][]subscraping.KeyCheck, error) {
+	return CheckSourceKeys(ctx, Sources(), proxy, timeout, options...)
+}
+
+// CheckSourceKeys is CheckKeys for the given sources
+func CheckSourceKeys(ctx context.Context, sources []subscraping.Source, proxy string, timeout int, options ...EnumerateOption) (map[string][]subscraping.KeyCheck, error) {
+	var enumerateOptions EnumerationOptions
+	for _, enumerateOption := range options {
+		enumerateOption(&enumerateOptions)
+	}
+
+	var multiRateLimiter *ratelimit.MultiLimiter
+	var err error
+	for _, source := range sources {
+		multiRateLimiter, err = addRateLimiter(ctx, multiRateLimiter, source.Name(), math.MaxUint32, time.Millisecond)
+		if err != nil {
+			return nil, err
+		}
+	}
+	session, err := subscraping.NewSession("", proxy, multiRateLimiter, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+	session.Cassette = enumerateOptions.cassette
+	if enumerateOptions.transport != nil {
+		session.Client.Transport = enumerateOptions.transport
+	}
+
+	checks := make(map[string][]subscraping.KeyCheck)
+	for _, source := range sources {
+		checker, ok := source.(subscraping.KeyChecker)
+		if !ok || !source.NeedsKey() {
+			continue
+		}
+		ctxWithValue := context.WithValue(ctx, subscraping.CtxSourceArg, source.Name())
+		if sourceChecks := checker.CheckKeys(ctxWithValue, session); len(sourceChecks) > 0 {
+			checks[source.Name()] = sourceChecks
+		}
+	}
+	return checks, nil
+}
@@ -2,19 +2,31 @@ package passive
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
-	"sort"
+	"net/http"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/projectdiscovery/gologger"
 	"github.com/projectdiscovery/ratelimit"
 	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
 )
 
 type EnumerationOptions struct {
 	customRateLimiter *subscraping.CustomRateLimit
+	multiRateLimiter  *ratelimit.MultiLimiter
+	statistics        *subscraping.RunStatistics
+	sourceTimeouts    map[string]time.Duration
+	retryPolicy       subscraping.RetryPolicy
+	sourceRetries     map[string]subscraping.RetryPolicy
+	circuitBreaker    *CircuitBreaker
+	responseCache     *subscraping.ResponseCache
+	cassette          *subscraping.Cassette
+	transport         http.RoundTripper
+	budgets           *subscraping.Budgets
 }
 
 type EnumerateOption func(opts *EnumerationOptions)
@@ -25,6 +37,77 @@ func WithCustomRateLimit(crl *subscraping.CustomRateLimit) EnumerateOption {
 	}
 }
 
+// WithMultiRateLimiter makes the enumeration use an already built rate limiter,
+// so that per-source limits are shared across concurrent enumerations. The
+// limiter is owned by the caller and is not stopped when the enumeration ends.
+func WithMultiRateLimiter(mrl *ratelimit.MultiLimiter) EnumerateOption {
+	return func(opts *EnumerationOptions) {
+		opts.multiRateLimiter = mrl
+	}
+}
+
+// WithStatistics collects the statistics of the enumeration run into stats,
+// which can be read once the results channel has been closed.
+func WithStatistics(stats *subscraping.RunStatistics) EnumerateOption {
+	return func(opts *EnumerationOptions) {
+		opts.statistics = stats
+	}
+}
+
+// WithSourceTimeouts sets a deadline per source name. A source reaching its
+// deadline is cancelled and its statistics are marked as timed out.
+func WithSourceTimeouts(timeouts map[string]time.Duration) EnumerateOption {
+	return func(opts *EnumerationOptions) {
+		opts.sourceTimeouts = timeouts
+	}
+}
+
+// WithRetryPolicy sets how transient request failures are retried, with
+// optional overrides per source name
+func WithRetryPolicy(policy subscraping.RetryPolicy, sourcePolicies map[string]subscraping.RetryPolicy) EnumerateOption {
+	return func(opts *EnumerationOptions) {
+		opts.retryPolicy = policy
+		opts.sourceRetries = sourcePolicies
+	}
+}
+
+// WithCircuitBreaker makes the enumeration skip the sources disabled by the
+// circuit breaker and record the outcome of the others into it
+func WithCircuitBreaker(cb *CircuitBreaker) EnumerateOption {
+	return func(opts *EnumerationOptions) {
+		opts.circuitBreaker = cb
+	}
+}
+
+// WithResponseCache makes the sources with a cache TTL reuse the responses stored on disk
+func WithResponseCache(cache *subscraping.ResponseCache) EnumerateOption {
+	return func(opts *EnumerationOptions) {
+		opts.responseCache = cache
+	}
+}
+
+// WithCassette makes the sources record their HTTP interactions to, or replay them from, a cassette
+func WithCassette(cassette *subscraping.Cassette) EnumerateOption {
+	return func(opts *EnumerationOptions) {
+		opts.cassette = cassette
+	}
+}
+
+// WithTransport makes the sources send their HTTP requests through the given round tripper
+func WithTransport(transport http.RoundTripper) EnumerateOption {
+	return func(opts *EnumerationOptions) {
+		opts.transport = transport
+	}
+}
+
+// WithBudgets makes the sources stop sending requests once they, or their keys,
+// reached their budget. The sources having reached it are skipped.
+func WithBudgets(budgets *subscraping.Budgets) EnumerateOption {
+	return func(opts *EnumerationOptions) {
+		opts.budgets = budgets
+	}
+}
+
 // EnumerateSubdomains wraps EnumerateSubdomainsWithCtx with an empty context
 func (a *Agent) EnumerateSubdomains(domain string, proxy string, rateLimit int, timeout int, maxEnumTime time.Duration, options ...EnumerateOption) chan subscraping.Result {
 	return a.EnumerateSubdomainsWithCtx(context.Background(), domain, proxy, rateLimit, timeout, maxEnumTime, options...)
@@ -42,12 +125,17 @@ func (a *Agent) EnumerateSubdomainsWithCtx(ctx context.Context, domain string, p
 			enumerateOption(&enumerateOptions)
 		}
 
-		multiRateLimiter, err := a.buildMultiRateLimiter(ctx, rateLimit, enumerateOptions.customRateLimiter)
-		if err != nil {
-			results <- subscraping.Result{
-				Type: subscraping.Error, Error: fmt.Errorf("could not init multi rate limiter for %s: %s", domain, err),
+		multiRateLimiter := enumerateOptions.multiRateLimiter
+		sharedRateLimiter := multiRateLimiter != nil
+		if !sharedRateLimiter {
+			var err error
+			multiRateLimiter, err = a.buildMultiRateLimiter(ctx, rateLimit, enumerateOptions.customRateLimiter)
+			if err != nil {
+				results <- subscraping.Result{
+					Type: subscraping.Error, Error: fmt.Errorf("could not init multi rate limiter for %s: %s", domain, err),
+				}
+				return
 			}
-			return
 		}
 		session, err := subscraping.NewSession(domain, proxy, multiRateLimiter, timeout)
 		if err != nil {
@@ -56,7 +144,28 @@ func (a *Agent) EnumerateSubdomainsWithCtx(ctx context.Context, domain string, p
 			}
 			return
 		}
-		defer session.Close()
+		if enumerateOptions.statistics != nil {
+			session.Statistics = enumerateOptions.statistics
+		}
+		a.statisticsMutex.Lock()
+		a.statistics = append(a.statistics, session.Statistics)
+		a.statisticsMutex.Unlock()
+		session.RetryPolicy = enumerateOptions.retryPolicy
+		session.SourceRetryPolicies = enumerateOptions.sourceRetries
+		session.Cache = enumerateOptions.responseCache
+		session.Cassette = enumerateOptions.cassette
+		session.Budget = enumerateOptions.budgets
+		if enumerateOptions.transport != nil {
+			session.Client.Transport = enumerateOptions.transport
+		}
+		defer func() {
+			// A shared rate limiter outlives this session and is stopped by its owner
+			if sharedRateLimiter {
+				session.Client.CloseIdleConnections()
+			} else {
+				session.Close()
+			}
+		}()
 
 		ctx, cancel := context.WithTimeout(ctx, maxEnumTime)
 
@@ -65,11 +174,82 @@ func (a *Agent) EnumerateSubdomainsWithCtx(ctx context.Context, domain string, p
 		for _, runner := range a.sources {
 			wg.Add(1)
 			go func(source subscraping.Source) {
-				ctxWithValue := context.WithValue(ctx, subscraping.CtxSourceArg, source.Name())
+				defer wg.Done()
+
+				// The reason is reported on the domain the source was disabled on
+				if cb := enumerateOptions.circuitBreaker; cb != nil {
+					if _, tripped := cb.Tripped(source.Name()); tripped {
+						session.Statistics.Skip(source.Name())
+						return
+					}
+				}
+
+				if budgetErr, reached := session.Budget.Reached(source.Name()); reached {
+					session.Statistics.Update(source.Name(), func(stats *subscraping.Statistics) {
+						stats.Skipped = true
+						stats.Budget = budgetErr.Describe()
+					})
+					return
+				}
+
+				sourceCtx := ctx
+				if timeout, ok := enumerateOptions.sourceTimeouts[source.Name()]; ok {
+					var sourceCancel context.CancelFunc
+					sourceCtx, sourceCancel = context.WithTimeout(ctx, timeout)
+					defer sourceCancel()
+				}
+				ctxWithValue := context.WithValue(sourceCtx, subscraping.CtxSourceArg, source.Name())
+				// The source deadline is only the cause when the enumeration itself is still running
+				deadlineReached := func() bool {
+					return sourceCtx != ctx && errors.Is(sourceCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil
+				}
+
+				startTime := time.Now()
+				var resultCount int
+				var timedOut bool
+				var budget string
+				var errs []error
 				for resp := range source.Run(ctxWithValue, domain, session) {
+					switch resp.Type {
+					case subscraping.Subdomain:
+						resultCount++
+					case subscraping.Error:
+						// Errors caused by the source deadline are not real errors
+						if deadlineReached() {
+							timedOut = true
+							continue
+						}
+						// Reaching a budget doesn't tell about the source health either
+						var budgetErr *subscraping.BudgetError
+						if errors.As(resp.Error, &budgetErr) {
+							budget = budgetErr.Describe()
+						} else {
+							errs = append(errs, resp.Error)
+						}
+					}
 					results <- resp
 				}
-				wg.Done()
+				timedOut = timedOut || deadlineReached()
+				if timedOut {
+					gologger.Warning().Msgf("Source %s reached its deadline for %s, results are partial\n", source.Name(), domain)
+				}
+				// Neither a timed out source nor a cancelled enumeration tells about the source health
+				var disabled string
+				if cb := enumerateOptions.circuitBreaker; cb != nil && !timedOut && ctx.Err() == nil {
+					disabled = cb.record(source.Name(), resultCount, errs)
+				}
+				session.Statistics.Update(source.Name(), func(stats *subscraping.Statistics) {
+					stats.TimeTaken = time.Since(startTime)
+					stats.Results += resultCount
+					stats.Errors += len(errs)
+					stats.TimedOut = stats.TimedOut || timedOut
+					if budget != "" {
+						stats.Budget = budget
+					}
+					if disabled != "" {
+						stats.Disabled = disabled
+					}
+				})
 			}(runner)
 		}
 		wg.Wait()
@@ -78,13 +258,37 @@ func (a *Agent) EnumerateSubdomainsWithCtx(ctx context.Context, domain string, p
 	return results
 }
 
+// GetStatistics returns the source statistics summed over every enumeration
+// run by the agent, keyed by source name. It replaces the statistics the
+// sources used to keep themselves.
+//
+// Deprecated: use WithStatistics to collect the statistics of an enumeration.
+func (a *Agent) GetStatistics() map[string]subscraping.Statistics {
+	a.statisticsMutex.Lock()
+	defer a.statisticsMutex.Unlock()
+
+	runs := make([]map[string]subscraping.Statistics, 0, len(a.statistics))
+	for _, runStatistics := range a.statistics {
+		runs = append(runs, runStatistics.All())
+	}
+	return subscraping.MergeStatistics(runs...)
+}
+
+// NewMultiRateLimiter builds a rate limiter holding one limiter per selected source.
+// It can be passed to several enumerations through WithMultiRateLimiter.
+func (a *Agent) NewMultiRateLimiter(ctx context.Context, globalRateLimit int, rateLimit *subscraping.CustomRateLimit) (*ratelimit.MultiLimiter, error) {
+	return a.buildMultiRateLimiter(ctx, globalRateLimit, rateLimit)
+}
+
 func (a *Agent) buildMultiRateLimiter(ctx context.Context, globalRateLimit int, rateLimit *subscraping.CustomRateLimit) (*ratelimit.MultiLimiter, error) {
 	var multiRateLimiter *ratelimit.MultiLimiter
 	var err error
 	for _, source := range a.sources {
 		var rl uint
-		if sourceRateLimit, ok := rateLimit.Custom.Get(strings.ToLower(source.Name())); ok {
-			rl = sourceRateLimitOrDefault(uint(globalRateLimit), sourceRateLimit)
+		if rateLimit != nil {
+			if sourceRateLimit, ok := rateLimit.Custom.Get(strings.ToLower(source.Name())); ok {
+				rl = sourceRateLimitOrDefault(uint(globalRateLimit), sourceRateLimit)
+			}
 		}
 
 		if rl > 0 {
@@ -125,15 +329,3 @@ func addRateLimiter(ctx context.Context, multiRateLimiter *ratelimit.MultiLimite
 	})
 	return multiRateLimiter, err
 }
-
-func (a *Agent) GetStatistics() map[string]subscraping.Statistics {
-	stats := make(map[string]subscraping.Statistics)
-	sort.Slice(a.sources, func(i, j int) bool {
-		return a.sources[i].Name() > a.sources[j].Name()
-	})
-
-	for _, source := range a.sources {
-		stats[source.Name()] = source.Statistics()
-	}
-	return stats
-}
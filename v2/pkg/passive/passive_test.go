@@ -0,0 +1,168 @@
+package passive
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
+	mapsutil "github.com/projectdiscovery/utils/maps"
+)
+
+// testSource emits the given subdomains and errors and then waits for
+// the context to be done when block is set
+type testSource struct {
+	name       string
+	subdomains []string
+	errors     []error
+	block      bool
+}
+
+func (s *testSource) Run(ctx context.Context, domain string, _ *subscraping.Session) <-chan subscraping.Result {
+	results := make(chan subscraping.Result)
+	go func() {
+		defer close(results)
+		for _, subdomain := range s.subdomains {
+			results <- subscraping.Result{Source: s.name, Type: subscraping.Subdomain, Value: subdomain + "." + domain}
+		}
+		for _, err := range s.errors {
+			results <- subscraping.Result{Source: s.name, Type: subscraping.Error, Error: err}
+		}
+		if s.block {
+			<-ctx.Done()
+			results <- subscraping.Result{Source: s.name, Type: subscraping.Error, Error: ctx.Err()}
+		}
+	}()
+	return results
+}
+
+func (s *testSource) Name() string              { return s.name }
+func (s *testSource) IsDefault() bool           { return true }
+func (s *testSource) HasRecursiveSupport() bool { return false }
+func (s *testSource) NeedsKey() bool            { return false }
+func (s *testSource) AddApiKeys(_ []string)     {}
+
+func TestEnumerateSourceTimeout(t *testing.T) {
+	agent := &Agent{sources: []subscraping.Source{
+		&testSource{name: "slow", subdomains: []string{"a", "b"}, block: true},
+		&testSource{name: "fast", subdomains: []string{"c"}},
+	}}
+
+	stats := subscraping.NewRunStatistics("example.com")
+	timeouts := map[string]time.Duration{"slow": 50 * time.Millisecond}
+
+	var subdomains, errs int
+	for result := range agent.EnumerateSubdomains("example.com", "", 0, 10, time.Minute, WithStatistics(stats), WithSourceTimeouts(timeouts)) {
+		switch result.Type {
+		case subscraping.Subdomain:
+			subdomains++
+		case subscraping.Error:
+			errs++
+		}
+	}
+	require.Equal(t, 3, subdomains)
+	require.Zero(t, errs, "deadline errors must not be reported")
+
+	slow, ok := stats.Get("slow")
+	require.True(t, ok)
+	require.True(t, slow.TimedOut)
+	require.Equal(t, 2, slow.Results)
+	require.Zero(t, slow.Errors)
+
+	fast, ok := stats.Get("fast")
+	require.True(t, ok)
+	require.False(t, fast.TimedOut)
+	require.Equal(t, 1, fast.Results)
+}
+
+func TestEnumerateCircuitBreaker(t *testing.T) {
+	agent := &Agent{sources: []subscraping.Source{
+		&testSource{name: "failing", errors: []error{errors.New("endpoint is down")}},
+		&testSource{name: "revoked", errors: []error{&subscraping.StatusCodeError{StatusCode: http.StatusUnauthorized}}},
+		&testSource{name: "working", subdomains: []string{"a"}},
+	}}
+	circuitBreaker := NewCircuitBreaker(2)
+
+	enumerate := func() *subscraping.RunStatistics {
+		stats := subscraping.NewRunStatistics("example.com")
+		for range agent.EnumerateSubdomains("example.com", "", 0, 10, time.Minute, WithStatistics(stats), WithCircuitBreaker(circuitBreaker)) {
+		}
+		return stats
+	}
+
+	// the first domain runs every source and trips the one failing authentication,
+	// the reason is reported on this domain only
+	stats := enumerate()
+	revoked, _ := stats.Get("revoked")
+	require.False(t, revoked.Skipped)
+	require.NotEmpty(t, revoked.Disabled)
+	failing, _ := stats.Get("failing")
+	require.Empty(t, failing.Disabled)
+	_, tripped := circuitBreaker.Tripped("revoked")
+	require.True(t, tripped)
+
+	// the second consecutive failure reaches the threshold
+	stats = enumerate()
+	revoked, _ = stats.Get("revoked")
+	require.True(t, revoked.Skipped)
+	require.Empty(t, revoked.Disabled, "the reason was reported on the previous domain")
+	failing, _ = stats.Get("failing")
+	require.NotEmpty(t, failing.Disabled)
+	_, tripped = circuitBreaker.Tripped("failing")
+	require.True(t, tripped)
+
+	stats = enumerate()
+	failing, _ = stats.Get("failing")
+	require.True(t, failing.Skipped)
+	require.Empty(t, failing.Disabled)
+	working, _ := stats.Get("working")
+	require.Empty(t, working.Disabled)
+	require.Equal(t, 1, working.Results)
+}
+
+func TestAgentWithSources(t *testing.T) {
+	inhouse := &testSource{name: "inhouse", subdomains: []string{"a"}}
+	crtsh := &testSource{name: "crtsh", subdomains: []string{"b"}}
+
+	agent := New(nil, nil, false, false, WithSources(inhouse))
+	require.Contains(t, agent.sources, inhouse, "agent sources are selected as default sources")
+	require.NotContains(t, NameSourceMap, "inhouse", "agent sources must not be registered globally")
+
+	agent = New([]string{"inhouse", "crtsh"}, nil, false, false, WithSources(inhouse, crtsh))
+	require.ElementsMatch(t, []subscraping.Source{inhouse, crtsh}, agent.sources, "agent sources replace the built-in ones")
+
+	agent = New(nil, []string{"inhouse"}, true, false, WithSources(inhouse))
+	require.NotContains(t, agent.sources, inhouse)
+	require.Len(t, agent.sources, len(AllSources)-len(expectedActiveSources))
+
+	agent = New([]string{"inhouse"}, nil, false, false, WithSources(inhouse))
+	rateLimit := &subscraping.CustomRateLimit{Custom: mapsutil.SyncLockMap[string, uint]{Map: map[string]uint{"inhouse": 5}}}
+	multiRateLimiter, err := agent.NewMultiRateLimiter(context.Background(), 0, rateLimit)
+	require.NoError(t, err)
+	defer multiRateLimiter.Stop()
+	limit, err := multiRateLimiter.GetLimit("inhouse")
+	require.NoError(t, err)
+	require.EqualValues(t, 5, limit)
+
+	stats := subscraping.NewRunStatistics("example.com")
+	for range agent.EnumerateSubdomains("example.com", "", 0, 10, time.Minute, WithStatistics(stats), WithMultiRateLimiter(multiRateLimiter)) {
+	}
+	inhouseStats, ok := stats.Get("inhouse")
+	require.True(t, ok)
+	require.Equal(t, 1, inhouseStats.Results)
+}
+
+func TestAgentGetStatistics(t *testing.T) {
+	agent := &Agent{sources: []subscraping.Source{&testSource{name: "one", subdomains: []string{"a", "b"}}}}
+	for _, domain := range []string{"example.com", "example.org"} {
+		for range agent.EnumerateSubdomains(domain, "", 0, 10, time.Minute) {
+		}
+	}
+
+	stats := agent.GetStatistics()
+	require.Equal(t, 4, stats["one"].Results, "the statistics are summed over the enumerations")
+}
@@ -3,6 +3,7 @@ package passive
 import (
 	"fmt"
 	"strings"
+	"sync"
 
 	"golang.org/x/exp/maps"
 
@@ -10,8 +11,10 @@ import (
 	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
 	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping/sources/alienvault"
 	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping/sources/anubis"
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping/sources/axfr"
 	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping/sources/bevigil"
 	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping/sources/binaryedge"
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping/sources/bruteforce"
 	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping/sources/bufferover"
 	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping/sources/builtwith"
 	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping/sources/c99"
@@ -21,6 +24,8 @@ import (
 	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping/sources/chinaz"
 	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping/sources/commoncrawl"
 	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping/sources/crtsh"
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping/sources/ctlog"
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping/sources/dataset"
 	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping/sources/digitorus"
 	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping/sources/dnsdb"
 	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping/sources/dnsdumpster"
@@ -29,6 +34,7 @@ import (
 	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping/sources/fofa"
 	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping/sources/fullhunt"
 	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping/sources/github"
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping/sources/gitlab"
 	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping/sources/hackertarget"
 	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping/sources/hunter"
 	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping/sources/intelx"
@@ -48,13 +54,16 @@ import (
 	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping/sources/whoisxmlapi"
 	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping/sources/zoomeyeapi"
 	mapsutil "github.com/projectdiscovery/utils/maps"
+	sliceutil "github.com/projectdiscovery/utils/slice"
 )
 
 var AllSources = [...]subscraping.Source{
 	&alienvault.Source{},
 	&anubis.Source{},
+	&axfr.Source{},
 	&bevigil.Source{},
 	&binaryedge.Source{},
+	&bruteforce.Source{},
 	&bufferover.Source{},
 	&c99.Source{},
 	&censys.Source{},
@@ -63,6 +72,8 @@ var AllSources = [...]subscraping.Source{
 	&chinaz.Source{},
 	&commoncrawl.Source{},
 	&crtsh.Source{},
+	&ctlog.Source{},
+	&dataset.Source{},
 	&digitorus.Source{},
 	&dnsdb.Source{},
 	&dnsdumpster.Source{},
@@ -70,6 +81,7 @@ var AllSources = [...]subscraping.Source{
 	&fofa.Source{},
 	&fullhunt.Source{},
 	&github.Source{},
+	&gitlab.Source{},
 	&hackertarget.Source{},
 	&hunter.Source{},
 	&intelx.Source{},
@@ -102,43 +114,143 @@ var sourceWarnings = mapsutil.NewSyncLockMap[string, string](
 
 var NameSourceMap = make(map[string]subscraping.Source, len(AllSources))
 
+// registeredSources are the sources added to the built-in ones, in registration order
+var registeredSources []subscraping.Source
+
 func init() {
 	for _, currentSource := range AllSources {
 		NameSourceMap[strings.ToLower(currentSource.Name())] = currentSource
 	}
 }
 
+// RegisterSource adds a source to the built-in ones, so that it can be
+// selected by name and gets its keys from the provider config. It must be
+// called before creating the agents and is not safe for concurrent use.
+func RegisterSource(source subscraping.Source) error {
+	name := strings.ToLower(source.Name())
+	if _, ok := NameSourceMap[name]; ok {
+		return fmt.Errorf("a source named %s already exists", name)
+	}
+	NameSourceMap[name] = source
+	registeredSources = append(registeredSources, source)
+	return nil
+}
+
+// Sources returns the built-in sources followed by the registered ones and
+// the given ones. A given source replaces the source having the same name.
+func Sources(sources ...subscraping.Source) []subscraping.Source {
+	all := append(AllSources[:len(AllSources):len(AllSources)], registeredSources...)
+	if len(sources) == 0 {
+		return all
+	}
+
+	replacements := make(map[string]subscraping.Source, len(sources))
+	for _, source := range sources {
+		replacements[strings.ToLower(source.Name())] = source
+	}
+	merged := make([]subscraping.Source, 0, len(all)+len(sources))
+	for _, source := range all {
+		name := strings.ToLower(source.Name())
+		if replacement, ok := replacements[name]; ok {
+			source = replacement
+			delete(replacements, name)
+		}
+		merged = append(merged, source)
+	}
+	for _, source := range sources {
+		if _, ok := replacements[strings.ToLower(source.Name())]; ok {
+			merged = append(merged, source)
+		}
+	}
+	return merged
+}
+
+// AgentOption configures an agent created by New
+type AgentOption func(opts *agentOptions)
+
+type agentOptions struct {
+	sources       []subscraping.Source
+	activeSources []string
+}
+
+// WithSources makes the agent choose among the given sources too, without
+// registering them globally. They are selected by name, as default sources
+// or with all sources like the built-in ones, and replace the sources having
+// the same name. Their keys must be added by the caller.
+func WithSources(sources ...subscraping.Source) AgentOption {
+	return func(opts *agentOptions) {
+		opts.sources = append(opts.sources, sources...)
+	}
+}
+
+// WithActiveSources adds the named active sources to the selected ones, the
+// default sources or the sources given by name, unless they are excluded
+func WithActiveSources(names ...string) AgentOption {
+	return func(opts *agentOptions) {
+		opts.activeSources = append(opts.activeSources, names...)
+	}
+}
+
 // Agent is a struct for running passive subdomain enumeration
 // against a given host. It wraps subscraping package and provides
 // a layer to build upon.
 type Agent struct {
 	sources []subscraping.Source
+
+	statisticsMutex sync.Mutex
+	// statistics are the statistics of the enumerations run by the agent
+	statistics []*subscraping.RunStatistics
 }
 
 // New creates a new agent for passive subdomain discovery
-func New(sourceNames, excludedSourceNames []string, useAllSources, useSourcesSupportingRecurse bool) *Agent {
-	sources := make(map[string]subscraping.Source, len(AllSources))
+func New(sourceNames, excludedSourceNames []string, useAllSources, useSourcesSupportingRecurse bool, options ...AgentOption) *Agent {
+	var agentOpts agentOptions
+	for _, option := range options {
+		option(&agentOpts)
+	}
+
+	nameSourceMap := NameSourceMap
+	if len(agentOpts.sources) > 0 {
+		nameSourceMap = make(map[string]subscraping.Source, len(NameSourceMap)+len(agentOpts.sources))
+		maps.Copy(nameSourceMap, NameSourceMap)
+		for _, source := range agentOpts.sources {
+			nameSourceMap[strings.ToLower(source.Name())] = source
+		}
+	}
+
+	sources := make(map[string]subscraping.Source, len(nameSourceMap))
 
 	if useAllSources {
-		maps.Copy(sources, NameSourceMap)
+		// Active sources are only used when they are named too
+		for name, source := range nameSourceMap {
+			if _, active := source.(subscraping.ActiveSource); !active || sliceutil.Contains(sourceNames, name) {
+				sources[name] = source
+			}
+		}
 	} else {
 		if len(sourceNames) > 0 {
 			for _, source := range sourceNames {
-				if NameSourceMap[source] == nil {
+				if nameSourceMap[source] == nil {
 					gologger.Fatal().Msgf("There is no source with the name: %s", source)
 				} else {
-					sources[source] = NameSourceMap[source]
+					sources[source] = nameSourceMap[source]
 				}
 			}
 		} else {
-			for _, currentSource := range AllSources {
+			for _, currentSource := range Sources(agentOpts.sources...) {
 				if currentSource.IsDefault() {
-					sources[currentSource.Name()] = currentSource
+					sources[strings.ToLower(currentSource.Name())] = currentSource
 				}
 			}
 		}
 	}
 
+	for _, name := range agentOpts.activeSources {
+		if source, ok := nameSourceMap[name].(subscraping.ActiveSource); ok {
+			sources[name] = source
+		}
+	}
+
 	if len(excludedSourceNames) > 0 {
 		for _, sourceName := range excludedSourceNames {
 			delete(sources, sourceName)
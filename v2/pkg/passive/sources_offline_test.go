@@ -0,0 +1,500 @@
+package passive
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/maps"
+
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
+	"github.com/projectdiscovery/subfinder/v2/pkg/testutils"
+)
+
+// offlineSource is the contract of a source against the fake API of its provider
+type offlineSource struct {
+	keys       []string
+	routes     map[string]http.Handler
+	subdomains []string
+	retries    int
+}
+
+// sourcesWithoutFakes talk to their provider without the session client
+var sourcesWithoutFakes = map[string]string{
+	"axfr":       "queries DNS servers, tested against a local server in its package",
+	"bruteforce": "resolves candidates, tested against a local server in its package",
+	"crtsh":      "queries the crt.sh postgres database first",
+	"ctlog":      "keeps an on-disk index, tested against a stub log in its package",
+	"dataset":    "reads local files",
+}
+
+var ccIndex = fmt.Sprintf("CC-MAIN-%d-10-index", time.Now().Year())
+
+// newOfflineSources returns the contracts of the sources, their handlers keep
+// state between requests so each test run needs fresh ones
+func newOfflineSources() map[string]offlineSource {
+	return map[string]offlineSource{
+		"alienvault": {
+			routes: map[string]http.Handler{
+				"otx.alienvault.com/api/v1/indicators/domain/example.com/passive_dns": testutils.JSON(200, `{"passive_dns":[{"hostname":"a.example.com"},{"hostname":"b.example.com"}]}`),
+			},
+			subdomains: []string{"a.example.com", "b.example.com"},
+		},
+		"anubis": {
+			routes: map[string]http.Handler{
+				"jonlu.ca/anubis/subdomains/example.com": testutils.JSON(200, `["a.example.com","b.example.com"]`),
+			},
+			subdomains: []string{"a.example.com", "b.example.com"},
+		},
+		"bevigil": {
+			keys: []string{"key"},
+			routes: map[string]http.Handler{
+				"osint.bevigil.com/api/example.com/subdomains/": testutils.JSON(200, `{"domain":"example.com","subdomains":["a.example.com"]}`),
+			},
+			subdomains: []string{"a.example.com"},
+		},
+		"binaryedge": {
+			keys: []string{"key"},
+			routes: map[string]http.Handler{
+				"api.binaryedge.io/v2/user/subscription": testutils.JSON(200, `{}`),
+				"api.binaryedge.io/v2/query/domains/subdomain/example.com": testutils.Pages(
+					testutils.JSON(200, `{"events":["a.example.com"],"page":1,"pagesize":1,"total":2}`),
+					testutils.JSON(200, `{"events":["b.example.com"],"page":2,"pagesize":1,"total":2}`),
+				),
+			},
+			subdomains: []string{"a.example.com", "b.example.com"},
+		},
+		"bufferover": {
+			keys: []string{"key"},
+			routes: map[string]http.Handler{
+				"tls.bufferover.run/dns": testutils.JSON(200, `{"Meta":{"Errors":[]},"Results":["1.2.3.4,a.example.com","b.example.com"]}`),
+			},
+			subdomains: []string{"a.example.com", "b.example.com"},
+		},
+		"builtwith": {
+			keys: []string{"key"},
+			routes: map[string]http.Handler{
+				"api.builtwith.com/v21/api.json": testutils.JSON(200, `{"Results":[{"Result":{"Paths":[{"Domain":"example.com","SubDomain":"a"}]}}]}`),
+			},
+			subdomains: []string{"a.example.com"},
+		},
+		"c99": {
+			keys: []string{"key"},
+			routes: map[string]http.Handler{
+				"api.c99.nl/subdomainfinder": testutils.JSON(200, `{"success":true,"subdomains":[{"subdomain":"a.example.com"},{"subdomain":".example.com"}]}`),
+			},
+			subdomains: []string{"a.example.com"},
+		},
+		"censys": {
+			keys: []string{"token:secret"},
+			routes: map[string]http.Handler{
+				"search.censys.io/api/v2/certificates/search": testutils.Pages(
+					testutils.JSON(200, `{"result":{"hits":[{"names":["a.example.com"]}],"links":{"next":"cursor"}}}`),
+					testutils.JSON(200, `{"result":{"hits":[{"names":["b.example.com"]}],"links":{"next":""}}}`),
+				),
+			},
+			subdomains: []string{"a.example.com", "b.example.com"},
+		},
+		"certspotter": {
+			keys: []string{"key"},
+			routes: map[string]http.Handler{
+				"api.certspotter.com/v1/issuances": testutils.Pages(
+					testutils.JSON(200, `[{"id":"1","dns_names":["a.example.com"]}]`),
+					testutils.JSON(200, `[{"id":"2","dns_names":["b.example.com"]}]`),
+					testutils.JSON(200, `[]`),
+				),
+			},
+			subdomains: []string{"a.example.com", "b.example.com"},
+		},
+		"chaos": {
+			keys: []string{"key"},
+			routes: map[string]http.Handler{
+				"dns.projectdiscovery.io/dns/example.com/subdomains": testutils.JSON(200, `{"domain":"example.com","subdomains":["a","b"]}`),
+			},
+			subdomains: []string{"a.example.com", "b.example.com"},
+		},
+		"chinaz": {
+			keys: []string{"key"},
+			routes: map[string]http.Handler{
+				"apidatav2.chinaz.com/single/alexa": testutils.JSON(200, `{"Result":{"ContributingSubdomainList":[{"DataUrl":"a.example.com"}]}}`),
+			},
+			subdomains: []string{"a.example.com"},
+		},
+		"commoncrawl": {
+			routes: map[string]http.Handler{
+				"index.commoncrawl.org/collinfo.json": testutils.JSON(200, `[{"id":"`+ccIndex+`","cdx-api":"https://index.commoncrawl.org/`+ccIndex+`"}]`),
+				"index.commoncrawl.org/" + ccIndex:    testutils.Body(200, "text/plain", "com,example,a)/ 20240101 {\"url\": \"https://a.example.com/\"}\n"),
+			},
+			subdomains: []string{"a.example.com"},
+		},
+		"digitorus": {
+			routes: map[string]http.Handler{
+				"certificatedetails.com/example.com": testutils.Body(200, "text/html", `<a href="/a.example.com">a.example.com</a>`),
+			},
+			subdomains: []string{"a.example.com"},
+		},
+		"dnsdb": {
+			keys: []string{"key"},
+			routes: map[string]http.Handler{
+				"api.dnsdb.info/dnsdb/v2/rate_limit": testutils.JSON(200, `{"rate":{"offset_max":1000}}`),
+				"api.dnsdb.info/dnsdb/v2/lookup/rrset/name/*.example.com": testutils.Pages(
+					testutils.Body(200, "application/x-ndjson", "{\"cond\":\"begin\"}\n{\"obj\":{\"rrname\":\"a.example.com.\"}}\n{\"cond\":\"limited\"}\n"),
+					testutils.Body(200, "application/x-ndjson", "{\"cond\":\"begin\"}\n{\"obj\":{\"rrname\":\"b.example.com.\"}}\n{\"cond\":\"succeeded\"}\n"),
+				),
+			},
+			subdomains: []string{"a.example.com", "b.example.com"},
+		},
+		"dnsdumpster": {
+			routes: map[string]http.Handler{
+				"dnsdumpster.com/": testutils.Pages(
+					testutils.Body(200, "text/html", `<input type="hidden" name="csrfmiddlewaretoken" value="token">`),
+					testutils.Body(200, "text/html", `<td>a.example.com</td>`),
+				),
+			},
+			subdomains: []string{"a.example.com"},
+		},
+		"dnsrepo": {
+			keys: []string{"key"},
+			routes: map[string]http.Handler{
+				"dnsrepo.noc.org/api/": testutils.JSON(200, `[{"domain":"a.example.com."}]`),
+			},
+			subdomains: []string{"a.example.com"},
+		},
+		"facebook": {
+			keys: []string{"app:secret"},
+			routes: map[string]http.Handler{
+				"graph.facebook.com/oauth/access_token": testutils.JSON(200, `{"access_token":"app|token"}`),
+				"graph.facebook.com/certificates": testutils.Pages(
+					testutils.JSON(200, `{"data":[{"domains":["a.example.com"]}],"paging":{"next":"https://graph.facebook.com/certificates?access_token=app|token&after=cursor"}}`),
+					testutils.JSON(200, `{"data":[{"domains":["b.example.com"]}],"paging":{}}`),
+				),
+			},
+			subdomains: []string{"a.example.com", "b.example.com"},
+		},
+		"fofa": {
+			keys: []string{"user@example.org:key"},
+			routes: map[string]http.Handler{
+				"fofa.info/api/v1/search/all": testutils.JSON(200, `{"error":false,"size":2,"results":["https://a.example.com:443","b.example.com"]}`),
+			},
+			subdomains: []string{"a.example.com", "b.example.com"},
+		},
+		"fullhunt": {
+			keys: []string{"key"},
+			routes: map[string]http.Handler{
+				"fullhunt.io/api/v1/domain/example.com/subdomains": testutils.JSON(200, `{"hosts":["a.example.com"]}`),
+			},
+			subdomains: []string{"a.example.com"},
+		},
+		"github": {
+			keys: []string{"token", "https://ghe.example.com:ghe-token"},
+			routes: map[string]http.Handler{
+				"ghe.example.com/api/v3/search/code":                       testutils.JSON(200, `{"items":[{"url":"https://ghe.example.com/api/v3/repositories/1/contents/hosts.txt?ref=abc","html_url":"https://ghe.example.com/org/repo/blob/abc/hosts.txt"}]}`),
+				"ghe.example.com/api/v3/repositories/1/contents/hosts.txt": testutils.Body(200, "text/plain", "intranet.example.com"),
+				"api.github.com/search/code": testutils.Pages(
+					http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						w.Header().Set("Link", `<https://api.github.com/search/code?q=example.com&page=2>; rel="next"`)
+						_, _ = w.Write([]byte(`{"items":[{"html_url":"https://github.com/org/repo/blob/main/hosts.txt","text_matches":[{"fragment":"api.example.com"}]}]}`))
+					}),
+					testutils.JSON(200, `{"items":[{"html_url":"https://github.com/org/repo/blob/main/other.txt","text_matches":[{"fragment":"mail.example.com"}]}]}`),
+				),
+				"raw.githubusercontent.com/org/repo/main/hosts.txt": testutils.Body(200, "text/plain", "www.example.com"),
+				"raw.githubusercontent.com/org/repo/main/other.txt": testutils.Body(200, "text/plain", "dev.example.com"),
+			},
+			subdomains: []string{"api.example.com", "dev.example.com", "intranet.example.com", "mail.example.com", "www.example.com"},
+		},
+		"gitlab": {
+			keys: []string{"token", "https://gitlab.example.com:internal-token"},
+			routes: map[string]http.Handler{
+				"gitlab.com/api/v4/search":                                          testutils.JSON(200, `[{"project_id":1,"path":"hosts.txt","ref":"main"}]`),
+				"gitlab.com/api/v4/projects/1/repository/files/hosts.txt/raw":       testutils.Body(200, "text/plain", "www.example.com"),
+				"gitlab.example.com/api/v4/search":                                  testutils.JSON(200, `[{"project_id":2,"path":"app.yml","ref":"main"}]`),
+				"gitlab.example.com/api/v4/projects/2/repository/files/app.yml/raw": testutils.Body(200, "text/plain", "db: db.internal.example.com"),
+			},
+			subdomains: []string{"db.internal.example.com", "www.example.com"},
+		},
+		"hackertarget": {
+			routes: map[string]http.Handler{
+				"api.hackertarget.com/hostsearch/": testutils.Body(200, "text/plain", "a.example.com,1.2.3.4\nb.example.com,1.2.3.5\n"),
+			},
+			subdomains: []string{"a.example.com", "b.example.com"},
+		},
+		"hunter": {
+			keys: []string{"key"},
+			routes: map[string]http.Handler{
+				"hunter.qianxin.com/openApi/search": testutils.JSON(200, `{"code":200,"data":{"arr":[{"domain":"a.example.com"}],"total":1}}`),
+			},
+			subdomains: []string{"a.example.com"},
+		},
+		"intelx": {
+			keys: []string{"2.intelx.io:key"},
+			routes: map[string]http.Handler{
+				"2.intelx.io/phonebook/search": testutils.JSON(200, `{"id":"search","status":0}`),
+				"2.intelx.io/phonebook/search/result": testutils.Pages(
+					testutils.JSON(200, `{"selectors":[{"selectorvalue":"a.example.com"}],"status":3}`),
+					testutils.JSON(200, `{"selectors":[{"selectorvalue":"b.example.com"}],"status":1}`),
+				),
+			},
+			subdomains: []string{"a.example.com", "b.example.com"},
+		},
+		"leakix": {
+			routes: map[string]http.Handler{
+				"leakix.net/api/subdomains/example.com": testutils.JSON(200, `[{"subdomain":"a.example.com"}]`),
+			},
+			subdomains: []string{"a.example.com"},
+		},
+		"netlas": {
+			keys: []string{"key"},
+			routes: map[string]http.Handler{
+				"app.netlas.io/api/domains_count/": testutils.JSON(200, `{"count":21}`),
+				"app.netlas.io/api/domains/": testutils.Pages(
+					testutils.JSON(200, `{"items":[{"data":{"domain":"a.example.com"}}]}`),
+					testutils.JSON(200, `{"items":[{"data":{"domain":"b.example.com"}}]}`),
+				),
+			},
+			subdomains: []string{"a.example.com", "b.example.com"},
+		},
+		"passivetotal": {
+			keys: []string{"user:secret"},
+			routes: map[string]http.Handler{
+				"api.passivetotal.org/v2/enrichment/subdomains": testutils.JSON(200, `{"subdomains":["a","b"]}`),
+			},
+			subdomains: []string{"a.example.com", "b.example.com"},
+		},
+		"quake": {
+			keys: []string{"key"},
+			routes: map[string]http.Handler{
+				"quake.360.net/api/v3/search/quake_service": testutils.JSON(200, `{"code":0,"data":[{"service":{"http":{"host":"a.example.com"}}}],"meta":{"pagination":{"total":1}}}`),
+			},
+			subdomains: []string{"a.example.com"},
+		},
+		"rapiddns": {
+			routes: map[string]http.Handler{
+				"rapiddns.io/subdomain/example.com": testutils.Pages(
+					testutils.Body(200, "text/html", `<td>a.example.com</td><li><a class="page-link ">2</a></li>`),
+					testutils.Body(200, "text/html", `<td>b.example.com</td>`),
+				),
+			},
+			subdomains: []string{"a.example.com", "b.example.com"},
+		},
+		"redhuntlabs": {
+			keys: []string{"https://reconapi.redhuntlabs.com/community/v1/domains/subdomains:key"},
+			routes: map[string]http.Handler{
+				"reconapi.redhuntlabs.com/community/v1/domains/subdomains": testutils.JSON(200, `{"subdomains":["a.example.com"],"metadata":{"result_count":1,"page_size":1000,"page_number":1}}`),
+			},
+			subdomains: []string{"a.example.com"},
+		},
+		"robtex": {
+			keys: []string{"key"},
+			routes: map[string]http.Handler{
+				"proapi.robtex.com/pdns/forward/example.com": testutils.Body(200, "application/x-ndjson", `{"rrname":"example.com","rrdata":"1.2.3.4","rrtype":"A"}`+"\n"),
+				"proapi.robtex.com/pdns/reverse/1.2.3.4":     testutils.Body(200, "application/x-ndjson", `{"rrname":"1.2.3.4","rrdata":"a.example.com","rrtype":"A"}`+"\n"),
+			},
+			subdomains: []string{"a.example.com"},
+		},
+		"securitytrails": {
+			keys: []string{"key"},
+			routes: map[string]http.Handler{
+				"api.securitytrails.com/v1/domains/list":  testutils.JSON(200, `{"meta":{"scroll_id":"scroll"},"records":[{"hostname":"a.example.com"}]}`),
+				"api.securitytrails.com/v1/scroll/scroll": testutils.JSON(200, `{"meta":{"scroll_id":""},"records":[{"hostname":"b.example.com"}]}`),
+			},
+			subdomains: []string{"a.example.com", "b.example.com"},
+		},
+		"shodan": {
+			keys: []string{"key"},
+			routes: map[string]http.Handler{
+				"api.shodan.io/dns/domain/example.com": testutils.RateLimited("0", testutils.Pages(
+					testutils.JSON(200, `{"subdomains":["a"],"more":true}`),
+					testutils.JSON(200, `{"subdomains":["b"],"more":false}`),
+				)),
+			},
+			subdomains: []string{"a.example.com", "b.example.com"},
+			retries:    1,
+		},
+		"sitedossier": {
+			routes: map[string]http.Handler{
+				"www.sitedossier.com/parentdomain/example.com":     testutils.Body(200, "text/html", `<li>a.example.com</li><a href="/parentdomain/example.com/101"><b>`),
+				"www.sitedossier.com/parentdomain/example.com/101": testutils.Body(200, "text/html", `<li>b.example.com</li>`),
+			},
+			subdomains: []string{"a.example.com", "b.example.com"},
+		},
+		"threatbook": {
+			keys: []string{"key"},
+			routes: map[string]http.Handler{
+				"api.threatbook.cn/v3/domain/sub_domains": testutils.JSON(200, `{"response_code":0,"data":{"sub_domains":{"total":"1","data":["a.example.com"]}}}`),
+			},
+			subdomains: []string{"a.example.com"},
+		},
+		"virustotal": {
+			keys: []string{"key"},
+			routes: map[string]http.Handler{
+				"www.virustotal.com/api/v3/domains/example.com/subdomains": testutils.Pages(
+					testutils.JSON(200, `{"data":[{"id":"a.example.com"}],"meta":{"cursor":"cursor"}}`),
+					testutils.JSON(200, `{"data":[{"id":"b.example.com"}],"meta":{}}`),
+				),
+			},
+			subdomains: []string{"a.example.com", "b.example.com"},
+		},
+		"waybackarchive": {
+			routes: map[string]http.Handler{
+				"web.archive.org/cdx/search/cdx": testutils.Body(200, "text/plain", "https://a.example.com/login\nhttp://B.example.com/index.html\n"),
+			},
+			subdomains: []string{"a.example.com", "b.example.com"},
+		},
+		"whoisxmlapi": {
+			keys: []string{"key"},
+			routes: map[string]http.Handler{
+				"subdomains.whoisxmlapi.com/api/v1": testutils.JSON(200, `{"result":{"count":1,"records":[{"domain":"a.example.com"}]}}`),
+			},
+			subdomains: []string{"a.example.com"},
+		},
+		"zoomeyeapi": {
+			keys: []string{"zoomeye.org:key"},
+			routes: map[string]http.Handler{
+				"api.zoomeye.org/domain/search": testutils.JSON(200, `{"status":200,"total":1,"list":[{"name":"a.example.com"}]}`),
+			},
+			subdomains: []string{"a.example.com"},
+		},
+	}
+}
+
+// newOfflineSource returns a fresh instance of a registered source so that
+// the keys added by a test don't leak into the others
+func newOfflineSource(source subscraping.Source, keys []string) subscraping.Source {
+	fresh := reflect.New(reflect.TypeOf(source).Elem()).Interface().(subscraping.Source)
+	fresh.AddApiKeys(keys)
+	return fresh
+}
+
+// enumerateOffline runs a source against a fake API and returns the sorted unique subdomains with the source statistics
+func enumerateOffline(t *testing.T, source subscraping.Source, fake *testutils.FakeAPI, options ...EnumerateOption) ([]string, subscraping.Statistics) {
+	t.Helper()
+
+	agent := &Agent{sources: []subscraping.Source{source}}
+	stats := subscraping.NewRunStatistics("example.com")
+	policy := subscraping.RetryPolicy{MaxRetries: 2, MinWait: time.Millisecond, MaxWait: time.Second}
+
+	options = append([]EnumerateOption{WithTransport(fake.Transport()), WithStatistics(stats), WithRetryPolicy(policy, nil)}, options...)
+	unique := make(map[string]struct{})
+	for result := range agent.EnumerateSubdomains("example.com", "", 0, 10, time.Minute, options...) {
+		require.Equal(t, source.Name(), result.Source, "wrong source name")
+		if result.Type == subscraping.Subdomain {
+			unique[result.Value] = struct{}{}
+		}
+	}
+
+	subdomains := maps.Keys(unique)
+	sort.Strings(subdomains)
+	sourceStats, _ := stats.Get(source.Name())
+	return subdomains, sourceStats
+}
+
+func TestSourcesOffline(t *testing.T) {
+	contracts := newOfflineSources()
+	for _, source := range AllSources {
+		if _, ok := sourcesWithoutFakes[source.Name()]; ok {
+			continue
+		}
+		contract, ok := contracts[source.Name()]
+		require.True(t, ok, "no offline contract for %s", source.Name())
+
+		t.Run(source.Name(), func(t *testing.T) {
+			fake := testutils.NewFakeAPI()
+			defer fake.Close()
+			for endpoint, handler := range contract.routes {
+				fake.Handle(endpoint, handler)
+			}
+
+			subdomains, stats := enumerateOffline(t, newOfflineSource(source, contract.keys), fake)
+
+			require.Empty(t, fake.Unmatched(), "requests to endpoints without a fake")
+			require.Equal(t, contract.subdomains, subdomains)
+			require.Zero(t, stats.Errors)
+			require.GreaterOrEqual(t, stats.Results, len(contract.subdomains))
+			require.Equal(t, contract.retries, stats.Retries)
+		})
+	}
+}
+
+func TestSourcesOfflineErrors(t *testing.T) {
+	contracts := newOfflineSources()
+	for _, source := range AllSources {
+		if _, ok := sourcesWithoutFakes[source.Name()]; ok {
+			continue
+		}
+		contract := contracts[source.Name()]
+
+		t.Run(source.Name(), func(t *testing.T) {
+			// without handlers the fake answers every request with an error
+			fake := testutils.NewFakeAPI()
+			defer fake.Close()
+
+			subdomains, stats := enumerateOffline(t, newOfflineSource(source, contract.keys), fake)
+
+			require.Empty(t, subdomains)
+			require.NotEmpty(t, fake.Unmatched())
+			require.Positive(t, stats.Errors, "failed requests must be reported")
+		})
+	}
+}
+
+// secretKeys replaces the tokens of the keys of a contract, the part after
+// the last colon, with values unique to the source
+func secretKeys(source string, keys []string) (replaced, secrets []string) {
+	for i, key := range keys {
+		secret := fmt.Sprintf("%s-secret-%d", source, i)
+		secrets = append(secrets, secret)
+		if separator := strings.LastIndex(key, ":"); separator >= 0 {
+			replaced = append(replaced, key[:separator+1]+secret)
+		} else {
+			replaced = append(replaced, secret)
+		}
+	}
+	return replaced, secrets
+}
+
+func TestSourcesCassetteRedaction(t *testing.T) {
+	contracts := newOfflineSources()
+	for _, source := range AllSources {
+		contract, ok := contracts[source.Name()]
+		if !ok || !source.NeedsKey() {
+			continue
+		}
+
+		t.Run(source.Name(), func(t *testing.T) {
+			fake := testutils.NewFakeAPI()
+			defer fake.Close()
+			for endpoint, handler := range contract.routes {
+				fake.Handle(endpoint, handler)
+			}
+			directory := t.TempDir()
+			cassette, err := subscraping.NewCassette(directory, subscraping.CassetteRecord)
+			require.NoError(t, err)
+
+			keys, secrets := secretKeys(source.Name(), contract.keys)
+			keyed := newOfflineSource(source, keys)
+			subdomains, _ := enumerateOffline(t, keyed, fake, WithCassette(cassette))
+			require.Equal(t, contract.subdomains, subdomains)
+			_, err = CheckSourceKeys(context.Background(), []subscraping.Source{keyed}, "", 10, WithTransport(fake.Transport()), WithCassette(cassette))
+			require.NoError(t, err)
+
+			files, err := filepath.Glob(filepath.Join(directory, "*.jsonl"))
+			require.NoError(t, err)
+			require.NotEmpty(t, files)
+			for _, file := range files {
+				data, err := os.ReadFile(file)
+				require.NoError(t, err)
+				for _, secret := range secrets {
+					require.NotContains(t, string(data), secret, "key recorded in %s", filepath.Base(file))
+				}
+			}
+		})
+	}
+}
@@ -7,14 +7,18 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/exp/maps"
+
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
 )
 
 var (
 	expectedAllSources = []string{
 		"alienvault",
 		"anubis",
+		"axfr",
 		"bevigil",
 		"binaryedge",
+		"bruteforce",
 		"bufferover",
 		"c99",
 		"censys",
@@ -23,6 +27,8 @@ var (
 		"chinaz",
 		"commoncrawl",
 		"crtsh",
+		"ctlog",
+		"dataset",
 		"digitorus",
 		"dnsdumpster",
 		"dnsdb",
@@ -30,6 +36,7 @@ var (
 		"fofa",
 		"fullhunt",
 		"github",
+		"gitlab",
 		"hackertarget",
 		"intelx",
 		"netlas",
@@ -92,10 +99,14 @@ var (
 
 	expectedDefaultRecursiveSources = []string{
 		"alienvault",
+		"axfr",
 		"binaryedge",
+		"bruteforce",
 		"bufferover",
 		"certspotter",
 		"crtsh",
+		"ctlog",
+		"dataset",
 		"dnsdumpster",
 		"dnsdb",
 		"digitorus",
@@ -107,11 +118,17 @@ var (
 		"facebook",
 		// "reconcloud",
 	}
+
+	expectedActiveSources = []string{
+		"axfr",
+		"bruteforce",
+	}
 )
 
 func TestSourceCategorization(t *testing.T) {
 	defaultSources := make([]string, 0, len(AllSources))
 	recursiveSources := make([]string, 0, len(AllSources))
+	activeSources := make([]string, 0, len(AllSources))
 	for _, source := range AllSources {
 		sourceName := source.Name()
 		if source.IsDefault() {
@@ -121,10 +138,15 @@ func TestSourceCategorization(t *testing.T) {
 		if source.HasRecursiveSupport() {
 			recursiveSources = append(recursiveSources, sourceName)
 		}
+
+		if _, ok := source.(subscraping.ActiveSource); ok {
+			activeSources = append(activeSources, sourceName)
+		}
 	}
 
 	assert.ElementsMatch(t, expectedDefaultSources, defaultSources)
 	assert.ElementsMatch(t, expectedDefaultRecursiveSources, recursiveSources)
+	assert.ElementsMatch(t, expectedActiveSources, activeSources)
 	assert.ElementsMatch(t, expectedAllSources, maps.Keys(NameSourceMap))
 }
 
@@ -153,14 +175,17 @@ func TestSourceFiltering(t *testing.T) {
 	}{
 		{someSources, someExclusions, false, false, len(someSources) - len(someExclusions)},
 		{someSources, someExclusions, false, true, 1},
-		{someSources, someExclusions, true, false, len(AllSources) - len(someExclusions)},
+		{someSources, someExclusions, true, false, len(AllSources) - len(expectedActiveSources) - len(someExclusions)},
 
 		{someSources, []string{}, false, false, len(someSources)},
-		{someSources, []string{}, true, false, len(AllSources)},
+		{someSources, []string{}, true, false, len(AllSources) - len(expectedActiveSources)},
 
 		{[]string{}, []string{}, false, false, len(expectedDefaultSources)},
-		{[]string{}, []string{}, true, false, len(AllSources)},
-		{[]string{}, []string{}, true, true, len(expectedDefaultRecursiveSources)},
+		{[]string{}, []string{}, true, false, len(AllSources) - len(expectedActiveSources)},
+		{[]string{}, []string{}, true, true, len(expectedDefaultRecursiveSources) - len(expectedActiveSources)},
+
+		{expectedActiveSources, []string{}, false, false, len(expectedActiveSources)},
+		{expectedActiveSources, []string{}, true, false, len(AllSources)},
 	}
 	for index, test := range tests {
 		t.Run(strconv.Itoa(index+1), func(t *testing.T) {
@@ -175,3 +200,26 @@ func TestSourceFiltering(t *testing.T) {
 		})
 	}
 }
+
+func TestWithActiveSources(t *testing.T) {
+	agent := New(nil, nil, false, false, WithActiveSources("bruteforce"))
+	assert.Len(t, agent.sources, len(expectedDefaultSources)+1)
+	assert.Contains(t, agent.sources, NameSourceMap["bruteforce"])
+
+	agent = New([]string{"crtsh"}, []string{"bruteforce"}, false, false, WithActiveSources("bruteforce"))
+	assert.Equal(t, []subscraping.Source{NameSourceMap["crtsh"]}, agent.sources)
+}
+
+func TestKeyedSourcesKeyCheck(t *testing.T) {
+	for _, source := range AllSources {
+		if !source.NeedsKey() {
+			continue
+		}
+		_, checker := source.(subscraping.KeyChecker)
+		unsupported, ok := source.(subscraping.KeyCheckUnsupported)
+		if ok {
+			assert.NotEmpty(t, unsupported.KeyCheckUnsupported(), source.Name())
+		}
+		assert.True(t, checker != ok, "%s must either check its keys or tell why it cannot", source.Name())
+	}
+}
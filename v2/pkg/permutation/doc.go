@@ -0,0 +1,3 @@
+// Package permutation builds candidate subdomains from the labels of the
+// subdomains already found, such as api-dev.example.com from api.example.com.
+package permutation
@@ -0,0 +1,240 @@
+package permutation
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	sliceutil "github.com/projectdiscovery/utils/slice"
+	"golang.org/x/exp/maps"
+)
+
+// Pattern is a way of altering the labels of the subdomains found
+type Pattern string
+
+const (
+	// Affix adds the words before and after the first label, api-dev for api
+	Affix Pattern = "affix"
+	// Number increments and decrements the numbers of the first label, web02
+	// for web01, or appends one, api2 for api
+	Number Pattern = "number"
+	// Word replaces the words found in the first label with the other ones,
+	// staging-web for dev-web
+	Word Pattern = "word"
+	// Swap reverses the two parts of the first label, web-dev for dev-web, and
+	// moves the first labels under the parents of the other subdomains,
+	// api.dev.example.com for api.example.com and web.dev.example.com
+	Swap Pattern = "swap"
+)
+
+// Patterns are the patterns in the order they are used when none are given
+var Patterns = []Pattern{Affix, Number, Word, Swap}
+
+// DefaultWords are the environment words used when none are given
+var DefaultWords = []string{
+	"dev", "development", "test", "testing", "qa", "uat", "stage", "staging", "stg",
+	"preprod", "prod", "production", "sandbox", "demo", "beta", "internal", "old", "new",
+}
+
+// DefaultMaxCandidates is the number of candidates when MaxCandidates is zero
+const DefaultMaxCandidates = 10000
+
+// maxLabelLength is the longest label allowed in a DNS name
+const maxLabelLength = 63
+
+// Generator builds the candidates of a domain from its subdomains
+type Generator struct {
+	// Patterns are the patterns used in order, all of them when empty
+	Patterns []Pattern
+	// Words are the words of the Affix and Word patterns, DefaultWords when empty
+	Words []string
+	// MaxCandidates caps the candidates of a domain, DefaultMaxCandidates when zero
+	MaxCandidates int
+}
+
+// subdomain is a subdomain split into its first label and its parent
+type subdomain struct {
+	label  string
+	parent string
+}
+
+// Candidates returns the candidates built from the hosts under the domain
+// which are not among the hosts. They are built pattern by pattern, for the
+// hosts in sorted order, until the cap is reached, in which case capped is true.
+func (g *Generator) Candidates(domain string, hosts []string) (candidates []string, capped bool) {
+	patterns := g.Patterns
+	if len(patterns) == 0 {
+		patterns = Patterns
+	}
+	words := g.Words
+	if len(words) == 0 {
+		words = DefaultWords
+	}
+	maxCandidates := g.MaxCandidates
+	if maxCandidates <= 0 {
+		maxCandidates = DefaultMaxCandidates
+	}
+
+	known := make(map[string]struct{}, len(hosts))
+	var subdomains []subdomain
+	for _, host := range hosts {
+		host = strings.ToLower(host)
+		if _, ok := known[host]; ok || !strings.HasSuffix(host, "."+domain) {
+			continue
+		}
+		known[host] = struct{}{}
+		label, parent, _ := strings.Cut(host, ".")
+		subdomains = append(subdomains, subdomain{label: label, parent: parent})
+	}
+	sort.Slice(subdomains, func(i, j int) bool {
+		return subdomains[i].label+"."+subdomains[i].parent < subdomains[j].label+"."+subdomains[j].parent
+	})
+
+	seen := make(map[string]struct{})
+	// add keeps a candidate and returns false once the cap is reached
+	add := func(label, parent string) bool {
+		if label == "" || len(label) > maxLabelLength || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return true
+		}
+		candidate := label + "." + parent
+		if _, ok := known[candidate]; ok {
+			return true
+		}
+		if _, ok := seen[candidate]; ok {
+			return true
+		}
+		if len(candidates) == maxCandidates {
+			capped = true
+			return false
+		}
+		seen[candidate] = struct{}{}
+		candidates = append(candidates, candidate)
+		return true
+	}
+
+	for _, pattern := range patterns {
+		if pattern == Swap {
+			if !swap(subdomains, domain, add) {
+				return candidates, capped
+			}
+			continue
+		}
+		for _, sub := range subdomains {
+			var labels []string
+			switch pattern {
+			case Affix:
+				labels = affix(sub.label, words)
+			case Number:
+				labels = number(sub.label)
+			case Word:
+				labels = replaceWords(sub.label, words)
+			}
+			for _, label := range labels {
+				if !add(label, sub.parent) {
+					return candidates, capped
+				}
+			}
+		}
+	}
+	return candidates, capped
+}
+
+// affix returns the label with each word before and after it
+func affix(label string, words []string) []string {
+	labels := make([]string, 0, 2*len(words))
+	for _, word := range words {
+		labels = append(labels, label+"-"+word, word+"-"+label)
+	}
+	return labels
+}
+
+// number returns the label with each number incremented and decremented,
+// keeping its width, or with 1 and 2 appended when it has none
+func number(label string) []string {
+	var labels []string
+	for start := 0; start < len(label); {
+		if !isDigit(label[start]) {
+			start++
+			continue
+		}
+		end := start
+		for end < len(label) && isDigit(label[end]) {
+			end++
+		}
+		value, err := strconv.Atoi(label[start:end])
+		if err == nil {
+			for _, next := range []int{value - 1, value + 1} {
+				if next < 0 {
+					continue
+				}
+				digits := strconv.Itoa(next)
+				if padding := end - start - len(digits); padding > 0 {
+					digits = strings.Repeat("0", padding) + digits
+				}
+				labels = append(labels, label[:start]+digits+label[end:])
+			}
+		}
+		start = end
+	}
+	if labels == nil {
+		labels = []string{label + "1", label + "2"}
+	}
+	return labels
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// replaceWords returns the label with each of its parts that is a word
+// replaced by the other words
+func replaceWords(label string, words []string) []string {
+	parts := strings.Split(label, "-")
+	var labels []string
+	for i, part := range parts {
+		if !sliceutil.Contains(words, part) {
+			continue
+		}
+		for _, word := range words {
+			if word == part {
+				continue
+			}
+			replaced := append([]string{}, parts...)
+			replaced[i] = word
+			labels = append(labels, strings.Join(replaced, "-"))
+		}
+	}
+	return labels
+}
+
+// swap adds the labels made of two parts reversed, then the first labels
+// under the parents of the other subdomains and the domain
+func swap(subdomains []subdomain, domain string, add func(label, parent string) bool) bool {
+	for _, sub := range subdomains {
+		if first, second, ok := strings.Cut(sub.label, "-"); ok && !strings.Contains(second, "-") {
+			if !add(second+"-"+first, sub.parent) {
+				return false
+			}
+		}
+	}
+
+	labels := make(map[string]struct{})
+	parents := map[string]struct{}{domain: {}}
+	for _, sub := range subdomains {
+		labels[sub.label] = struct{}{}
+		parents[sub.parent] = struct{}{}
+	}
+	sortedLabels := maps.Keys(labels)
+	sort.Strings(sortedLabels)
+	sortedParents := maps.Keys(parents)
+	sort.Strings(sortedParents)
+
+	for _, parent := range sortedParents {
+		for _, label := range sortedLabels {
+			if !add(label, parent) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,58 @@
+package permutation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCandidatesPatterns(t *testing.T) {
+	hosts := []string{"api.example.com", "dev-web.example.com", "web01.dev.example.com", "API.example.com", "other.org"}
+	words := []string{"dev", "staging"}
+
+	tests := []struct {
+		pattern  Pattern
+		expected []string
+	}{
+		{Affix, []string{
+			"api-dev.example.com", "dev-api.example.com", "api-staging.example.com", "staging-api.example.com",
+			"dev-web-dev.example.com", "dev-dev-web.example.com", "dev-web-staging.example.com", "staging-dev-web.example.com",
+			"web01-dev.dev.example.com", "dev-web01.dev.example.com", "web01-staging.dev.example.com", "staging-web01.dev.example.com",
+		}},
+		{Number, []string{"api1.example.com", "api2.example.com", "dev-web1.example.com", "dev-web2.example.com", "web00.dev.example.com", "web02.dev.example.com"}},
+		{Word, []string{"staging-web.example.com"}},
+		{Swap, []string{"web-dev.example.com", "api.dev.example.com", "dev-web.dev.example.com", "web01.example.com"}},
+	}
+	for _, test := range tests {
+		t.Run(string(test.pattern), func(t *testing.T) {
+			generator := &Generator{Patterns: []Pattern{test.pattern}, Words: words}
+			candidates, capped := generator.Candidates("example.com", hosts)
+			require.False(t, capped)
+			require.Equal(t, test.expected, candidates)
+		})
+	}
+}
+
+func TestCandidatesCap(t *testing.T) {
+	hosts := []string{"api.example.com", "dev-web.example.com"}
+
+	candidates, capped := (&Generator{}).Candidates("example.com", hosts)
+	require.False(t, capped)
+	require.Contains(t, candidates, "api-dev.example.com")
+	require.Contains(t, candidates, "staging-web.example.com")
+	require.NotContains(t, candidates, "api.example.com", "known hosts are not candidates")
+
+	candidates, capped = (&Generator{MaxCandidates: 3}).Candidates("example.com", hosts)
+	require.True(t, capped)
+	require.Equal(t, []string{"api-dev.example.com", "dev-api.example.com", "api-development.example.com"}, candidates)
+
+	candidates, capped = (&Generator{MaxCandidates: len(candidates)}).Candidates("example.com", nil)
+	require.False(t, capped)
+	require.Empty(t, candidates)
+}
+
+func TestNumber(t *testing.T) {
+	require.Equal(t, []string{"api1", "api2"}, number("api"))
+	require.Equal(t, []string{"api1"}, number("api0"))
+	require.Equal(t, []string{"web009-2", "web011-2", "web010-1", "web010-3"}, number("web010-2"))
+}
@@ -1,6 +1,9 @@
 package resolve
 
 import (
+	"net/http"
+
+	"github.com/miekg/dns"
 	"github.com/projectdiscovery/dnsx/libs/dnsx"
 )
 
@@ -18,10 +21,27 @@ var DefaultResolvers = []string{
 	"208.67.220.220:53", // OpenDNS Secondary
 }
 
-// Resolver is a struct for resolving DNS names
+// RecordTypes are the record types which can be queried for each host
+var RecordTypes = map[string]uint16{
+	"a":     dns.TypeA,
+	"aaaa":  dns.TypeAAAA,
+	"cname": dns.TypeCNAME,
+	"mx":    dns.TypeMX,
+	"txt":   dns.TypeTXT,
+}
+
+// Resolver is a struct for resolving DNS names. The resolution pools query
+// the question types of the DNSClient options for each host, which must
+// include A, or only look up its A records when there are none.
 type Resolver struct {
 	DNSClient *dnsx.DNSX
 	Resolvers []string
+	// RecordTypes are the record types whose answers are kept on the
+	// results, they must be among the question types of the DNSClient
+	RecordTypes []uint16
+	// HTTPClient, when set, fetches the hosts the wildcard classifier
+	// can't tell apart from a wildcard to compare their responses
+	HTTPClient *http.Client
 }
 
 // New creates a new resolver struct with the default resolvers
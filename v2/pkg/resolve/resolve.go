@@ -1,10 +1,11 @@
 package resolve
 
 import (
+	"context"
 	"fmt"
 	"sync"
 
-	"github.com/rs/xid"
+	"github.com/projectdiscovery/gologger"
 )
 
 const (
@@ -20,7 +21,9 @@ type ResolutionPool struct {
 	wg             *sync.WaitGroup
 	removeWildcard bool
 
-	wildcardIPs map[string]struct{}
+	// zones caches the wildcard verdict of the parent zones of the hosts
+	zones      map[string]*zoneVerdict
+	zonesMutex sync.Mutex
 }
 
 // HostEntry defines a host with the source
@@ -37,6 +40,25 @@ type Result struct {
 	IP     string
 	Error  error
 	Source string
+	// Records holds the answers of the queried record types, nil when
+	// no record types were asked
+	Records *Records
+	// Classification is the wildcard verdict of the host
+	Classification Classification
+}
+
+// Records contains the answers for a host by record type. The CNAME
+// records hold the chain followed while answering the other types.
+type Records struct {
+	A     []string `json:"a,omitempty"`
+	AAAA  []string `json:"aaaa,omitempty"`
+	CNAME []string `json:"cname,omitempty"`
+	MX    []string `json:"mx,omitempty"`
+	TXT   []string `json:"txt,omitempty"`
+}
+
+func (r *Records) empty() bool {
+	return len(r.A)+len(r.AAAA)+len(r.CNAME)+len(r.MX)+len(r.TXT) == 0
 }
 
 // ResultType is the type of result found
@@ -56,7 +78,7 @@ func (r *Resolver) NewResolutionPool(workers int, removeWildcard bool) *Resoluti
 		Results:        make(chan Result),
 		wg:             &sync.WaitGroup{},
 		removeWildcard: removeWildcard,
-		wildcardIPs:    make(map[string]struct{}),
+		zones:          make(map[string]*zoneVerdict),
 	}
 
 	go func() {
@@ -71,22 +93,48 @@ func (r *Resolver) NewResolutionPool(workers int, removeWildcard bool) *Resoluti
 	return resolutionPool
 }
 
-// InitWildcards inits the wildcard ips array
+// InitWildcards checks whether the domain itself is a wildcard zone. The
+// zones under it are checked as the hosts are resolved.
 func (r *ResolutionPool) InitWildcards(domain string) error {
-	for i := 0; i < maxWildcardChecks; i++ {
-		uid := xid.New().String()
+	if !r.zone(domain, nil).wildcard {
+		return fmt.Errorf("%s is not a wildcard domain", domain)
+	}
+	return nil
+}
 
-		hosts, _ := r.DNSClient.Lookup(uid + "." + domain)
-		if len(hosts) == 0 {
-			return fmt.Errorf("%s is not a wildcard domain", domain)
-		}
+// ResolveCandidates resolves guessed hosts of a domain with a pool of
+// workers and returns the ones which exist, without the wildcard answers.
+// The channel is closed once every candidate was resolved or the context is
+// done, it must be read until then.
+func (r *Resolver) ResolveCandidates(ctx context.Context, domain, source string, hosts []string, workers int) <-chan string {
+	found := make(chan string)
+	pool := r.NewResolutionPool(workers, true)
 
-		// Append all wildcard ips found for domains
+	go func() {
+		defer close(pool.Tasks)
 		for _, host := range hosts {
-			r.wildcardIPs[host] = struct{}{}
+			select {
+			case <-ctx.Done():
+				return
+			case pool.Tasks <- HostEntry{Domain: domain, Host: host, Source: source}:
+			}
 		}
-	}
-	return nil
+	}()
+
+	go func() {
+		defer close(found)
+		for result := range pool.Results {
+			switch result.Type {
+			case Error:
+				// Most candidates don't exist, failed lookups aren't worth reporting
+				gologger.Debug().Msgf("Could not resolve %s: %s\n", result.Host, result.Error)
+			case Subdomain:
+				found <- result.Host
+			}
+		}
+	}()
+
+	return found
 }
 
 func (r *ResolutionPool) resolveWorker() {
@@ -96,28 +144,55 @@ func (r *ResolutionPool) resolveWorker() {
 			continue
 		}
 
-		hosts, err := r.DNSClient.Lookup(task.Host)
+		answer, err := r.lookup(task.Host)
 		if err != nil {
 			r.Results <- Result{Type: Error, Host: task.Host, Source: task.Source, Error: err}
 			continue
 		}
 
-		if len(hosts) == 0 {
+		// Hosts without addresses are only kept for their other records
+		if len(answer.ips) == 0 && (answer.records == nil || answer.records.empty()) {
 			continue
 		}
 
-		var skip bool
-		for _, host := range hosts {
-			// Ignore the host if it exists in wildcard ips map
-			if _, ok := r.wildcardIPs[host]; ok {
-				skip = true
-				break
+		// Ignore the host if a wildcard of one of its parent zones answered it
+		classification := r.classify(task.Domain, task.Host, answer)
+		if classification != Wildcard {
+			var ip string
+			if len(answer.ips) > 0 {
+				ip = answer.ips[0]
 			}
-		}
-
-		if !skip {
-			r.Results <- Result{Type: Subdomain, Host: task.Host, IP: hosts[0], Source: task.Source}
+			r.Results <- Result{Type: Subdomain, Host: task.Host, IP: ip, Source: task.Source, Records: answer.records, Classification: classification}
 		}
 	}
 	r.wg.Done()
 }
+
+// hostAnswer is what the resolvers answered for a host
+type hostAnswer struct {
+	ips []string
+	// cnames is the CNAME chain followed to the IPs
+	cnames []string
+	ttl    uint32
+	// records holds the answers of the record types asked, if any
+	records *Records
+}
+
+// lookup queries the question types of the DNS client for a host, or only
+// looks up its IPs when there are none
+func (r *ResolutionPool) lookup(host string) (*hostAnswer, error) {
+	if len(r.DNSClient.Options.QuestionTypes) == 0 {
+		ips, err := r.DNSClient.Lookup(host)
+		return &hostAnswer{ips: ips}, err
+	}
+
+	data, err := r.DNSClient.QueryMultiple(host)
+	if err != nil {
+		return nil, err
+	}
+	answer := &hostAnswer{ips: data.A, cnames: data.CNAME, ttl: data.TTL}
+	if len(r.RecordTypes) > 0 {
+		answer.records = &Records{A: data.A, AAAA: data.AAAA, CNAME: data.CNAME, MX: data.MX, TXT: data.TXT}
+	}
+	return answer, nil
+}
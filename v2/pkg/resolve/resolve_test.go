@@ -0,0 +1,233 @@
+package resolve
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/projectdiscovery/dnsx/libs/dnsx"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/maps"
+)
+
+// startResolver starts a DNS server on 127.0.0.1 answering from records
+func startResolver(t *testing.T, records ...string) string {
+	t.Helper()
+
+	var answers []dns.RR
+	for _, record := range records {
+		rr, err := dns.NewRR(record)
+		require.NoError(t, err)
+		answers = append(answers, rr)
+	}
+
+	// owner returns the owner name of the records of a name, its closest
+	// wildcard when it has none
+	owner := func(name string) string {
+		for candidate := name; ; {
+			for _, answer := range answers {
+				if answer.Header().Name == candidate {
+					return candidate
+				}
+			}
+			_, parent, ok := strings.Cut(strings.TrimPrefix(candidate, "*."), ".")
+			if !ok || parent == "" {
+				return name
+			}
+			candidate = "*." + parent
+		}
+	}
+
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		msg := new(dns.Msg)
+		msg.SetReply(r)
+		question := r.Question[0]
+		// Follow the CNAME chain like a recursive resolver
+		for name := question.Name; name != ""; {
+			target := ""
+			for _, answer := range answers {
+				if answer.Header().Name != owner(name) {
+					continue
+				}
+				if answer.Header().Rrtype != question.Qtype && answer.Header().Rrtype != dns.TypeCNAME {
+					continue
+				}
+				answer = dns.Copy(answer)
+				answer.Header().Name = name
+				msg.Answer = append(msg.Answer, answer)
+				if cname, ok := answer.(*dns.CNAME); ok && question.Qtype != dns.TypeCNAME {
+					target = cname.Target
+				}
+			}
+			name = target
+		}
+		_ = w.WriteMsg(msg)
+	})
+
+	packetConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &dns.Server{PacketConn: packetConn, Handler: handler}
+	go func() { _ = server.ActivateAndServe() }()
+	t.Cleanup(func() { _ = server.Shutdown() })
+	return packetConn.LocalAddr().String()
+}
+
+// resolveHosts resolves hosts of example.com with the resolver, given
+// without DNS client, and returns the results by host
+func resolveHosts(t *testing.T, resolver *Resolver, questionTypes []uint16, hosts ...string) (map[string]Result, *ResolutionPool) {
+	t.Helper()
+
+	address := startResolver(t,
+		"www.example.com. 60 IN CNAME edge.cloud.example.net.",
+		"edge.cloud.example.net. 60 IN A 192.0.2.1",
+		"edge.cloud.example.net. 60 IN AAAA 2001:db8::1",
+		"mail.example.com. 60 IN MX 10 mx.example.net.",
+		"mail.example.com. 60 IN TXT \"v=spf1 -all\"",
+		"*.dev.example.com. 60 IN A 192.0.2.50",
+		"api.dev.example.com. 60 IN A 192.0.2.7",
+		"*.k8s.prod.example.com. 60 IN CNAME ingress.example.net.",
+		"ingress.example.net. 60 IN A 192.0.2.60",
+		"*.eu.k8s.prod.example.com. 60 IN A 192.0.2.60",
+		"own.k8s.prod.example.com. 60 IN CNAME other.example.net.",
+		"other.example.net. 60 IN A 192.0.2.60",
+		"*.cdn.example.com. 60 IN A 192.0.2.80",
+		"*.cdn.example.com. 60 IN A 192.0.2.81",
+		"shop.cdn.example.com. 3600 IN A 192.0.2.80",
+		"mixed.cdn.example.com. 60 IN A 192.0.2.80",
+		"mixed.cdn.example.com. 60 IN A 192.0.2.99",
+		"same.cdn.example.com. 60 IN A 192.0.2.81",
+	)
+	client, err := dnsx.New(dnsx.Options{BaseResolvers: []string{address}, MaxRetries: 1, QuestionTypes: questionTypes})
+	require.NoError(t, err)
+
+	resolver.DNSClient = client
+	pool := resolver.NewResolutionPool(2, true)
+	go func() {
+		for _, host := range hosts {
+			pool.Tasks <- HostEntry{Domain: "example.com", Host: host, Source: "test"}
+		}
+		close(pool.Tasks)
+	}()
+
+	results := make(map[string]Result)
+	for result := range pool.Results {
+		results[result.Host] = result
+	}
+	return results, pool
+}
+
+func TestResolutionPoolRecords(t *testing.T) {
+	recordTypes := []uint16{dns.TypeA, dns.TypeAAAA, dns.TypeCNAME, dns.TypeMX, dns.TypeTXT}
+	results, _ := resolveHosts(t, &Resolver{RecordTypes: recordTypes}, recordTypes, "www.example.com", "mail.example.com", "missing.example.com")
+	require.Len(t, results, 2, "hosts without any record are dropped")
+
+	www := results["www.example.com"]
+	require.Equal(t, "192.0.2.1", www.IP)
+	require.Equal(t, &Records{
+		A:     []string{"192.0.2.1"},
+		AAAA:  []string{"2001:db8::1"},
+		CNAME: []string{"edge.cloud.example.net"},
+	}, www.Records)
+
+	mail := results["mail.example.com"]
+	require.Empty(t, mail.IP)
+	require.Equal(t, []string{"mx.example.net"}, mail.Records.MX)
+	require.Equal(t, []string{"v=spf1 -all"}, mail.Records.TXT)
+}
+
+func TestResolutionPoolLookup(t *testing.T) {
+	results, _ := resolveHosts(t, &Resolver{}, nil, "www.example.com", "mail.example.com")
+	require.Equal(t, Result{Type: Subdomain, Host: "www.example.com", IP: "192.0.2.1", Source: "test", Classification: Real}, results["www.example.com"])
+	require.Equal(t, Error, results["mail.example.com"].Type)
+}
+
+func TestResolutionPoolNestedWildcards(t *testing.T) {
+	results, pool := resolveHosts(t, &Resolver{}, nil,
+		"www.example.com",
+		"a.dev.example.com",
+		"b.c.dev.example.com",
+		"api.dev.example.com",
+		"web.k8s.prod.example.com",
+		"web.eu.k8s.prod.example.com",
+	)
+	require.ElementsMatch(t, []string{"www.example.com", "api.dev.example.com"}, maps.Keys(results))
+
+	// eu.k8s.prod.example.com answers like its parent wildcard and is reported as it
+	require.Equal(t, []WildcardZone{
+		{Zone: "dev.example.com", Answers: []string{"192.0.2.50"}, Filtered: 2},
+		{Zone: "k8s.prod.example.com", Answers: []string{"192.0.2.60"}, Filtered: 2},
+	}, pool.Wildcards())
+	require.Error(t, pool.InitWildcards("example.com"))
+}
+
+func TestParentZones(t *testing.T) {
+	require.Equal(t, []string{"example.com", "prod.example.com", "k8s.prod.example.com"}, parentZones("example.com", "web.k8s.prod.example.com"))
+	require.Equal(t, []string{"example.com"}, parentZones("example.com", "www.example.com"))
+	require.Equal(t, []string{"example.com"}, parentZones("example.com", "example.com"))
+}
+
+func TestResolutionPoolClassification(t *testing.T) {
+	results, pool := resolveHosts(t, &Resolver{}, []uint16{dns.TypeA},
+		"own.k8s.prod.example.com",
+		"web.k8s.prod.example.com",
+		"shop.cdn.example.com",
+		"mixed.cdn.example.com",
+		"same.cdn.example.com",
+	)
+	classifications := make(map[string]Classification)
+	for host, result := range results {
+		classifications[host] = result.Classification
+	}
+	// own.k8s.prod.example.com shares its IP with the wildcard but has its own CNAME target
+	require.Equal(t, map[string]Classification{
+		"own.k8s.prod.example.com": Real,
+		"shop.cdn.example.com":     Uncertain,
+		"mixed.cdn.example.com":    Uncertain,
+	}, classifications)
+
+	require.Equal(t, []WildcardZone{
+		{Zone: "cdn.example.com", Answers: []string{"192.0.2.80", "192.0.2.81"}, Filtered: 1, Uncertain: 2},
+		{Zone: "k8s.prod.example.com", Answers: []string{"192.0.2.60", "ingress.example.net"}, Filtered: 1},
+	}, pool.Wildcards())
+}
+
+func TestResolutionPoolHTTPClassification(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host == "same.cdn.example.com" {
+			_, _ = w.Write([]byte("<html><title>Shop</title></html>"))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("<html><title>No such site: " + r.Host + "</title></html>"))
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+		},
+	}}
+
+	results, _ := resolveHosts(t, &Resolver{HTTPClient: client}, []uint16{dns.TypeA}, "same.cdn.example.com", "random.cdn.example.com")
+	require.Len(t, results, 1)
+	require.Equal(t, Real, results["same.cdn.example.com"].Classification)
+}
+
+func TestResolveCandidates(t *testing.T) {
+	address := startResolver(t,
+		"www.example.com. 60 IN A 192.0.2.1",
+		"*.dev.example.com. 60 IN A 192.0.2.50",
+	)
+	client, err := dnsx.New(dnsx.Options{BaseResolvers: []string{address}, MaxRetries: 1, QuestionTypes: []uint16{dns.TypeA}})
+	require.NoError(t, err)
+	resolver := &Resolver{DNSClient: client}
+
+	var found []string
+	for host := range resolver.ResolveCandidates(context.Background(), "example.com", "test", []string{"www.example.com", "missing.example.com", "api.dev.example.com"}, 2) {
+		found = append(found, host)
+	}
+	require.Equal(t, []string{"www.example.com"}, found, "missing and wildcard candidates are dropped")
+}
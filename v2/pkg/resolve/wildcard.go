@@ -0,0 +1,310 @@
+package resolve
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/rs/xid"
+	"golang.org/x/exp/maps"
+)
+
+// Classification is the verdict of the wildcard classifier for a host
+type Classification string
+
+const (
+	// Real hosts have their own records
+	Real Classification = "real"
+	// Wildcard hosts are answered by the wildcard of one of their parent zones
+	Wildcard Classification = "wildcard"
+	// Uncertain hosts share part of their answers with a wildcard
+	Uncertain Classification = "uncertain"
+)
+
+// maxHTTPBody is the size of the response bodies read for the HTTP comparison
+const maxHTTPBody = 256 * 1024
+
+// WildcardZone is a zone answering for any name under it
+type WildcardZone struct {
+	// Zone is the parent of the wildcard, dev.example.com for *.dev.example.com
+	Zone string
+	// Answers are the IPs and CNAME targets returned for random names under the zone
+	Answers []string
+	// Filtered is the number of hosts removed as answered by the wildcard
+	Filtered int
+	// Uncertain is the number of hosts kept which might be answered by the wildcard
+	Uncertain int
+}
+
+// zoneVerdict caches whether a zone is a wildcard and what it answers
+type zoneVerdict struct {
+	name string
+	once sync.Once
+
+	wildcard bool
+	ips      map[string]struct{}
+	cnames   map[string]struct{}
+	maxTTL   uint32
+	// sample is a random name answered by the wildcard
+	sample string
+	// reported is the wildcard zone the classified hosts are counted for:
+	// the zone itself, or the parent wildcard it is covered by
+	reported  *zoneVerdict
+	filtered  int
+	uncertain int
+
+	httpOnce sync.Once
+	http     *httpFingerprint
+}
+
+// zone returns the verdict of a zone, probing it on first use. The verdict
+// of its parent zone, if any, must be given.
+func (r *ResolutionPool) zone(name string, parent *zoneVerdict) *zoneVerdict {
+	r.zonesMutex.Lock()
+	verdict, ok := r.zones[name]
+	if !ok {
+		verdict = &zoneVerdict{name: name}
+		r.zones[name] = verdict
+	}
+	r.zonesMutex.Unlock()
+
+	verdict.once.Do(func() {
+		r.probe(verdict, parent)
+	})
+	return verdict
+}
+
+// probe resolves random names under a zone, which is a wildcard when all of
+// them resolve. A zone answering like its parent wildcard is covered by it.
+func (r *ResolutionPool) probe(verdict *zoneVerdict, parent *zoneVerdict) {
+	ips := make(map[string]struct{})
+	cnames := make(map[string]struct{})
+	var maxTTL uint32
+	var sample string
+	for i := 0; i < maxWildcardChecks; i++ {
+		name := xid.New().String() + "." + verdict.name
+		answer, _ := r.lookup(name)
+		if answer == nil || len(answer.ips) == 0 && len(answer.cnames) == 0 {
+			return
+		}
+		for _, ip := range answer.ips {
+			ips[ip] = struct{}{}
+		}
+		for _, cname := range answer.cnames {
+			cnames[cname] = struct{}{}
+		}
+		maxTTL = max(maxTTL, answer.ttl)
+		sample = name
+	}
+	verdict.wildcard = true
+	verdict.ips = ips
+	verdict.cnames = cnames
+	verdict.maxTTL = maxTTL
+	verdict.sample = sample
+	verdict.reported = verdict
+
+	if parent != nil && parent.wildcard && subset(ips, parent.ips) && subset(cnames, parent.cnames) {
+		verdict.reported = parent.reported
+		return
+	}
+	gologger.Info().Msgf("Found wildcard zone *.%s answering %s\n", verdict.name, strings.Join(verdict.answers(), ", "))
+}
+
+// answers returns the sorted CNAME targets and IPs of a wildcard
+func (verdict *zoneVerdict) answers() []string {
+	answers := append(maps.Keys(verdict.cnames), maps.Keys(verdict.ips)...)
+	sort.Strings(answers)
+	return answers
+}
+
+// compare classifies the answer for a host against the wildcard of the zone.
+// A host with its own CNAME target, or with IPs the wildcard never returned,
+// is real. A host sharing only part of its IPs with the wildcard, or having
+// a longer TTL than the wildcard answers, is uncertain.
+func (verdict *zoneVerdict) compare(answer *hostAnswer) Classification {
+	if len(answer.cnames) > 0 || len(verdict.cnames) > 0 {
+		for _, cname := range answer.cnames {
+			if _, ok := verdict.cnames[cname]; ok {
+				return Wildcard
+			}
+		}
+		return Real
+	}
+
+	var shared int
+	for _, ip := range answer.ips {
+		if _, ok := verdict.ips[ip]; ok {
+			shared++
+		}
+	}
+	switch {
+	case shared == 0:
+		return Real
+	case shared < len(answer.ips):
+		return Uncertain
+	case verdict.maxTTL > 0 && answer.ttl > verdict.maxTTL:
+		// Cached answers count down from the TTL of their record, a longer
+		// TTL comes from another record
+		return Uncertain
+	default:
+		return Wildcard
+	}
+}
+
+// classify compares the answer for a host with the wildcards of its parent
+// zones, from the domain down to its direct parent. When the HTTP client is
+// set, the hosts not told apart by DNS are compared with the wildcard over HTTP.
+func (r *ResolutionPool) classify(domain, host string, answer *hostAnswer) Classification {
+	classification := Real
+	var uncertain *zoneVerdict
+	var parent *zoneVerdict
+	for _, name := range parentZones(domain, host) {
+		verdict := r.zone(name, parent)
+		parent = verdict
+		if !verdict.wildcard {
+			continue
+		}
+
+		zoneClassification := verdict.compare(answer)
+		if zoneClassification != Real && r.HTTPClient != nil {
+			zoneClassification = r.compareHTTP(verdict, host, zoneClassification)
+		}
+		switch zoneClassification {
+		case Wildcard:
+			r.zonesMutex.Lock()
+			verdict.reported.filtered++
+			r.zonesMutex.Unlock()
+			return Wildcard
+		case Uncertain:
+			classification = Uncertain
+			uncertain = verdict
+		}
+	}
+
+	if uncertain != nil {
+		r.zonesMutex.Lock()
+		uncertain.reported.uncertain++
+		r.zonesMutex.Unlock()
+	}
+	return classification
+}
+
+// compareHTTP fetches the host and a name answered by the wildcard, the host
+// is a wildcard when the responses look alike. The DNS classification is kept
+// when either can't be fetched.
+func (r *ResolutionPool) compareHTTP(verdict *zoneVerdict, host string, classification Classification) Classification {
+	verdict.httpOnce.Do(func() {
+		fingerprint, err := r.fetchFingerprint(verdict.sample)
+		if err != nil {
+			gologger.Debug().Msgf("Could not fetch wildcard zone *.%s: %s\n", verdict.name, err)
+			return
+		}
+		verdict.http = fingerprint
+	})
+	if verdict.http == nil {
+		return classification
+	}
+
+	fingerprint, err := r.fetchFingerprint(host)
+	if err != nil {
+		gologger.Debug().Msgf("Could not fetch %s: %s\n", host, err)
+		return classification
+	}
+	if fingerprint.similar(verdict.http) {
+		return Wildcard
+	}
+	return Real
+}
+
+// httpFingerprint summarizes a response with the host name removed
+type httpFingerprint struct {
+	status   int
+	location string
+	title    string
+	length   int
+}
+
+var titleRegex = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+func (r *ResolutionPool) fetchFingerprint(host string) (*httpFingerprint, error) {
+	resp, err := r.HTTPClient.Get(fmt.Sprintf("http://%s/", host))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPBody))
+	if err != nil {
+		return nil, err
+	}
+	normalized := strings.ReplaceAll(strings.ToLower(string(body)), host, "")
+	fingerprint := &httpFingerprint{
+		status:   resp.StatusCode,
+		location: strings.ReplaceAll(strings.ToLower(resp.Header.Get("Location")), host, ""),
+		length:   len(normalized),
+	}
+	if match := titleRegex.FindStringSubmatch(normalized); match != nil {
+		fingerprint.title = strings.TrimSpace(match[1])
+	}
+	return fingerprint, nil
+}
+
+// similar returns true when the responses have the same status, redirect and
+// title, and bodies whose lengths differ by less than 5%
+func (f *httpFingerprint) similar(other *httpFingerprint) bool {
+	if f.status != other.status || f.location != other.location || f.title != other.title {
+		return false
+	}
+	difference := f.length - other.length
+	if difference < 0 {
+		difference = -difference
+	}
+	return difference*20 <= max(f.length, other.length)
+}
+
+// parentZones returns the domain and the zones between it and the host, the
+// parents of the host not under the domain are not checked
+func parentZones(domain, host string) []string {
+	zones := []string{domain}
+	if !strings.HasSuffix(host, "."+domain) {
+		return zones
+	}
+	labels := strings.Split(strings.TrimSuffix(host, "."+domain), ".")
+	for i := len(labels) - 1; i > 0; i-- {
+		zones = append(zones, strings.Join(labels[i:], ".")+"."+domain)
+	}
+	return zones
+}
+
+func subset(set, of map[string]struct{}) bool {
+	for value := range set {
+		if _, ok := of[value]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Wildcards returns the wildcard zones found, sorted by name. Zones covered by
+// a parent wildcard are reported as the parent. It must be called once the
+// results were all read.
+func (r *ResolutionPool) Wildcards() []WildcardZone {
+	r.zonesMutex.Lock()
+	defer r.zonesMutex.Unlock()
+
+	var zones []WildcardZone
+	for _, verdict := range r.zones {
+		if !verdict.wildcard || verdict.reported != verdict {
+			continue
+		}
+		zones = append(zones, WildcardZone{Zone: verdict.name, Answers: verdict.answers(), Filtered: verdict.filtered, Uncertain: verdict.uncertain})
+	}
+	sort.Slice(zones, func(i, j int) bool {
+		return zones[i].Zone < zones[j].Zone
+	})
+	return zones
+}
@@ -0,0 +1,93 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/subfinder/v2/pkg/passive"
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
+	"golang.org/x/exp/maps"
+)
+
+// CheckKeys checks the API keys of the provider config against the account
+// or quota endpoints of the sources and prints their status. An error is
+// returned when any key is invalid.
+func (r *Runner) CheckKeys(ctx context.Context) error {
+	sources := passive.Sources(r.options.CustomSources...)
+	checks, err := passive.CheckSourceKeys(ctx, sources, r.options.Proxy, r.options.Timeout, passive.WithCassette(r.cassette))
+	if err != nil {
+		return err
+	}
+
+	// Keys of the sources unable to check them are reported with the reason
+	providerKeys, _, _ := readProviderConfig(r.providerConfig)
+	var unchecked []string
+	for _, source := range sources {
+		if _, ok := source.(subscraping.KeyChecker); ok || !source.NeedsKey() {
+			continue
+		}
+		if len(providerKeys[strings.ToLower(source.Name())]) == 0 {
+			continue
+		}
+		reason := "unknown"
+		if unsupported, ok := source.(subscraping.KeyCheckUnsupported); ok {
+			reason = unsupported.KeyCheckUnsupported()
+		}
+		unchecked = append(unchecked, fmt.Sprintf("%-20s %s", source.Name(), reason))
+	}
+
+	printKeyChecks(checks, unchecked)
+
+	var invalid []string
+	for source, sourceChecks := range checks {
+		for _, check := range sourceChecks {
+			if check.Status == subscraping.KeyInvalid {
+				invalid = append(invalid, source)
+				break
+			}
+		}
+	}
+	if len(invalid) > 0 {
+		sort.Strings(invalid)
+		return fmt.Errorf("invalid api keys for %s", strings.Join(invalid, ", "))
+	}
+	return nil
+}
+
+func printKeyChecks(checks map[string][]subscraping.KeyCheck, unchecked []string) {
+	sources := maps.Keys(checks)
+	sort.Strings(sources)
+
+	var lines []string
+	for _, source := range sources {
+		for _, check := range checks[source] {
+			remaining := "-"
+			if check.Remaining >= 0 {
+				remaining = strconv.Itoa(check.Remaining)
+			}
+			details := ""
+			if check.Error != nil {
+				details = check.Error.Error()
+			}
+			lines = append(lines, fmt.Sprintf(" %-20s %-20s %-10s %10s  %s", source, check.Key, check.Status, remaining, details))
+		}
+	}
+
+	if len(lines) > 0 {
+		gologger.Print().Msgf("\n Source               Key                  Status      Remaining  Details\n%s\n", strings.Repeat("─", 76))
+		gologger.Print().Msgf(strings.Join(lines, "\n"))
+		gologger.Print().Msgf("\n")
+	} else {
+		gologger.Info().Msgf("No API keys to check were found in the provider config")
+	}
+
+	if len(unchecked) > 0 {
+		gologger.Print().Msgf("\n The keys of the following sources cannot be checked...\n\n")
+		gologger.Print().Msgf(" " + strings.Join(unchecked, "\n "))
+		gologger.Print().Msgf("\n\n")
+	}
+}
@@ -1,6 +1,7 @@
 package runner
 
 import (
+	"fmt"
 	"os"
 	"strings"
 
@@ -8,6 +9,7 @@ import (
 
 	"github.com/projectdiscovery/gologger"
 	"github.com/projectdiscovery/subfinder/v2/pkg/passive"
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
 	fileutil "github.com/projectdiscovery/utils/file"
 )
 
@@ -30,16 +32,25 @@ func createProviderConfigYAML(configFilePath string) error {
 	return yaml.NewEncoder(configFile).Encode(sourcesRequiringApiKeysMap)
 }
 
+// providerBudgetsKey is the entry of the provider config holding the request
+// budgets, by source name or by source:key for the budget of a key
+const providerBudgetsKey = subscraping.ProviderBudgetsEntry
+
 // UnmarshalFrom writes the marshaled yaml config to disk
 func UnmarshalFrom(file string) error {
-	reader, err := fileutil.SubstituteConfigFromEnvVars(file)
-	if err != nil {
-		return err
+	_, err := loadProviderConfig(file)
+	return err
+}
+
+// loadProviderConfig adds the API keys of the provider config to the
+// registered sources and the given ones, and returns its budgets
+func loadProviderConfig(file string, sources ...subscraping.Source) (map[string]string, error) {
+	sourceApiKeysMap, budgets, err := readProviderConfig(file)
+	if sourceApiKeysMap == nil {
+		return nil, err
 	}
 
-	sourceApiKeysMap := map[string][]string{}
-	err = yaml.NewDecoder(reader).Decode(sourceApiKeysMap)
-	for _, source := range passive.AllSources {
+	for _, source := range passive.Sources(sources...) {
 		sourceName := strings.ToLower(source.Name())
 		apiKeys := sourceApiKeysMap[sourceName]
 		if source.NeedsKey() && apiKeys != nil && len(apiKeys) > 0 {
@@ -47,5 +58,38 @@ func UnmarshalFrom(file string) error {
 			source.AddApiKeys(apiKeys)
 		}
 	}
-	return err
+	return budgets, err
+}
+
+// readProviderConfig reads the API keys of the provider config by source name, and its budgets
+func readProviderConfig(file string) (map[string][]string, map[string]string, error) {
+	reader, err := fileutil.SubstituteConfigFromEnvVars(file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entries := map[string]yaml.Node{}
+	err = yaml.NewDecoder(reader).Decode(entries)
+
+	sourceApiKeysMap := map[string][]string{}
+	budgets := map[string]string{}
+	// A malformed entry doesn't prevent the others from being used
+	for name, entry := range entries {
+		var decodeErr error
+		if name == providerBudgetsKey {
+			if decodeErr = entry.Decode(budgets); decodeErr != nil {
+				decodeErr = fmt.Errorf("invalid budgets: %w", decodeErr)
+			}
+		} else {
+			var apiKeys []string
+			if decodeErr = entry.Decode(&apiKeys); decodeErr != nil {
+				decodeErr = fmt.Errorf("invalid keys for %s: %w", name, decodeErr)
+			}
+			sourceApiKeysMap[name] = apiKeys
+		}
+		if err == nil {
+			err = decodeErr
+		}
+	}
+	return sourceApiKeysMap, budgets, err
 }
@@ -0,0 +1,40 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
+)
+
+// keyedSource records the keys it is given
+type keyedSource struct {
+	keys []string
+}
+
+func (s *keyedSource) Run(_ context.Context, _ string, _ *subscraping.Session) <-chan subscraping.Result {
+	results := make(chan subscraping.Result)
+	close(results)
+	return results
+}
+
+func (s *keyedSource) Name() string              { return "inhouse" }
+func (s *keyedSource) IsDefault() bool           { return true }
+func (s *keyedSource) HasRecursiveSupport() bool { return false }
+func (s *keyedSource) NeedsKey() bool            { return true }
+func (s *keyedSource) AddApiKeys(keys []string)  { s.keys = keys }
+
+func TestLoadProviderConfigCustomSources(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "provider-config.yaml")
+	require.NoError(t, os.WriteFile(file, []byte("inhouse:\n  - first\n  - second\nbudgets:\n  inhouse: 10/day\n"), 0600))
+
+	source := &keyedSource{}
+	budgets, err := loadProviderConfig(file, source)
+	require.NoError(t, err)
+	require.Equal(t, []string{"first", "second"}, source.keys)
+	require.Equal(t, map[string]string{"inhouse": "10/day"}, budgets)
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/hako/durafmt"
+	"golang.org/x/exp/slices"
 
 	"github.com/projectdiscovery/gologger"
 
@@ -31,8 +32,19 @@ func (r *Runner) EnumerateSingleDomain(domain string, writers []io.Writer) error
 
 // EnumerateSingleDomainWithCtx performs subdomain enumeration against a single domain
 func (r *Runner) EnumerateSingleDomainWithCtx(ctx context.Context, domain string, writers []io.Writer) error {
+	return r.enumerateSingleDomainWithCtx(ctx, domain, writers)
+}
+
+func (r *Runner) enumerateSingleDomainWithCtx(ctx context.Context, domain string, writers []io.Writer, options ...passive.EnumerateOption) error {
 	gologger.Info().Msgf("Enumerating subdomains for %s\n", domain)
 
+	// The budget usage is saved even when the enumeration is cancelled or fails
+	defer func() {
+		if err := r.budgets.Save(); err != nil {
+			gologger.Warning().Msgf("Could not save the budget usage: %s\n", err)
+		}
+	}()
+
 	// Check if the user has asked to remove wildcards explicitly.
 	// If yes, create the resolution pool and get the wildcards for the current domain
 	var resolutionPool *resolve.ResolutionPool
@@ -47,7 +59,14 @@ func (r *Runner) EnumerateSingleDomainWithCtx(ctx context.Context, domain string
 
 	// Run the passive subdomain enumeration
 	now := time.Now()
-	passiveResults := r.passiveAgent.EnumerateSubdomainsWithCtx(ctx, domain, r.options.Proxy, r.options.RateLimit, r.options.Timeout, time.Duration(r.options.MaxEnumerationTime)*time.Minute, passive.WithCustomRateLimit(r.rateLimit))
+	runStatistics := subscraping.NewRunStatistics(domain)
+	// Clip the options of the caller, domains enumerated concurrently must not append to the same array
+	options = append(slices.Clip(options), passive.WithCustomRateLimit(r.rateLimit), passive.WithStatistics(runStatistics), passive.WithSourceTimeouts(r.options.sourceTimeouts), r.retryPolicyOption(), passive.WithResponseCache(r.responseCache), passive.WithCassette(r.cassette), passive.WithBudgets(r.budgets))
+	var passiveResults <-chan subscraping.Result = r.passiveAgent.EnumerateSubdomainsWithCtx(ctx, domain, r.options.Proxy, r.options.RateLimit, r.options.Timeout, time.Duration(r.options.MaxEnumerationTime)*time.Minute, options...)
+	// The permutations of the subdomains found are resolved once the sources are done
+	if r.permutations != nil {
+		passiveResults = r.permute(ctx, domain, passiveResults, runStatistics)
+	}
 
 	wg := &sync.WaitGroup{}
 	wg.Add(1)
@@ -56,6 +75,10 @@ func (r *Runner) EnumerateSingleDomainWithCtx(ctx context.Context, domain string
 	// Create a map to track sources for each host
 	sourceMap := make(map[string]map[string]struct{})
 	skippedCounts := make(map[string]int)
+	outputWriter := NewOutputWriter(r.options.JSON)
+	outputWriter.HostIP = r.options.HostIP
+	// streamErr keeps the first error hit while streaming results to the writers
+	var streamErr error
 	// Process the results in a separate goroutine
 	go func() {
 		for result := range passiveResults {
@@ -76,7 +99,8 @@ func (r *Runner) EnumerateSingleDomainWithCtx(ctx context.Context, domain string
 					}
 
 					// Log the verbose message about the found subdomain per source
-					if _, ok := sourceMap[subdomain][result.Source]; !ok {
+					_, knownSource := sourceMap[subdomain][result.Source]
+					if !knownSource {
 						gologger.Verbose().Label(result.Source).Msg(subdomain)
 					}
 
@@ -86,6 +110,12 @@ func (r *Runner) EnumerateSingleDomainWithCtx(ctx context.Context, domain string
 					// send the subdomain for resolution.
 					if _, ok := uniqueMap[subdomain]; ok {
 						skippedCounts[result.Source]++
+						// When streaming, send the updated source list of the host as a later event
+						if r.options.Stream && r.options.CaptureSources && !r.options.RemoveWildcard && !knownSource {
+							if err := r.streamSources(outputWriter, domain, subdomain, sourceMap[subdomain], writers); err != nil && streamErr == nil {
+								streamErr = err
+							}
+						}
 						continue
 					}
 
@@ -93,10 +123,20 @@ func (r *Runner) EnumerateSingleDomainWithCtx(ctx context.Context, domain string
 
 					uniqueMap[subdomain] = hostEntry
 					// If the user asked to remove wildcard then send on the resolve
-					// queue. Otherwise, if mode is not verbose print the results on
-					// the screen as they are discovered.
+					// queue. Otherwise, if streaming is asked, write the result
+					// to the outputs as soon as it is discovered.
 					if r.options.RemoveWildcard {
 						resolutionPool.Tasks <- hostEntry
+					} else if r.options.Stream {
+						var err error
+						if r.options.CaptureSources {
+							err = r.streamSources(outputWriter, domain, subdomain, sourceMap[subdomain], writers)
+						} else {
+							err = r.streamHost(outputWriter, domain, hostEntry, writers)
+						}
+						if err != nil && streamErr == nil {
+							streamErr = err
+						}
 					}
 				}
 			}
@@ -121,16 +161,35 @@ func (r *Runner) EnumerateSingleDomainWithCtx(ctx context.Context, domain string
 				// Add the found subdomain to a map.
 				if _, ok := foundResults[result.Host]; !ok {
 					foundResults[result.Host] = result
+					if r.options.Stream {
+						if err := r.streamResult(outputWriter, domain, result, writers); err != nil && streamErr == nil {
+							streamErr = err
+						}
+					}
 				}
 			}
 		}
 	}
 	wg.Wait()
-	outputWriter := NewOutputWriter(r.options.JSON)
-	// Now output all results in output writers
+
+	// Domains may be enumerated concurrently, keep their outputs from interleaving
+	r.outputMutex.Lock()
+	defer r.outputMutex.Unlock()
+
+	if streamErr != nil {
+		gologger.Error().Msgf("Could not write results for %s: %s\n", domain, streamErr)
+		return streamErr
+	}
+
+	// Now output all results in output writers. When streaming,
+	// the results were already written as they were found.
+	bulkWriters := writers
+	if r.options.Stream {
+		bulkWriters = nil
+	}
 	var err error
-	for _, writer := range writers {
-		if r.options.HostIP {
+	for _, writer := range bulkWriters {
+		if r.options.writesResolved() {
 			err = outputWriter.WriteHostIP(domain, foundResults, writer)
 		} else {
 			if r.options.RemoveWildcard {
@@ -149,6 +208,13 @@ func (r *Runner) EnumerateSingleDomainWithCtx(ctx context.Context, domain string
 		}
 	}
 
+	// A cancelled domain has partial results, it is enumerated again on resume
+	if r.resume != nil && ctx.Err() == nil {
+		if err := r.resume.complete(domain); err != nil {
+			gologger.Warning().Msgf("Could not record %s as completed: %s\n", domain, err)
+		}
+	}
+
 	// Show found subdomain count in any case.
 	duration := durafmt.Parse(time.Since(now)).LimitFirstN(maxNumCount).String()
 	var numberOfSubDomains int
@@ -158,7 +224,7 @@ func (r *Runner) EnumerateSingleDomainWithCtx(ctx context.Context, domain string
 		numberOfSubDomains = len(uniqueMap)
 	}
 
-	if r.options.ResultCallback != nil {
+	if r.options.ResultCallback != nil && !r.options.Stream {
 		if r.options.RemoveWildcard {
 			for host, result := range foundResults {
 				r.options.ResultCallback(&resolve.HostEntry{Domain: host, Host: result.Host, Source: result.Source})
@@ -171,24 +237,101 @@ func (r *Runner) EnumerateSingleDomainWithCtx(ctx context.Context, domain string
 	}
 	gologger.Info().Msgf("Found %d subdomains for %s in %s\n", numberOfSubDomains, domain, duration)
 
+	// This is a hack to remove the skipped count from the statistics
+	// as we don't want to show it in the statistics.
+	// TODO: Design a better way to do this.
+	for source, count := range skippedCounts {
+		runStatistics.Update(source, func(stats *subscraping.Statistics) {
+			stats.Results -= count
+		})
+	}
+	_ = r.statistics.Set(domain, runStatistics)
+	var wildcards []resolve.WildcardZone
+	if resolutionPool != nil {
+		wildcards = resolutionPool.Wildcards()
+		r.wildcardsMutex.Lock()
+		r.wildcards[domain] = wildcards
+		r.wildcardsMutex.Unlock()
+	}
+
 	if r.options.Statistics {
 		gologger.Info().Msgf("Printing source statistics for %s", domain)
-		statistics := r.passiveAgent.GetStatistics()
-		// This is a hack to remove the skipped count from the statistics
-		// as we don't want to show it in the statistics.
-		// TODO: Design a better way to do this.
-		for source, count := range skippedCounts {
-			if stat, ok := statistics[source]; ok {
-				stat.Results -= count
-				statistics[source] = stat
-			}
+		printStatistics(runStatistics.All())
+		printWildcards(wildcards)
+	}
+
+	return nil
+}
+
+// streamHost writes a newly found host to the writers and passes it to the result callback
+func (r *Runner) streamHost(outputWriter *OutputWriter, domain string, hostEntry resolve.HostEntry, writers []io.Writer) error {
+	r.outputMutex.Lock()
+	defer r.outputMutex.Unlock()
+
+	if r.options.ResultCallback != nil {
+		r.options.ResultCallback(&hostEntry)
+	}
+	results := map[string]resolve.HostEntry{hostEntry.Host: hostEntry}
+	for _, writer := range writers {
+		if err := outputWriter.WriteHost(domain, results, writer); err != nil {
+			return err
 		}
-		printStatistics(statistics)
 	}
+	return nil
+}
 
+// streamSources writes the sources known so far for a host to the writers. The
+// first event for a host also passes it to the result callback.
+func (r *Runner) streamSources(outputWriter *OutputWriter, domain, host string, sources map[string]struct{}, writers []io.Writer) error {
+	r.outputMutex.Lock()
+	defer r.outputMutex.Unlock()
+
+	if r.options.ResultCallback != nil && len(sources) == 1 {
+		for source := range sources {
+			r.options.ResultCallback(&resolve.HostEntry{Domain: domain, Host: host, Source: source})
+		}
+	}
+	sourceMap := map[string]map[string]struct{}{host: sources}
+	for _, writer := range writers {
+		if err := outputWriter.WriteSourceHost(domain, sourceMap, writer); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// streamResult writes a newly resolved host to the writers and passes it to the result callback
+func (r *Runner) streamResult(outputWriter *OutputWriter, domain string, result resolve.Result, writers []io.Writer) error {
+	r.outputMutex.Lock()
+	defer r.outputMutex.Unlock()
+
+	if r.options.ResultCallback != nil {
+		r.options.ResultCallback(&resolve.HostEntry{Domain: domain, Host: result.Host, Source: result.Source})
+	}
+	results := map[string]resolve.Result{result.Host: result}
+	for _, writer := range writers {
+		var err error
+		if r.options.writesResolved() {
+			err = outputWriter.WriteHostIP(domain, results, writer)
+		} else {
+			err = outputWriter.WriteHostNoWildcard(domain, results, writer)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// retryPolicyOption builds the retry policies of the enumeration from the options
+func (r *Runner) retryPolicyOption() passive.EnumerateOption {
+	sourcePolicies := make(map[string]subscraping.RetryPolicy, len(r.options.sourceRetries))
+	for source, retries := range r.options.sourceRetries {
+		sourcePolicies[source] = subscraping.NewRetryPolicy(retries)
+	}
+	return passive.WithRetryPolicy(subscraping.NewRetryPolicy(r.options.Retries), sourcePolicies)
+}
+
 func (r *Runner) filterAndMatchSubdomain(subdomain string) bool {
 	if r.options.filterRegexes != nil {
 		for _, filter := range r.options.filterRegexes {
@@ -1,10 +1,16 @@
 package runner
 
 import (
+	"context"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
 )
 
 func TestFilterAndMatchSubdomain(t *testing.T) {
@@ -143,3 +149,50 @@ func TestFilterAndMatchSubdomain(t *testing.T) {
 		}
 	})
 }
+
+// echoSource returns the www subdomain of each domain
+type echoSource struct{}
+
+func (s *echoSource) Run(_ context.Context, domain string, _ *subscraping.Session) <-chan subscraping.Result {
+	results := make(chan subscraping.Result, 1)
+	results <- subscraping.Result{Source: s.Name(), Type: subscraping.Subdomain, Value: "www." + domain}
+	close(results)
+	return results
+}
+
+func (s *echoSource) Name() string              { return "echo" }
+func (s *echoSource) IsDefault() bool           { return true }
+func (s *echoSource) HasRecursiveSupport() bool { return false }
+func (s *echoSource) NeedsKey() bool            { return false }
+func (s *echoSource) AddApiKeys(_ []string)     {}
+
+// TestEnumerateMultipleDomainsOutputDirectory checks each domain is written to its
+// own file when the writers of the caller have spare capacity, run it with -race
+func TestEnumerateMultipleDomainsOutputDirectory(t *testing.T) {
+	dir := t.TempDir()
+	options := &Options{
+		Sources:            []string{"echo"},
+		CustomSources:      []subscraping.Source{&echoSource{}},
+		OutputDirectory:    dir,
+		ProviderConfig:     filepath.Join(dir, "provider-config.yaml"),
+		DomainConcurrency:  4,
+		Threads:            10,
+		Timeout:            10,
+		MaxEnumerationTime: 1,
+		Output:             io.Discard,
+		Silent:             true,
+	}
+	runner, err := NewRunner(options)
+	require.NoError(t, err)
+
+	domains := []string{"a.com", "b.com", "c.com", "d.com", "e.com", "f.com", "g.com", "h.com"}
+	writers := make([]io.Writer, 1, 8)
+	writers[0] = io.Discard
+	require.NoError(t, runner.EnumerateMultipleDomains(strings.NewReader(strings.Join(domains, "\n")), writers))
+
+	for _, domain := range domains {
+		data, err := os.ReadFile(filepath.Join(dir, domain+".txt"))
+		require.NoError(t, err)
+		require.Equal(t, "www."+domain+"\n", string(data))
+	}
+}
@@ -1,17 +1,45 @@
 package runner
 
 import (
+	"crypto/tls"
 	"net"
+	"net/http"
+	"net/url"
 	"strings"
+	"time"
 
 	"github.com/projectdiscovery/dnsx/libs/dnsx"
+	sliceutil "github.com/projectdiscovery/utils/slice"
+
 	"github.com/projectdiscovery/subfinder/v2/pkg/passive"
+	"github.com/projectdiscovery/subfinder/v2/pkg/permutation"
 	"github.com/projectdiscovery/subfinder/v2/pkg/resolve"
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping/sources/axfr"
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping/sources/bruteforce"
 )
 
 // initializePassiveEngine creates the passive engine and loads sources etc
 func (r *Runner) initializePassiveEngine() {
-	r.passiveAgent = passive.New(r.options.Sources, r.options.ExcludeSources, r.options.All, r.options.OnlyRecursive)
+	// The active sources hold the options and the resolver of the runner,
+	// they are created for each runner instead of shared through AllSources
+	r.activeSources = []subscraping.ActiveSource{
+		&axfr.Source{IXFR: r.options.IXFR},
+		&bruteforce.Source{Wordlist: r.options.Wordlist, Threads: r.options.Threads},
+	}
+	sources := make([]subscraping.Source, 0, len(r.activeSources)+len(r.options.CustomSources))
+	for _, source := range r.activeSources {
+		sources = append(sources, source)
+	}
+	// The sources given by the caller replace the ones of the runner
+	sources = append(sources, r.options.CustomSources...)
+
+	options := []passive.AgentOption{passive.WithSources(sources...)}
+	// A wordlist enables the bruteforce source along the other ones
+	if r.options.Wordlist != "" {
+		options = append(options, passive.WithActiveSources("bruteforce"))
+	}
+	r.passiveAgent = passive.New(r.options.Sources, r.options.ExcludeSources, r.options.All, r.options.OnlyRecursive, options...)
 }
 
 // initializeResolver creates the resolver used to resolve the found subdomains
@@ -41,11 +69,77 @@ func (r *Runner) initializeResolver() error {
 	}
 
 	r.resolverClient = resolve.New()
+	r.resolverClient.Resolvers = resolvers
+	r.resolverClient.RecordTypes = r.options.recordTypes
+	// The A records are queried for the wildcard classifier when no record types are asked
+	questionTypes := r.options.recordTypes
+	if len(questionTypes) == 0 {
+		questionTypes = []uint16{resolve.RecordTypes["a"]}
+	}
+	if r.options.WildcardHTTP {
+		client, err := newWildcardHTTPClient(r.options.Proxy, time.Duration(r.options.Timeout)*time.Second)
+		if err != nil {
+			return err
+		}
+		r.resolverClient.HTTPClient = client
+	}
 	var err error
-	r.resolverClient.DNSClient, err = dnsx.New(dnsx.Options{BaseResolvers: resolvers, MaxRetries: 5})
+	r.resolverClient.DNSClient, err = dnsx.New(dnsx.Options{BaseResolvers: resolvers, MaxRetries: 5, QuestionTypes: questionTypes})
 	if err != nil {
 		return nil
 	}
 
 	return nil
 }
+
+// initializeActiveSources gives the resolver to the active sources
+func (r *Runner) initializeActiveSources() {
+	for _, source := range r.activeSources {
+		source.SetResolver(r.resolverClient)
+	}
+}
+
+// initializePermutations creates the generator of the permutations resolved
+// after the sources
+func (r *Runner) initializePermutations() error {
+	r.permutations = &permutation.Generator{
+		Patterns:      r.options.permutePatterns,
+		MaxCandidates: r.options.MaxPermutations,
+	}
+	if r.options.PermuteWords == "" {
+		return nil
+	}
+	words, err := loadFromFile(r.options.PermuteWords)
+	if err != nil {
+		return err
+	}
+	for _, word := range words {
+		word = strings.ToLower(word)
+		if !sliceutil.Contains(r.permutations.Words, word) {
+			r.permutations.Words = append(r.permutations.Words, word)
+		}
+	}
+	return nil
+}
+
+// newWildcardHTTPClient creates the client comparing the hosts with the
+// wildcards, redirects are compared instead of followed
+func newWildcardHTTPClient(proxy string, timeout time.Duration) (*http.Client, error) {
+	transport := &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+	if proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}, nil
+}
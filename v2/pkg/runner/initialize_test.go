@@ -0,0 +1,27 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/projectdiscovery/subfinder/v2/pkg/passive"
+	"github.com/projectdiscovery/subfinder/v2/pkg/resolve"
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping/sources/axfr"
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping/sources/bruteforce"
+)
+
+func TestRunnersHaveTheirOwnActiveSources(t *testing.T) {
+	newRunner := func(wordlist string) *Runner {
+		runner := &Runner{options: &Options{Wordlist: wordlist, IXFR: true}, resolverClient: resolve.New()}
+		runner.initializePassiveEngine()
+		runner.initializeActiveSources()
+		return runner
+	}
+	first, second := newRunner("first.txt"), newRunner("second.txt")
+
+	require.Equal(t, "first.txt", first.activeSources[1].(*bruteforce.Source).Wordlist)
+	require.Equal(t, "second.txt", second.activeSources[1].(*bruteforce.Source).Wordlist)
+	require.Empty(t, passive.NameSourceMap["bruteforce"].(*bruteforce.Source).Wordlist, "the shared source is left untouched")
+	require.False(t, passive.NameSourceMap["axfr"].(*axfr.Source).IXFR, "the shared source is left untouched")
+}
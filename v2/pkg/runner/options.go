@@ -9,12 +9,17 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/projectdiscovery/chaos-client/pkg/chaos"
 	"github.com/projectdiscovery/goflags"
 	"github.com/projectdiscovery/gologger"
 	"github.com/projectdiscovery/subfinder/v2/pkg/passive"
+	"github.com/projectdiscovery/subfinder/v2/pkg/permutation"
 	"github.com/projectdiscovery/subfinder/v2/pkg/resolve"
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping/sources/custom"
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping/sources/plugin"
 	fileutil "github.com/projectdiscovery/utils/file"
 	folderutil "github.com/projectdiscovery/utils/folder"
 	logutil "github.com/projectdiscovery/utils/log"
@@ -25,6 +30,10 @@ var (
 	configDir                     = folderutil.AppConfigDirOrDefault(".", "subfinder")
 	defaultConfigLocation         = filepath.Join(configDir, "config.yaml")
 	defaultProviderConfigLocation = filepath.Join(configDir, "provider-config.yaml")
+	defaultCacheLocation          = filepath.Join(configDir, "cache")
+	defaultBudgetLocation         = filepath.Join(configDir, "budgets.json")
+	defaultCustomSourcesLocation  = filepath.Join(configDir, "sources")
+	defaultPluginsLocation        = filepath.Join(configDir, "plugins")
 )
 
 // Options contains the configuration options for tuning
@@ -38,14 +47,32 @@ type Options struct {
 	ListSources        bool                // ListSources specifies whether to list all available sources
 	RemoveWildcard     bool                // RemoveWildcard specifies whether to remove potential wildcard or dead subdomains from the results.
 	CaptureSources     bool                // CaptureSources specifies whether to save all sources that returned a specific domains or just the first source
+	Stream             bool                // Stream specifies whether to write each unique subdomain as soon as it is found
 	Stdin              bool                // Stdin specifies whether stdin input was given to the process
 	Version            bool                // Version specifies if we should just show version and exit
 	OnlyRecursive      bool                // Recursive specifies whether to use only recursive subdomain enumeration sources
 	All                bool                // All specifies whether to use all (slow) sources.
+	IXFR               bool                // IXFR makes the axfr source try an IXFR when the AXFR is refused
+	Wordlist           string              // Wordlist is the file of words the bruteforce source resolves under the domains
+	Permute            bool                // Permute resolves the permutations of the subdomains found once the sources are done
+	PermutePatterns    goflags.StringSlice `yaml:"permutation-patterns,omitempty"` // PermutePatterns contains the permutation patterns to use, all of them when empty
+	PermuteWords       string              // PermuteWords is the file of words for the permutations, the built-in ones when empty
+	MaxPermutations    int                 // MaxPermutations caps the permutations resolved for each domain
 	Statistics         bool                // Statistics specifies whether to report source statistics
+	CheckKeys          bool                // CheckKeys specifies whether to check the API keys of the provider config instead of enumerating
 	Threads            int                 // Threads controls the number of threads to use for active enumerations
+	DomainConcurrency  int                 // DomainConcurrency is the number of domains to enumerate concurrently
 	Timeout            int                 // Timeout is the seconds to wait for sources to respond
 	MaxEnumerationTime int                 // MaxEnumerationTime is the maximum amount of time in minutes to wait for enumeration
+	SourceTimeouts     goflags.StringSlice `yaml:"source-timeout,omitempty"` // SourceTimeouts contains the per-source deadlines in source=duration format
+	Retries            int                 // Retries is the number of times a transient request failure is retried
+	SourceRetries      goflags.StringSlice `yaml:"source-retries,omitempty"` // SourceRetries contains the per-source retries in source=count format
+	MaxSourceFailures  int                 // MaxSourceFailures is the number of consecutive failed domains after which a source is disabled
+	CacheTTLs          goflags.StringSlice `yaml:"cache-ttl,omitempty"` // CacheTTLs contains the per-source response cache TTLs in source=duration format
+	NoCache            bool                // NoCache disables the response cache
+	RefreshCache       bool                // RefreshCache ignores the cached responses and stores new ones
+	Record             string              // Record is the directory to record the HTTP interactions of the sources to
+	Replay             string              // Replay is the directory to replay the recorded HTTP interactions of the sources from
 	Domain             goflags.StringSlice // Domain is the domain to find subdomains for
 	DomainsFile        string              // DomainsFile is the file containing list of domains to find subdomains for
 	Output             io.Writer
@@ -53,8 +80,11 @@ type Options struct {
 	OutputDirectory    string               // OutputDirectory is the directory to write results to in case list of domains is given
 	Sources            goflags.StringSlice  `yaml:"sources,omitempty"`         // Sources contains a comma-separated list of sources to use for enumeration
 	ExcludeSources     goflags.StringSlice  `yaml:"exclude-sources,omitempty"` // ExcludeSources contains the comma-separated sources to not include in the enumeration process
+	CustomSources      []subscraping.Source `yaml:"-"`                         // CustomSources are sources added to the built-in ones for this runner only
 	Resolvers          goflags.StringSlice  `yaml:"resolvers,omitempty"`       // Resolvers is the comma-separated resolvers to use for enumeration
 	ResolverList       string               // ResolverList is a text file containing list of resolvers to use for enumeration
+	RecordTypes        goflags.StringSlice  `yaml:"record-types,omitempty"` // RecordTypes contains the record types to query for each subdomain with RemoveWildcard
+	WildcardHTTP       bool                 // WildcardHTTP compares the HTTP responses of the hosts the wildcard classifier can't tell apart
 	Config             string               // Config contains the location of the config file
 	ProviderConfig     string               // ProviderConfig contains the location of the provider config file
 	Proxy              string               // HTTP proxy
@@ -65,8 +95,15 @@ type Options struct {
 	Filter             goflags.StringSlice
 	matchRegexes       []*regexp.Regexp
 	filterRegexes      []*regexp.Regexp
+	sourceTimeouts     map[string]time.Duration
+	sourceRetries      map[string]int
+	cacheTTLs          map[string]time.Duration
+	budgets            map[string]string
+	recordTypes        []uint16
+	permutePatterns    []permutation.Pattern
 	ResultCallback     OnResultCallback // OnResult callback
 	DisableUpdateCheck bool             // DisableUpdateCheck disable update checking
+	Resume             bool             // Resume skips the domains completed by a previous interrupted run
 }
 
 // OnResultCallback (hostResult)
@@ -85,12 +122,19 @@ func ParseOptions() *Options {
 	flagSet.CreateGroup("input", "Input",
 		flagSet.StringSliceVarP(&options.Domain, "domain", "d", nil, "domains to find subdomains for", goflags.NormalizedStringSliceOptions),
 		flagSet.StringVarP(&options.DomainsFile, "list", "dL", "", "file containing list of domains for subdomain discovery"),
+		flagSet.BoolVar(&options.Resume, "resume", false, "resume an interrupted run, skipping the domains it completed"),
 	)
 
 	flagSet.CreateGroup("source", "Source",
 		flagSet.StringSliceVarP(&options.Sources, "sources", "s", nil, "specific sources to use for discovery (-s crtsh,github). Use -ls to display all available sources.", goflags.NormalizedStringSliceOptions),
 		flagSet.BoolVar(&options.OnlyRecursive, "recursive", false, "use only sources that can handle subdomains recursively (e.g. subdomain.domain.tld vs domain.tld)"),
 		flagSet.BoolVar(&options.All, "all", false, "use all sources for enumeration (slow)"),
+		flagSet.BoolVar(&options.IXFR, "ixfr", false, "try an IXFR when a nameserver refuses the AXFR (-s axfr only)"),
+		flagSet.StringVarP(&options.Wordlist, "wordlist", "w", "", "file of words to bruteforce subdomains with, enables the bruteforce source"),
+		flagSet.BoolVar(&options.Permute, "permute", false, "resolve permutations of the subdomains found, reported by the permutation source"),
+		flagSet.StringSliceVarP(&options.PermutePatterns, "permutation-pattern", "pp", nil, "permutation patterns to use (affix,number,word,swap) (-permute only)", goflags.NormalizedStringSliceOptions),
+		flagSet.StringVarP(&options.PermuteWords, "permutation-words", "pw", "", "file of environment words for the affix and word patterns (-permute only)"),
+		flagSet.IntVarP(&options.MaxPermutations, "max-permutations", "mp", permutation.DefaultMaxCandidates, "maximum number of permutations to resolve for each domain (-permute only)"),
 		flagSet.StringSliceVarP(&options.ExcludeSources, "exclude-sources", "es", nil, "sources to exclude from enumeration (-es alienvault,zoomeyeapi)", goflags.NormalizedStringSliceOptions),
 	)
 
@@ -102,7 +146,8 @@ func ParseOptions() *Options {
 	flagSet.CreateGroup("rate-limit", "Rate-limit",
 		flagSet.IntVarP(&options.RateLimit, "rate-limit", "rl", 0, "maximum number of http requests to send per second (global)"),
 		flagSet.RateLimitMapVarP(&options.RateLimits, "rate-limits", "rls", defaultRateLimits, "maximum number of http requests to send per second four providers in key=value format (-rls hackertarget=10/m)", goflags.NormalizedStringSliceOptions),
-		flagSet.IntVar(&options.Threads, "t", 10, "number of concurrent goroutines for resolving (-active only)"),
+		flagSet.IntVar(&options.Threads, "t", 10, "number of concurrent goroutines for resolving (-active, -wordlist and -permute only)"),
+		flagSet.IntVarP(&options.DomainConcurrency, "domain-concurrency", "dc", 1, "number of domains to enumerate concurrently"),
 	)
 
 	flagSet.CreateGroup("update", "Update",
@@ -116,6 +161,7 @@ func ParseOptions() *Options {
 		flagSet.StringVarP(&options.OutputDirectory, "output-dir", "oD", "", "directory to write output (-dL only)"),
 		flagSet.BoolVarP(&options.CaptureSources, "collect-sources", "cs", false, "include all sources in the output (-json only)"),
 		flagSet.BoolVarP(&options.HostIP, "ip", "oI", false, "include host IP in output (-active only)"),
+		flagSet.BoolVar(&options.Stream, "stream", false, "write each unique subdomain as soon as it is found"),
 	)
 
 	flagSet.CreateGroup("configuration", "Configuration",
@@ -124,6 +170,8 @@ func ParseOptions() *Options {
 		flagSet.StringSliceVar(&options.Resolvers, "r", nil, "comma separated list of resolvers to use", goflags.NormalizedStringSliceOptions),
 		flagSet.StringVarP(&options.ResolverList, "rlist", "rL", "", "file containing list of resolvers to use"),
 		flagSet.BoolVarP(&options.RemoveWildcard, "active", "nW", false, "display active subdomains only"),
+		flagSet.StringSliceVarP(&options.RecordTypes, "record-type", "rt", nil, "record types to query for each subdomain, written with -json (a,aaaa,cname,mx,txt) (-active only)", goflags.NormalizedStringSliceOptions),
+		flagSet.BoolVarP(&options.WildcardHTTP, "wildcard-http", "wh", false, "compare the http responses of hosts sharing answers with a wildcard (-active only)"),
 		flagSet.StringVar(&options.Proxy, "proxy", "", "http proxy to use with subfinder"),
 		flagSet.BoolVarP(&options.ExcludeIps, "exclude-ip", "ei", false, "exclude IPs from the list of domains"),
 	)
@@ -135,11 +183,21 @@ func ParseOptions() *Options {
 		flagSet.BoolVarP(&options.NoColor, "no-color", "nc", false, "disable color in output"),
 		flagSet.BoolVarP(&options.ListSources, "list-sources", "ls", false, "list all available sources"),
 		flagSet.BoolVar(&options.Statistics, "stats", false, "report source statistics"),
+		flagSet.BoolVarP(&options.CheckKeys, "check-keys", "ck", false, "check the api keys of the provider config and report their remaining credits"),
+		flagSet.StringVar(&options.Record, "record", "", "record the http requests and responses of the sources to a directory"),
+		flagSet.StringVar(&options.Replay, "replay", "", "replay the http responses recorded to a directory without network access"),
 	)
 
 	flagSet.CreateGroup("optimization", "Optimization",
 		flagSet.IntVar(&options.Timeout, "timeout", 30, "seconds to wait before timing out"),
 		flagSet.IntVar(&options.MaxEnumerationTime, "max-time", 10, "minutes to wait for enumeration results"),
+		flagSet.StringSliceVarP(&options.SourceTimeouts, "source-timeout", "st", nil, "maximum time to wait for a source in key=value format (-st crtsh=2m,commoncrawl=5m)", goflags.NormalizedStringSliceOptions),
+		flagSet.IntVar(&options.Retries, "retries", 2, "number of times to retry a request failing with a transient error"),
+		flagSet.StringSliceVarP(&options.SourceRetries, "source-retries", "sr", nil, "number of retries for a source in key=value format (-sr shodan=5,crtsh=0)", goflags.NormalizedStringSliceOptions),
+		flagSet.StringSliceVarP(&options.CacheTTLs, "cache-ttl", "ct", nil, "cache source responses on disk for the given time in key=value format (-ct securitytrails=24h,shodan=12h)", goflags.NormalizedStringSliceOptions),
+		flagSet.BoolVarP(&options.NoCache, "no-cache", "nC", false, "disable the response cache"),
+		flagSet.BoolVar(&options.RefreshCache, "refresh", false, "ignore cached responses and cache new ones"),
+		flagSet.IntVarP(&options.MaxSourceFailures, "max-source-failures", "msf", 5, "number of consecutive failed domains after which a source is disabled (0 to only disable on auth failures)"),
 	)
 
 	if err := flagSet.Parse(); err != nil {
@@ -191,6 +249,13 @@ func ParseOptions() *Options {
 		}
 	}
 
+	if err := loadCustomSources(defaultCustomSourcesLocation); err != nil {
+		gologger.Fatal().Msgf("Could not load custom sources: %s\n", err)
+	}
+	if err := loadPluginSources(defaultPluginsLocation); err != nil {
+		gologger.Fatal().Msgf("Could not load plugin sources: %s\n", err)
+	}
+
 	if options.ListSources {
 		listSources(options)
 		os.Exit(0)
@@ -215,26 +280,68 @@ func (options *Options) loadProvidersFrom(location string) {
 
 	// We skip bailing out if file doesn't exist because we'll create it
 	// at the end of options parsing from default via goflags.
-	if err := UnmarshalFrom(location); err != nil && (!strings.Contains(err.Error(), "file doesn't exist") || errors.Is(os.ErrNotExist, err)) {
+	budgets, err := loadProviderConfig(location, options.CustomSources...)
+	options.budgets = budgets
+	if err != nil && (!strings.Contains(err.Error(), "file doesn't exist") || errors.Is(os.ErrNotExist, err)) {
 		gologger.Error().Msgf("Could not read providers from %s: %s\n", location, err)
 	}
 }
 
+// loadCustomSources registers the sources defined by the YAML files of a directory
+func loadCustomSources(directory string) error {
+	sources, err := custom.LoadDefinitions(directory)
+	if err != nil {
+		return err
+	}
+	for _, source := range sources {
+		if err := passive.RegisterSource(source); err != nil {
+			return err
+		}
+		gologger.Debug().Msgf("Loaded custom source %s", source.Name())
+	}
+	return nil
+}
+
+// loadPluginSources registers the plugin sources defined by the YAML files of a directory
+func loadPluginSources(directory string) error {
+	sources, err := plugin.LoadDefinitions(directory)
+	if err != nil {
+		return err
+	}
+	for _, source := range sources {
+		if err := passive.RegisterSource(source); err != nil {
+			return err
+		}
+		gologger.Debug().Msgf("Loaded plugin source %s", source.Name())
+	}
+	return nil
+}
+
 func listSources(options *Options) {
-	gologger.Info().Msgf("Current list of available sources. [%d]\n", len(passive.AllSources))
+	gologger.Info().Msgf("Current list of available sources. [%d]\n", len(passive.Sources()))
 	gologger.Info().Msgf("Sources marked with an * need key(s) or token(s) to work.\n")
+	gologger.Info().Msgf("Sources marked with a + query the target's DNS servers and are only used when given with -s.\n")
 	gologger.Info().Msgf("You can modify %s to configure your keys/tokens.\n\n", options.ProviderConfig)
 
-	for _, source := range passive.AllSources {
+	for _, source := range passive.Sources() {
 		message := "%s\n"
 		sourceName := source.Name()
 		if source.NeedsKey() {
 			message = "%s *\n"
 		}
+		if _, ok := source.(subscraping.ActiveSource); ok {
+			message = "%s +\n"
+		}
 		gologger.Silent().Msgf(message, sourceName)
 	}
 }
 
+// writesResolved returns true when the resolved hosts are written with their
+// IP or, in JSON, with their records and wildcard classification
+func (options *Options) writesResolved() bool {
+	return options.HostIP || options.JSON && options.RemoveWildcard
+}
+
 func (options *Options) preProcessDomains() {
 	for i, domain := range options.Domain {
 		options.Domain[i], _ = sanitize(domain)
@@ -255,6 +362,6 @@ var defaultRateLimits = []string{
 	"securitytrails=2/s",
 	"sitedossier=8/m",
 	"netlas=1/s",
-	// "gitlab=2/s",
+	"gitlab=2/s",
 	"github=83/m",
 }
@@ -16,6 +16,9 @@ import (
 // OutputWriter outputs content to writers.
 type OutputWriter struct {
 	JSON bool
+	// HostIP writes the IP of the resolved hosts in JSON, which otherwise
+	// only hold their records and wildcard classification
+	HostIP bool
 }
 
 type jsonSourceResult struct {
@@ -26,9 +29,12 @@ type jsonSourceResult struct {
 
 type jsonSourceIPResult struct {
 	Host   string `json:"host"`
-	IP     string `json:"ip"`
+	IP     string `json:"ip,omitempty"`
 	Input  string `json:"input"`
 	Source string `json:"source"`
+	// Records adds the answers by record type when they were queried
+	*resolve.Records
+	Wildcard resolve.Classification `json:"wildcard,omitempty"`
 }
 
 type jsonSourcesResult struct {
@@ -76,7 +82,7 @@ func (o *OutputWriter) createFile(filename string, appendToFile bool) (*os.File,
 func (o *OutputWriter) WriteHostIP(input string, results map[string]resolve.Result, writer io.Writer) error {
 	var err error
 	if o.JSON {
-		err = writeJSONHostIP(input, results, writer)
+		err = writeJSONHostIP(input, results, o.HostIP, writer)
 	} else {
 		err = writePlainHostIP(input, results, writer)
 	}
@@ -105,16 +111,21 @@ func writePlainHostIP(_ string, results map[string]resolve.Result, writer io.Wri
 	return bufwriter.Flush()
 }
 
-func writeJSONHostIP(input string, results map[string]resolve.Result, writer io.Writer) error {
+func writeJSONHostIP(input string, results map[string]resolve.Result, hostIP bool, writer io.Writer) error {
 	encoder := jsoniter.NewEncoder(writer)
 
 	var data jsonSourceIPResult
 
 	for _, result := range results {
 		data.Host = result.Host
-		data.IP = result.IP
+		data.IP = ""
+		if hostIP {
+			data.IP = result.IP
+		}
 		data.Input = input
 		data.Source = result.Source
+		data.Records = result.Records
+		data.Wildcard = result.Classification
 
 		err := encoder.Encode(&data)
 		if err != nil {
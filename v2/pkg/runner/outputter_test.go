@@ -0,0 +1,37 @@
+package runner
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/projectdiscovery/subfinder/v2/pkg/resolve"
+)
+
+func TestWriteJSONHostIPRecords(t *testing.T) {
+	var buffer bytes.Buffer
+	err := writeJSONHostIP("example.com", map[string]resolve.Result{
+		"www.example.com": {Host: "www.example.com", IP: "192.0.2.1", Source: "crtsh", Classification: resolve.Uncertain, Records: &resolve.Records{
+			A:     []string{"192.0.2.1"},
+			CNAME: []string{"edge.cloud.example.net"},
+		}},
+	}, true, &buffer)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"host":"www.example.com","ip":"192.0.2.1","input":"example.com","source":"crtsh","a":["192.0.2.1"],"cname":["edge.cloud.example.net"],"wildcard":"uncertain"}`, buffer.String())
+
+	buffer.Reset()
+	err = writeJSONHostIP("example.com", map[string]resolve.Result{
+		"www.example.com": {Host: "www.example.com", IP: "192.0.2.1", Source: "crtsh"},
+	}, true, &buffer)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"host":"www.example.com","ip":"192.0.2.1","input":"example.com","source":"crtsh"}`, buffer.String())
+
+	// The IP is only written when asked, as with -json -nW
+	buffer.Reset()
+	err = writeJSONHostIP("example.com", map[string]resolve.Result{
+		"www.example.com": {Host: "www.example.com", IP: "192.0.2.1", Source: "crtsh", Classification: resolve.Real},
+	}, false, &buffer)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"host":"www.example.com","input":"example.com","source":"crtsh","wildcard":"real"}`, buffer.String())
+}
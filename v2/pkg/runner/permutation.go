@@ -0,0 +1,63 @@
+package runner
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/projectdiscovery/gologger"
+	"golang.org/x/exp/maps"
+
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
+)
+
+// permutationSource is the source the resolved permutations are reported by
+const permutationSource = "permutation"
+
+// permute forwards the results of the sources and, once they are done,
+// resolves the permutations of the subdomains they found. The permutations
+// answered by a wildcard are filtered, the others are sent as results of
+// the permutation source.
+func (r *Runner) permute(ctx context.Context, domain string, results <-chan subscraping.Result, runStatistics *subscraping.RunStatistics) <-chan subscraping.Result {
+	permuted := make(chan subscraping.Result)
+
+	go func() {
+		defer close(permuted)
+
+		hosts := make(map[string]struct{})
+		for result := range results {
+			if result.Type == subscraping.Subdomain {
+				subdomain := strings.ToLower(replacer.Replace(result.Value))
+				if strings.HasSuffix(subdomain, "."+domain) && r.filterAndMatchSubdomain(subdomain) {
+					hosts[subdomain] = struct{}{}
+				}
+			}
+			permuted <- result
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		candidates, capped := r.permutations.Candidates(domain, maps.Keys(hosts))
+		if capped {
+			gologger.Warning().Msgf("Permutations of %s capped to %d candidates\n", domain, len(candidates))
+		}
+		if len(candidates) == 0 {
+			return
+		}
+		gologger.Info().Msgf("Resolving %d permutations of %d subdomains for %s\n", len(candidates), len(hosts), domain)
+
+		now := time.Now()
+		var found int
+		for host := range r.resolverClient.ResolveCandidates(ctx, domain, permutationSource, candidates, r.options.Threads) {
+			found++
+			permuted <- subscraping.Result{Source: permutationSource, Type: subscraping.Subdomain, Value: host}
+		}
+		runStatistics.Update(permutationSource, func(stats *subscraping.Statistics) {
+			stats.Results += found
+			stats.TimeTaken = time.Since(now)
+		})
+	}()
+
+	return permuted
+}
@@ -0,0 +1,117 @@
+package runner
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/projectdiscovery/dnsx/libs/dnsx"
+	"github.com/stretchr/testify/require"
+
+	"github.com/projectdiscovery/subfinder/v2/pkg/permutation"
+	"github.com/projectdiscovery/subfinder/v2/pkg/resolve"
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
+)
+
+// startPermutationServer starts a DNS server on 127.0.0.1 answering the A
+// questions of the hosts, and of any name under dev.example.com
+func startPermutationServer(t *testing.T, hosts ...string) string {
+	t.Helper()
+
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		msg := new(dns.Msg)
+		msg.SetReply(r)
+		question := r.Question[0]
+		name := strings.TrimSuffix(strings.ToLower(question.Name), ".")
+		ip := ""
+		for i, host := range hosts {
+			if host == name {
+				ip = net.IPv4(192, 0, 2, byte(i+1)).String()
+			}
+		}
+		if strings.HasSuffix(name, ".dev.example.com") {
+			ip = "192.0.2.100"
+		}
+		switch {
+		case ip == "":
+			msg.Rcode = dns.RcodeNameError
+		case question.Qtype == dns.TypeA:
+			msg.Answer = append(msg.Answer, &dns.A{
+				Hdr: dns.RR_Header{Name: question.Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+				A:   net.ParseIP(ip),
+			})
+		}
+		_ = w.WriteMsg(msg)
+	})
+
+	packetConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &dns.Server{PacketConn: packetConn, Handler: handler}
+	go func() { _ = server.ActivateAndServe() }()
+	t.Cleanup(func() { _ = server.Shutdown() })
+	return packetConn.LocalAddr().String()
+}
+
+func TestPermute(t *testing.T) {
+	address := startPermutationServer(t, "api-dev.example.com", "staging-web.example.com", "api.dev.example.com")
+	client, err := dnsx.New(dnsx.Options{BaseResolvers: []string{address}, MaxRetries: 1, QuestionTypes: []uint16{dns.TypeA}})
+	require.NoError(t, err)
+
+	runner := &Runner{
+		options:        &Options{Threads: 2},
+		resolverClient: &resolve.Resolver{DNSClient: client},
+		permutations:   &permutation.Generator{Words: []string{"dev", "staging"}},
+	}
+
+	results := make(chan subscraping.Result, 4)
+	results <- subscraping.Result{Source: "crtsh", Type: subscraping.Subdomain, Value: "api.example.com"}
+	results <- subscraping.Result{Source: "crtsh", Type: subscraping.Subdomain, Value: "*.dev-web.example.com"}
+	results <- subscraping.Result{Source: "crtsh", Type: subscraping.Subdomain, Value: "www.dev.example.com"}
+	results <- subscraping.Result{Source: "crtsh", Type: subscraping.Subdomain, Value: "other.org"}
+	close(results)
+
+	runStatistics := subscraping.NewRunStatistics("example.com")
+	var sources, permuted []string
+	for result := range runner.permute(context.Background(), "example.com", results, runStatistics) {
+		if result.Source == permutationSource {
+			permuted = append(permuted, result.Value)
+		} else {
+			sources = append(sources, result.Value)
+		}
+	}
+	sort.Strings(permuted)
+
+	require.Equal(t, []string{"api.example.com", "*.dev-web.example.com", "www.dev.example.com", "other.org"}, sources, "the results of the sources are forwarded")
+	require.Equal(t, []string{"api-dev.example.com", "staging-web.example.com"}, permuted, "the permutations under dev.example.com are answered by its wildcard")
+
+	stats, ok := runStatistics.Get(permutationSource)
+	require.True(t, ok)
+	require.Equal(t, 2, stats.Results)
+}
+
+func TestValidatePermutationOptions(t *testing.T) {
+	newOptions := func() *Options {
+		return &Options{Domain: []string{"example.com"}, Threads: 10, Timeout: 10, MaxPermutations: permutation.DefaultMaxCandidates}
+	}
+
+	options := newOptions()
+	options.PermutePatterns = []string{"affix"}
+	require.Error(t, options.validateOptions(), "patterns need -permute")
+
+	options = newOptions()
+	options.Permute = true
+	options.PermutePatterns = []string{"Number", "affix", "number"}
+	require.NoError(t, options.validateOptions())
+	require.Equal(t, []permutation.Pattern{permutation.Number, permutation.Affix}, options.permutePatterns)
+
+	options.PermutePatterns = []string{"reverse"}
+	require.Error(t, options.validateOptions())
+
+	options = newOptions()
+	options.Permute = true
+	options.MaxPermutations = 0
+	require.Error(t, options.validateOptions())
+}
@@ -0,0 +1,161 @@
+package runner
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/projectdiscovery/gologger"
+	fileutil "github.com/projectdiscovery/utils/file"
+)
+
+// resumeEntry is a line of the checkpoint file. An entry without domain
+// records the size of the output file when the run started.
+type resumeEntry struct {
+	Domain string `json:"domain,omitempty"`
+	Offset int64  `json:"offset"`
+}
+
+// resumeState records the domains completed by a run, and the size of the
+// output file after each of them, so that an interrupted run can be resumed
+type resumeState struct {
+	path       string
+	outputFile string
+
+	mutex     sync.Mutex
+	file      *os.File
+	completed map[string]struct{}
+}
+
+// resumeFileName returns the checkpoint file of a run, which depends on its inputs and outputs
+func resumeFileName(options *Options) string {
+	domainsFile := options.DomainsFile
+	if domainsFile != "" {
+		domainsFile, _ = filepath.Abs(domainsFile)
+	}
+	outputFile := options.OutputFile
+	if outputFile != "" {
+		outputFile, _ = filepath.Abs(outputFile)
+	}
+	key := strings.Join([]string{strings.Join(options.Domain, ","), domainsFile, outputFile, options.OutputDirectory}, "\n")
+	hash := sha256.Sum256([]byte(key))
+	return filepath.Join(configDir, "resume-"+hex.EncodeToString(hash[:8])+".jsonl")
+}
+
+// loadResumeState reads the checkpoint file of a previous run if any. The output file
+// is truncated to its size after the last completed domain so that the domains
+// that were not completed are written again without duplicates.
+func loadResumeState(path, outputFile string) (*resumeState, error) {
+	state := &resumeState{path: path, outputFile: outputFile, completed: make(map[string]struct{})}
+
+	lastOffset := int64(-1)
+	if fileutil.FileExists(path) {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var entry resumeEntry
+			// A partially written last line is ignored
+			if err := jsoniter.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				continue
+			}
+			if entry.Domain != "" {
+				state.completed[entry.Domain] = struct{}{}
+			}
+			lastOffset = max(lastOffset, entry.Offset)
+		}
+		file.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		gologger.Info().Msgf("Resuming from %s, skipping %d completed domains", path, len(state.completed))
+	}
+
+	if outputFile != "" && lastOffset >= 0 && fileutil.FileExists(outputFile) {
+		if err := os.Truncate(outputFile, lastOffset); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	state.file = file
+
+	if lastOffset < 0 {
+		if err := state.record(resumeEntry{Offset: state.outputOffset()}); err != nil {
+			return nil, err
+		}
+	}
+	return state, nil
+}
+
+// isCompleted returns true if the domain was completed by a previous run
+func (s *resumeState) isCompleted(domain string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	_, ok := s.completed[domain]
+	return ok
+}
+
+// complete records a domain as completed. It must be called once its results
+// are written, before the results of another domain are.
+func (s *resumeState) complete(domain string) error {
+	s.mutex.Lock()
+	s.completed[domain] = struct{}{}
+	s.mutex.Unlock()
+
+	return s.record(resumeEntry{Domain: domain, Offset: s.outputOffset()})
+}
+
+func (s *resumeState) record(entry resumeEntry) error {
+	data, err := jsoniter.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+// outputOffset returns the current size of the output file
+func (s *resumeState) outputOffset() int64 {
+	if s.outputFile == "" {
+		return 0
+	}
+	info, err := os.Stat(s.outputFile)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// finish closes the checkpoint file and removes it once the whole run is completed
+func (s *resumeState) finish(completed bool) error {
+	err := s.file.Close()
+	if completed {
+		if removeErr := os.Remove(s.path); removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) {
+			return removeErr
+		}
+	}
+	return err
+}
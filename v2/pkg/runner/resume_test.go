@@ -0,0 +1,104 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
+)
+
+func TestResumeState(t *testing.T) {
+	dir := t.TempDir()
+	checkpoint := filepath.Join(dir, "resume.jsonl")
+	output := filepath.Join(dir, "output.txt")
+
+	appendOutput := func(data string) {
+		file, err := os.OpenFile(output, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		require.NoError(t, err)
+		_, err = file.WriteString(data)
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+	}
+
+	// output of an earlier run must be kept
+	appendOutput("www.previous.com\n")
+
+	state, err := loadResumeState(checkpoint, output)
+	require.NoError(t, err)
+	require.False(t, state.isCompleted("example.com"))
+
+	appendOutput("www.example.com\n")
+	require.NoError(t, state.complete("example.com"))
+	// partial output of a domain interrupted before completion
+	appendOutput("www.hackerone")
+	require.NoError(t, state.finish(false))
+
+	state, err = loadResumeState(checkpoint, output)
+	require.NoError(t, err)
+	require.True(t, state.isCompleted("example.com"))
+	require.False(t, state.isCompleted("hackerone.com"))
+
+	data, err := os.ReadFile(output)
+	require.NoError(t, err)
+	require.Equal(t, "www.previous.com\nwww.example.com\n", string(data))
+
+	require.NoError(t, state.finish(true))
+	require.NoFileExists(t, checkpoint)
+}
+
+func TestResumeStateInterruptedBeforeAnyDomain(t *testing.T) {
+	dir := t.TempDir()
+	checkpoint := filepath.Join(dir, "resume.jsonl")
+	output := filepath.Join(dir, "output.txt")
+
+	state, err := loadResumeState(checkpoint, output)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(output, []byte("www.exam"), 0644))
+	require.NoError(t, state.finish(false))
+
+	state, err = loadResumeState(checkpoint, output)
+	require.NoError(t, err)
+	defer state.finish(true)
+
+	data, err := os.ReadFile(output)
+	require.NoError(t, err)
+	require.Empty(t, data)
+}
+
+func TestResumeCancelledDomain(t *testing.T) {
+	dir := t.TempDir()
+	runner, err := NewRunner(&Options{
+		Sources:            []string{"echo"},
+		CustomSources:      []subscraping.Source{&echoSource{}},
+		ProviderConfig:     filepath.Join(dir, "provider-config.yaml"),
+		Threads:            10,
+		Timeout:            10,
+		MaxEnumerationTime: 1,
+		Output:             io.Discard,
+	})
+	require.NoError(t, err)
+	runner.resume, err = loadResumeState(filepath.Join(dir, "resume.jsonl"), "")
+	require.NoError(t, err)
+	defer runner.resume.finish(true)
+
+	require.NoError(t, runner.EnumerateSingleDomainWithCtx(context.Background(), "example.com", []io.Writer{io.Discard}))
+	require.True(t, runner.resume.isCompleted("example.com"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.NoError(t, runner.EnumerateSingleDomainWithCtx(ctx, "hackerone.com", []io.Writer{io.Discard}))
+	require.False(t, runner.resume.isCompleted("hackerone.com"), "a cancelled domain must not be checkpointed")
+}
+
+func TestValidateResumeStream(t *testing.T) {
+	options := &Options{Domain: []string{"example.com"}, Threads: 10, Timeout: 10, Resume: true, Stream: true, DomainConcurrency: 2}
+	require.Error(t, options.validateOptions())
+
+	options.DomainConcurrency = 1
+	require.NoError(t, options.validateOptions())
+}
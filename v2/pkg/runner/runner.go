@@ -10,15 +10,19 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/pkg/errors"
+	"golang.org/x/exp/slices"
 
 	"github.com/projectdiscovery/gologger"
 	contextutil "github.com/projectdiscovery/utils/context"
 	fileutil "github.com/projectdiscovery/utils/file"
 	mapsutil "github.com/projectdiscovery/utils/maps"
+	syncutil "github.com/projectdiscovery/utils/sync"
 
 	"github.com/projectdiscovery/subfinder/v2/pkg/passive"
+	"github.com/projectdiscovery/subfinder/v2/pkg/permutation"
 	"github.com/projectdiscovery/subfinder/v2/pkg/resolve"
 	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
 )
@@ -30,6 +34,23 @@ type Runner struct {
 	passiveAgent   *passive.Agent
 	resolverClient *resolve.Resolver
 	rateLimit      *subscraping.CustomRateLimit
+	statistics     *mapsutil.SyncLockMap[string, *subscraping.RunStatistics]
+	// wildcards holds the wildcard zones found by domain with RemoveWildcard
+	wildcards      map[string][]resolve.WildcardZone
+	wildcardsMutex sync.Mutex
+	responseCache  *subscraping.ResponseCache
+	cassette       *subscraping.Cassette
+	budgets        *subscraping.Budgets
+	// activeSources are the active sources created for the runner
+	activeSources []subscraping.ActiveSource
+	// permutations builds the candidates resolved after the sources with Permute
+	permutations *permutation.Generator
+	// providerConfig is the location the API keys were loaded from
+	providerConfig string
+	// resume records the completed domains when resuming is asked
+	resume *resumeState
+	// outputMutex serializes writes and callbacks of concurrently enumerated domains
+	outputMutex sync.Mutex
 }
 
 // NewRunner creates a new runner struct instance by parsing
@@ -37,17 +58,22 @@ type Runner struct {
 // and setting up loggers, etc.
 func NewRunner(options *Options) (*Runner, error) {
 	options.ConfigureOutput()
-	runner := &Runner{options: options}
+	runner := &Runner{
+		options:    options,
+		statistics: mapsutil.NewSyncLockMap[string, *subscraping.RunStatistics](),
+		wildcards:  make(map[string][]resolve.WildcardZone),
+	}
 
 	// Check if the application loading with any provider configuration, then take it
 	// Otherwise load the default provider config
 	if fileutil.FileExists(options.ProviderConfig) {
 		gologger.Info().Msgf("Loading provider config from %s", options.ProviderConfig)
-		options.loadProvidersFrom(options.ProviderConfig)
+		runner.providerConfig = options.ProviderConfig
 	} else {
 		gologger.Info().Msgf("Loading provider config from the default location: %s", defaultProviderConfigLocation)
-		options.loadProvidersFrom(defaultProviderConfigLocation)
+		runner.providerConfig = defaultProviderConfigLocation
 	}
+	options.loadProvidersFrom(runner.providerConfig)
 
 	// Initialize the passive subdomain enumeration engine
 	runner.initializePassiveEngine()
@@ -57,6 +83,49 @@ func NewRunner(options *Options) (*Runner, error) {
 	if err != nil {
 		return nil, err
 	}
+	runner.initializeActiveSources()
+
+	// Initialize the permutations of the subdomains found
+	if options.Permute {
+		if err := runner.initializePermutations(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Initialize the cassette recording or replaying the sources
+	switch {
+	case options.Record != "":
+		runner.cassette, err = subscraping.NewCassette(options.Record, subscraping.CassetteRecord)
+	case options.Replay != "":
+		runner.cassette, err = subscraping.NewCassette(options.Replay, subscraping.CassetteReplay)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Initialize the response cache of the sources with a cache TTL,
+	// replayed responses must not be served from the cache
+	if !options.NoCache && options.Replay == "" && len(options.cacheTTLs) > 0 {
+		runner.responseCache, err = subscraping.NewResponseCache(defaultCacheLocation, options.cacheTTLs, options.RefreshCache)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Initialize the request budgets of the provider config
+	if len(options.budgets) > 0 {
+		budgets := make(map[string][]subscraping.Budget, len(options.budgets))
+		for name, value := range options.budgets {
+			budgets[name], err = subscraping.ParseBudgets(value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid budget for %s", name)
+			}
+		}
+		runner.budgets, err = subscraping.NewBudgets(defaultBudgetLocation, budgets)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	// Initialize the custom rate limit
 	runner.rateLimit = &subscraping.CustomRateLimit{
@@ -76,12 +145,14 @@ func NewRunner(options *Options) (*Runner, error) {
 
 // RunEnumeration wraps RunEnumerationWithCtx with an empty context
 func (r *Runner) RunEnumeration() error {
-	ctx, _ := contextutil.WithValues(context.Background(), contextutil.ContextArg("All"), contextutil.ContextArg(strconv.FormatBool(r.options.All)))
-	return r.RunEnumerationWithCtx(ctx)
+	return r.RunEnumerationWithCtx(context.Background())
 }
 
 // RunEnumerationWithCtx runs the subdomain enumeration flow on the targets specified
 func (r *Runner) RunEnumerationWithCtx(ctx context.Context) error {
+	if _, ok := ctx.Value(contextutil.ContextArg("All")).(contextutil.ContextArg); !ok {
+		ctx, _ = contextutil.WithValues(ctx, contextutil.ContextArg("All"), contextutil.ContextArg(strconv.FormatBool(r.options.All)))
+	}
 	outputs := []io.Writer{r.options.Output}
 
 	if len(r.options.Domain) > 0 {
@@ -114,8 +185,54 @@ func (r *Runner) EnumerateMultipleDomains(reader io.Reader, writers []io.Writer)
 }
 
 // EnumerateMultipleDomainsWithCtx enumerates subdomains for multiple domains
-// We keep enumerating subdomains for a given domain until we reach an error
+// We keep enumerating subdomains for a given domain until we reach an error.
+// Up to DomainConcurrency domains are enumerated at the same time, sharing
+// the per-source rate limiters and the circuit breaker.
 func (r *Runner) EnumerateMultipleDomainsWithCtx(ctx context.Context, reader io.Reader, writers []io.Writer) error {
+	multiRateLimiter, err := r.passiveAgent.NewMultiRateLimiter(ctx, r.options.RateLimit, r.rateLimit)
+	if err != nil {
+		return err
+	}
+	defer multiRateLimiter.Stop()
+
+	// Sources failing on consecutive domains are disabled for the rest of the run
+	circuitBreaker := passive.NewCircuitBreaker(r.options.MaxSourceFailures)
+
+	wg, err := syncutil.New(syncutil.WithSize(max(r.options.DomainConcurrency, 1)))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		errMutex sync.Mutex
+		firstErr error
+	)
+	setErr := func(err error) {
+		errMutex.Lock()
+		defer errMutex.Unlock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+
+	// The checkpoint file is removed once every domain has been completed
+	if r.options.Resume {
+		r.resume, err = loadResumeState(resumeFileName(r.options), r.options.OutputFile)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := r.resume.finish(ctx.Err() == nil && firstErr == nil); err != nil {
+				gologger.Warning().Msgf("Could not close resume file: %s\n", err)
+			}
+			r.resume = nil
+		}()
+	}
+
 	scanner := bufio.NewScanner(reader)
 	ip, _ := regexp.Compile(`^([0-9\.]+$)`)
 	for scanner.Scan() {
@@ -124,46 +241,58 @@ func (r *Runner) EnumerateMultipleDomainsWithCtx(ctx context.Context, reader io.
 		if errors.Is(err, ErrEmptyInput) || (r.options.ExcludeIps && isIp) {
 			continue
 		}
+		if r.resume != nil && r.resume.isCompleted(domain) {
+			continue
+		}
 
-		var file *os.File
-		// If the user has specified an output file, use that output file instead
-		// of creating a new output file for each domain. Else create a new file
-		// for each domain in the directory.
-		if r.options.OutputFile != "" {
-			outputWriter := NewOutputWriter(r.options.JSON)
-			file, err = outputWriter.createFile(r.options.OutputFile, true)
-			if err != nil {
-				gologger.Error().Msgf("Could not create file %s for %s: %s\n", r.options.OutputFile, r.options.Domain, err)
-				return err
-			}
-
-			err = r.EnumerateSingleDomainWithCtx(ctx, domain, append(writers, file))
-
-			file.Close()
-		} else if r.options.OutputDirectory != "" {
-			outputFile := path.Join(r.options.OutputDirectory, domain)
-			if r.options.JSON {
-				outputFile += ".json"
-			} else {
-				outputFile += ".txt"
-			}
+		if err := wg.AddWithContext(ctx); err != nil {
+			break
+		}
+		go func(domain string) {
+			defer wg.Done()
 
-			outputWriter := NewOutputWriter(r.options.JSON)
-			file, err = outputWriter.createFile(outputFile, false)
-			if err != nil {
-				gologger.Error().Msgf("Could not create file %s for %s: %s\n", r.options.OutputFile, r.options.Domain, err)
-				return err
+			if err := r.enumerateDomainToOutputs(ctx, domain, writers, passive.WithMultiRateLimiter(multiRateLimiter), passive.WithCircuitBreaker(circuitBreaker)); err != nil {
+				setErr(err)
 			}
+		}(domain)
+	}
+	wg.Wait()
+	return firstErr
+}
 
-			err = r.EnumerateSingleDomainWithCtx(ctx, domain, append(writers, file))
+// enumerateDomainToOutputs enumerates a single domain, writing the results to the
+// given writers and to the output file or directory requested by the user
+func (r *Runner) enumerateDomainToOutputs(ctx context.Context, domain string, writers []io.Writer, options ...passive.EnumerateOption) error {
+	// If the user has specified an output file, use that output file instead
+	// of creating a new output file for each domain. Else create a new file
+	// for each domain in the directory.
+	if r.options.OutputFile != "" {
+		outputWriter := NewOutputWriter(r.options.JSON)
+		file, err := outputWriter.createFile(r.options.OutputFile, true)
+		if err != nil {
+			gologger.Error().Msgf("Could not create file %s for %s: %s\n", r.options.OutputFile, domain, err)
+			return err
+		}
+		defer file.Close()
 
-			file.Close()
+		return r.enumerateSingleDomainWithCtx(ctx, domain, append(slices.Clip(writers), file), options...)
+	} else if r.options.OutputDirectory != "" {
+		outputFile := path.Join(r.options.OutputDirectory, domain)
+		if r.options.JSON {
+			outputFile += ".json"
 		} else {
-			err = r.EnumerateSingleDomainWithCtx(ctx, domain, writers)
+			outputFile += ".txt"
 		}
+
+		outputWriter := NewOutputWriter(r.options.JSON)
+		file, err := outputWriter.createFile(outputFile, false)
 		if err != nil {
+			gologger.Error().Msgf("Could not create file %s for %s: %s\n", outputFile, domain, err)
 			return err
 		}
+		defer file.Close()
+
+		return r.enumerateSingleDomainWithCtx(ctx, domain, append(slices.Clip(writers), file), options...)
 	}
-	return nil
+	return r.enumerateSingleDomainWithCtx(ctx, domain, writers, options...)
 }
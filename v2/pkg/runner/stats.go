@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/subfinder/v2/pkg/resolve"
 	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
 	"golang.org/x/exp/maps"
 )
@@ -18,22 +19,89 @@ func printStatistics(stats map[string]subscraping.Statistics) {
 
 	var lines []string
 	var skipped []string
+	var timedOut []string
+	var disabled []string
+	var cached []string
+	var keys []string
+	var budgets []string
+	var findings []string
 
 	for _, source := range sources {
 		sourceStats := stats[source]
-		if sourceStats.Skipped {
+		if sourceStats.Disabled != "" {
+			disabled = append(disabled, fmt.Sprintf(" %-20s %s", source, sourceStats.Disabled))
+		}
+		if sourceStats.Skipped && sourceStats.Budget == "" {
 			skipped = append(skipped, fmt.Sprintf(" %s", source))
-		} else {
-			lines = append(lines, fmt.Sprintf(" %-20s %-10s %10d %10d", source, sourceStats.TimeTaken.Round(time.Millisecond).String(), sourceStats.Results, sourceStats.Errors))
+		} else if !sourceStats.Skipped {
+			lines = append(lines, fmt.Sprintf(" %-20s %-10s %10d %10d %10d", source, sourceStats.TimeTaken.Round(time.Millisecond).String(), sourceStats.Results, sourceStats.Errors, sourceStats.Retries))
+		}
+		if sourceStats.CacheHits > 0 || sourceStats.CacheMisses > 0 {
+			cached = append(cached, fmt.Sprintf(" %-20s %10d %12d", source, sourceStats.CacheHits, sourceStats.CacheMisses))
+		}
+		labels := maps.Keys(sourceStats.Keys)
+		sort.Strings(labels)
+		for _, label := range labels {
+			usage := sourceStats.Keys[label]
+			status := "ok"
+			if usage.Exhausted {
+				status = "exhausted"
+			}
+			keys = append(keys, fmt.Sprintf(" %-20s %-20s %10d %10d  %s", source, label, usage.Requests, usage.Rejected, status))
+		}
+		if sourceStats.Budget != "" {
+			budgets = append(budgets, fmt.Sprintf(" %-20s %s", source, sourceStats.Budget))
+		}
+		if sourceStats.TimedOut {
+			timedOut = append(timedOut, fmt.Sprintf(" %s", source))
+		}
+		for _, finding := range sourceStats.Findings {
+			findings = append(findings, fmt.Sprintf(" %-20s %s", source, finding))
 		}
 	}
 
 	if len(lines) > 0 {
-		gologger.Print().Msgf("\n Source               Duration      Results     Errors\n%s\n", strings.Repeat("─", 56))
+		gologger.Print().Msgf("\n Source               Duration      Results     Errors    Retries\n%s\n", strings.Repeat("─", 67))
 		gologger.Print().Msgf(strings.Join(lines, "\n"))
 		gologger.Print().Msgf("\n")
 	}
 
+	if len(cached) > 0 {
+		gologger.Print().Msgf("\n Source               Cache hits  Cache misses\n%s\n", strings.Repeat("─", 46))
+		gologger.Print().Msgf(strings.Join(cached, "\n"))
+		gologger.Print().Msgf("\n")
+	}
+
+	if len(keys) > 0 {
+		gologger.Print().Msgf("\n Source               Key                    Requests   Rejected  Status\n%s\n", strings.Repeat("─", 76))
+		gologger.Print().Msgf(strings.Join(keys, "\n"))
+		gologger.Print().Msgf("\n")
+	}
+
+	if len(findings) > 0 {
+		gologger.Print().Msgf("\n The following findings were reported by the sources...\n\n")
+		gologger.Print().Msgf(strings.Join(findings, "\n"))
+		gologger.Print().Msgf("\n\n")
+	}
+
+	if len(timedOut) > 0 {
+		gologger.Print().Msgf("\n The following sources timed out (partial)...\n\n")
+		gologger.Print().Msgf(strings.Join(timedOut, "\n"))
+		gologger.Print().Msgf("\n\n")
+	}
+
+	if len(disabled) > 0 {
+		gologger.Print().Msgf("\n The following sources were disabled for the remaining domains...\n\n")
+		gologger.Print().Msgf(strings.Join(disabled, "\n"))
+		gologger.Print().Msgf("\n\n")
+	}
+
+	if len(budgets) > 0 {
+		gologger.Print().Msgf("\n The following sources reached their request budget (partial or skipped)...\n\n")
+		gologger.Print().Msgf(strings.Join(budgets, "\n"))
+		gologger.Print().Msgf("\n\n")
+	}
+
 	if len(skipped) > 0 {
 		gologger.Print().Msgf("\n The following sources were included but skipped...\n\n")
 		gologger.Print().Msgf(strings.Join(skipped, "\n"))
@@ -41,6 +109,48 @@ func printStatistics(stats map[string]subscraping.Statistics) {
 	}
 }
 
+// printWildcards prints the wildcard zones found and the hosts they filtered,
+// or kept as uncertain
+func printWildcards(zones []resolve.WildcardZone) {
+	if len(zones) == 0 {
+		return
+	}
+
+	lines := make([]string, 0, len(zones))
+	for _, zone := range zones {
+		lines = append(lines, fmt.Sprintf(" %-40s %10d %10d  %s", "*."+zone.Zone, zone.Filtered, zone.Uncertain, strings.Join(zone.Answers, ", ")))
+	}
+	gologger.Print().Msgf("\n Wildcard zone                              Filtered  Uncertain  Answers\n%s\n", strings.Repeat("─", 87))
+	gologger.Print().Msgf(strings.Join(lines, "\n"))
+	gologger.Print().Msgf("\n")
+}
+
+// GetStatistics returns the source statistics summed over every enumerated
+// domain, keyed by source name
+//
+// Deprecated: use GetDomainStatistics to get the statistics of each domain.
 func (r *Runner) GetStatistics() map[string]subscraping.Statistics {
-	return r.passiveAgent.GetStatistics()
+	var runs []map[string]subscraping.Statistics
+	for _, runStatistics := range r.statistics.GetAll() {
+		runs = append(runs, runStatistics.All())
+	}
+	return subscraping.MergeStatistics(runs...)
+}
+
+// GetDomainStatistics returns the source statistics of every enumerated
+// domain, keyed by domain and then by source name
+func (r *Runner) GetDomainStatistics() map[string]map[string]subscraping.Statistics {
+	stats := make(map[string]map[string]subscraping.Statistics)
+	for domain, runStatistics := range r.statistics.GetAll() {
+		stats[domain] = runStatistics.All()
+	}
+	return stats
+}
+
+// GetWildcards returns the wildcard zones found for every domain enumerated
+// with RemoveWildcard, keyed by domain
+func (r *Runner) GetWildcards() map[string][]resolve.WildcardZone {
+	r.wildcardsMutex.Lock()
+	defer r.wildcardsMutex.Unlock()
+	return maps.Clone(r.wildcards)
 }
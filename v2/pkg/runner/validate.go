@@ -4,13 +4,17 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/projectdiscovery/gologger"
 	"github.com/projectdiscovery/gologger/formatter"
 	"github.com/projectdiscovery/gologger/levels"
 	"github.com/projectdiscovery/subfinder/v2/pkg/passive"
-	mapsutil "github.com/projectdiscovery/utils/maps"
+	"github.com/projectdiscovery/subfinder/v2/pkg/permutation"
+	"github.com/projectdiscovery/subfinder/v2/pkg/resolve"
+	fileutil "github.com/projectdiscovery/utils/file"
 	sliceutil "github.com/projectdiscovery/utils/slice"
 )
 
@@ -18,7 +22,7 @@ import (
 func (options *Options) validateOptions() error {
 	// Check if domain, list of domains, or stdin info was provided.
 	// If none was provided, then return.
-	if len(options.Domain) == 0 && options.DomainsFile == "" && !options.Stdin {
+	if len(options.Domain) == 0 && options.DomainsFile == "" && !options.Stdin && !options.CheckKeys {
 		return errors.New("no input list provided")
 	}
 
@@ -27,6 +31,11 @@ func (options *Options) validateOptions() error {
 		return errors.New("both verbose and silent mode specified")
 	}
 
+	// Both record and replay flags were used
+	if options.Record != "" && options.Replay != "" {
+		return errors.New("both record and replay mode specified")
+	}
+
 	// Validate threads and options
 	if options.Threads == 0 {
 		return errors.New("threads cannot be zero")
@@ -34,12 +43,67 @@ func (options *Options) validateOptions() error {
 	if options.Timeout == 0 {
 		return errors.New("timeout cannot be zero")
 	}
+	if options.DomainConcurrency < 0 {
+		return errors.New("domain concurrency cannot be negative")
+	}
+	// The streamed results of concurrent domains interleave in the output file,
+	// which then can't be truncated after the last completed domain
+	if options.Resume && options.Stream && options.DomainConcurrency > 1 {
+		return errors.New("resume flag can't be used with stream when domains are enumerated concurrently")
+	}
 
 	// Always remove wildcard with hostip
 	if options.HostIP && !options.RemoveWildcard {
 		return errors.New("hostip flag must be used with RemoveWildcard option")
 	}
 
+	if options.Wordlist != "" && !fileutil.FileExists(options.Wordlist) {
+		return fmt.Errorf("wordlist %s does not exist", options.Wordlist)
+	}
+
+	if !options.Permute && (len(options.PermutePatterns) > 0 || options.PermuteWords != "") {
+		return errors.New("permutation flags must be used with permute option")
+	}
+	if options.Permute {
+		if options.MaxPermutations <= 0 {
+			return errors.New("max permutations must be positive")
+		}
+		if options.PermuteWords != "" && !fileutil.FileExists(options.PermuteWords) {
+			return fmt.Errorf("permutation words %s do not exist", options.PermuteWords)
+		}
+		options.permutePatterns = nil
+		for _, name := range options.PermutePatterns {
+			pattern := permutation.Pattern(strings.ToLower(name))
+			if !sliceutil.Contains(permutation.Patterns, pattern) {
+				return fmt.Errorf("invalid permutation pattern %s", name)
+			}
+			if !sliceutil.Contains(options.permutePatterns, pattern) {
+				options.permutePatterns = append(options.permutePatterns, pattern)
+			}
+		}
+	}
+
+	if options.WildcardHTTP && !options.RemoveWildcard {
+		return errors.New("wildcard-http flag must be used with RemoveWildcard option")
+	}
+
+	if len(options.RecordTypes) > 0 {
+		if !options.RemoveWildcard {
+			return errors.New("record-type flag must be used with RemoveWildcard option")
+		}
+		// The A records are always queried to detect wildcards
+		options.recordTypes = []uint16{resolve.RecordTypes["a"]}
+		for _, name := range options.RecordTypes {
+			recordType, ok := resolve.RecordTypes[strings.ToLower(name)]
+			if !ok {
+				return fmt.Errorf("invalid record type %s", name)
+			}
+			if !sliceutil.Contains(options.recordTypes, recordType) {
+				options.recordTypes = append(options.recordTypes, recordType)
+			}
+		}
+	}
+
 	if options.Match != nil {
 		options.matchRegexes = make([]*regexp.Regexp, len(options.Match))
 		var err error
@@ -59,14 +123,78 @@ func (options *Options) validateOptions() error {
 		}
 	}
 
-	sources := mapsutil.GetKeys(passive.NameSourceMap)
+	var sources []string
+	for _, source := range passive.Sources(options.CustomSources...) {
+		sources = append(sources, strings.ToLower(source.Name()))
+	}
 	for source := range options.RateLimits.AsMap() {
 		if !sliceutil.Contains(sources, source) {
 			return fmt.Errorf("invalid source %s specified in -rls flag", source)
 		}
 	}
+
+	var err error
+	options.sourceTimeouts, err = parseSourceValues(options.SourceTimeouts, "-st", sources, func(value string) (time.Duration, error) {
+		timeout, err := time.ParseDuration(value)
+		if err == nil && timeout <= 0 {
+			err = errors.New("duration must be positive")
+		}
+		return timeout, err
+	})
+	if err != nil {
+		return err
+	}
+
+	options.cacheTTLs, err = parseSourceValues(options.CacheTTLs, "-ct", sources, func(value string) (time.Duration, error) {
+		ttl, err := time.ParseDuration(value)
+		if err == nil && ttl <= 0 {
+			err = errors.New("duration must be positive")
+		}
+		return ttl, err
+	})
+	if err != nil {
+		return err
+	}
+
+	if options.MaxSourceFailures < 0 {
+		return errors.New("max source failures cannot be negative")
+	}
+	if options.Retries < 0 {
+		return errors.New("retries cannot be negative")
+	}
+	options.sourceRetries, err = parseSourceValues(options.SourceRetries, "-sr", sources, func(value string) (int, error) {
+		retries, err := strconv.Atoi(value)
+		if err == nil && retries < 0 {
+			err = errors.New("retries cannot be negative")
+		}
+		return retries, err
+	})
+	if err != nil {
+		return err
+	}
 	return nil
 }
+
+// parseSourceValues parses per-source flag values given in source=value format
+func parseSourceValues[T any](values []string, flagName string, sources []string, parse func(value string) (T, error)) (map[string]T, error) {
+	sourceValues := make(map[string]T, len(values))
+	for _, sourceValue := range values {
+		source, value, ok := strings.Cut(sourceValue, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid value %s specified in %s flag, expected source=value", sourceValue, flagName)
+		}
+		if !sliceutil.Contains(sources, source) {
+			return nil, fmt.Errorf("invalid source %s specified in %s flag", source, flagName)
+		}
+		parsed, err := parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %s specified in %s flag for %s: %s", value, flagName, source, err)
+		}
+		sourceValues[source] = parsed
+	}
+	return sourceValues, nil
+}
+
 func stripRegexString(val string) string {
 	val = strings.ReplaceAll(val, ".", "\\.")
 	val = strings.ReplaceAll(val, "*", ".*")
@@ -46,7 +46,7 @@ func NewSession(domain string, proxy string, multiRateLimiter *ratelimit.MultiLi
 		Timeout:   time.Duration(timeout) * time.Second,
 	}
 
-	session := &Session{Client: client}
+	session := &Session{Client: client, Statistics: NewRunStatistics(domain)}
 
 	// Initiate rate limit instance
 	session.MultiRateLimiter = multiRateLimiter
@@ -78,9 +78,115 @@ func (s *Session) SimplePost(ctx context.Context, postURL, contentType string, b
 	return s.HTTPRequest(ctx, http.MethodPost, postURL, "", map[string]string{"Content-Type": contentType}, body, BasicAuth{})
 }
 
-// HTTPRequest makes any HTTP request to a URL with extended parameters
+// HTTPRequest makes any HTTP request to a URL with extended parameters.
+// Transient failures are retried following the retry policy of the source.
 func (s *Session) HTTPRequest(ctx context.Context, method, requestURL, cookies string, headers map[string]string, body io.Reader, basicAuth BasicAuth) (*http.Response, error) {
-	req, err := http.NewRequestWithContext(ctx, method, requestURL, body)
+	// Buffer the body so that it can be sent again on retries
+	var bodyBytes []byte
+	if body != nil {
+		var err error
+		bodyBytes, err = io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sourceName := ctx.Value(CtxSourceArg).(string)
+	// The key is set by the key pool sending the request
+	key, _ := ctx.Value(ctxKeyArg).(string)
+	retryPolicy := s.retryPolicy(sourceName)
+
+	useCache := s.Cache.enabled(sourceName)
+	if useCache {
+		req, err := newHTTPRequest(ctx, method, requestURL, cookies, headers, bodyBytes, body != nil, basicAuth)
+		if err != nil {
+			return nil, err
+		}
+		if response, ok := s.Cache.get(sourceName, req, bodyBytes); ok {
+			s.Statistics.Update(sourceName, func(stats *Statistics) {
+				stats.CacheHits++
+			})
+			return response, nil
+		}
+		s.Statistics.Update(sourceName, func(stats *Statistics) {
+			stats.CacheMisses++
+		})
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := newHTTPRequest(ctx, method, requestURL, cookies, headers, bodyBytes, body != nil, basicAuth)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := s.takeBudget(sourceName, key); err != nil {
+			return nil, err
+		}
+		// Replayed requests don't reach the provider
+		if !s.replaying() {
+			mrlErr := s.MultiRateLimiter.Take(sourceName)
+			if mrlErr != nil {
+				return nil, mrlErr
+			}
+		}
+
+		response, err := s.doRequest(sourceName, req, bodyBytes)
+		if useCache && err == nil {
+			response, err = s.Cache.put(sourceName, req, bodyBytes, response)
+			if err != nil && response != nil {
+				gologger.Warning().Msgf("Could not cache response for %s: %s\n", sourceName, err)
+				err = nil
+			}
+		}
+		if attempt >= retryPolicy.MaxRetries || !isRetryable(ctx, response, err) {
+			return response, err
+		}
+		wait, ok := retryPolicy.wait(attempt, response)
+		if !ok {
+			return response, err
+		}
+		s.DiscardHTTPResponse(response)
+		s.Statistics.Update(sourceName, func(stats *Statistics) {
+			stats.Retries++
+		})
+		gologger.Debug().Msgf("Retrying request for %s in %s: %s\n", sourceName, wait, err)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// replaying returns true when the responses are replayed from a cassette
+func (s *Session) replaying() bool {
+	return s.Cassette != nil && s.Cassette.Mode == CassetteReplay
+}
+
+// takeBudget counts a request of a source, sent with the given key when not
+// empty, against the budgets of the source and of the key. Replayed requests
+// don't reach the provider and are not counted.
+func (s *Session) takeBudget(source, key string) error {
+	if s.replaying() {
+		return nil
+	}
+	return s.Budget.take(source, key)
+}
+
+func (s *Session) retryPolicy(sourceName string) RetryPolicy {
+	if retryPolicy, ok := s.SourceRetryPolicies[sourceName]; ok {
+		return retryPolicy
+	}
+	return s.RetryPolicy
+}
+
+func newHTTPRequest(ctx context.Context, method, requestURL, cookies string, headers map[string]string, body []byte, hasBody bool, basicAuth BasicAuth) (*http.Request, error) {
+	var bodyReader io.Reader
+	if hasBody {
+		bodyReader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, bodyReader)
 	if err != nil {
 		return nil, err
 	}
@@ -101,14 +207,7 @@ func (s *Session) HTTPRequest(ctx context.Context, method, requestURL, cookies s
 	for key, value := range headers {
 		req.Header.Set(key, value)
 	}
-
-	sourceName := ctx.Value(CtxSourceArg).(string)
-	mrlErr := s.MultiRateLimiter.Take(sourceName)
-	if mrlErr != nil {
-		return nil, mrlErr
-	}
-
-	return httpRequestWrapper(s.Client, req)
+	return req, nil
 }
 
 // DiscardHTTPResponse discards the response content by demand
@@ -129,8 +228,19 @@ func (s *Session) Close() {
 	s.Client.CloseIdleConnections()
 }
 
-func httpRequestWrapper(client *http.Client, request *http.Request) (*http.Response, error) {
-	response, err := client.Do(request)
+// doRequest sends a request, or replays it from the cassette, and turns
+// any non-200 response into an error
+func (s *Session) doRequest(sourceName string, request *http.Request, body []byte) (*http.Response, error) {
+	var response *http.Response
+	var err error
+	if s.replaying() {
+		response, err = s.Cassette.replay(sourceName, request)
+	} else {
+		response, err = s.Client.Do(request)
+		if err == nil && s.Cassette != nil {
+			response, err = s.Cassette.record(sourceName, request, body, response)
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -143,7 +253,7 @@ func httpRequestWrapper(client *http.Client, request *http.Request) (*http.Respo
 			_, _ = buffer.ReadFrom(response.Body)
 			return fmt.Sprintf("Response for failed request against %s:\n%s", requestURL, buffer.String())
 		})
-		return response, fmt.Errorf("unexpected status code %d received from %s", response.StatusCode, requestURL)
+		return response, &StatusCodeError{StatusCode: response.StatusCode, URL: requestURL}
 	}
 	return response, nil
 }
@@ -0,0 +1,293 @@
+package subscraping
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// BudgetPeriod is the calendar period, in UTC, over which the requests of a budget are counted
+type BudgetPeriod string
+
+const (
+	BudgetDaily   BudgetPeriod = "day"
+	BudgetMonthly BudgetPeriod = "month"
+)
+
+// Budget caps the number of requests sent during a period
+type Budget struct {
+	Limit  int
+	Period BudgetPeriod
+}
+
+func (b Budget) String() string {
+	return fmt.Sprintf("%d/%s", b.Limit, b.Period)
+}
+
+// window returns the identifier of the period containing t and the start of the next one
+func (b Budget) window(t time.Time) (string, time.Time) {
+	t = t.UTC()
+	if b.Period == BudgetDaily {
+		start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return "day:" + start.Format("2006-01-02"), start.AddDate(0, 0, 1)
+	}
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return "month:" + start.Format("2006-01"), start.AddDate(0, 1, 0)
+}
+
+// ParseBudgets parses comma separated budgets such as 100/day,2000/month
+func ParseBudgets(value string) ([]Budget, error) {
+	var budgets []Budget
+	for _, part := range strings.Split(value, ",") {
+		limit, period, ok := strings.Cut(strings.TrimSpace(part), "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid budget %q, expected requests/period", part)
+		}
+		var budget Budget
+		var err error
+		budget.Limit, err = strconv.Atoi(limit)
+		if err != nil || budget.Limit < 0 {
+			return nil, fmt.Errorf("invalid budget %q, the number of requests must be a non-negative integer", part)
+		}
+		switch strings.ToLower(period) {
+		case "d", "day", "daily":
+			budget.Period = BudgetDaily
+		case "m", "month", "monthly":
+			budget.Period = BudgetMonthly
+		default:
+			return nil, fmt.Errorf("invalid budget %q, the period must be day or month", part)
+		}
+		budgets = append(budgets, budget)
+	}
+	return budgets, nil
+}
+
+// ErrBudgetReached is matched by the errors of the requests over a budget
+var ErrBudgetReached = errors.New("budget reached")
+
+// BudgetError is returned instead of sending a request over the budget of a source or of one of its keys
+type BudgetError struct {
+	Source string
+	// Key is the masked key the budget applies to, empty for the budget of the source
+	Key    string
+	Budget Budget
+	// Reset is when the budget allows requests again
+	Reset time.Time
+}
+
+func (e *BudgetError) Error() string {
+	if e.Key != "" {
+		return fmt.Sprintf("%s budget of %s key %s reached", e.Budget, e.Source, e.Key)
+	}
+	return fmt.Sprintf("%s budget of %s reached", e.Budget, e.Source)
+}
+
+// Describe returns the budget reached without the source name
+func (e *BudgetError) Describe() string {
+	if e.Key != "" {
+		return fmt.Sprintf("%s for key %s", e.Budget, e.Key)
+	}
+	return e.Budget.String()
+}
+
+func (e *BudgetError) Unwrap() error {
+	return ErrBudgetReached
+}
+
+// Budgets enforces the request budgets of the sources and of their keys.
+// The usage is persisted to a file so that the budgets hold across runs,
+// keys are only stored hashed. It is safe for concurrent use.
+type Budgets struct {
+	file string
+
+	mutex   sync.Mutex
+	budgets map[string][]Budget
+	// usage holds the requests sent by budget identifier, then by period
+	usage map[string]map[string]int
+	// unsaved holds the requests sent since the last save, Save adds them to
+	// the usage on disk which other processes may have updated meanwhile
+	unsaved map[string]map[string]int
+}
+
+// NewBudgets loads the usage persisted to file of the given budgets. They are
+// given by source name, or by source and key as source:key for the budget of a key.
+func NewBudgets(file string, budgets map[string][]Budget) (*Budgets, error) {
+	b := &Budgets{file: file, budgets: make(map[string][]Budget), unsaved: make(map[string]map[string]int)}
+	for name, sourceBudgets := range budgets {
+		source, key, _ := strings.Cut(name, ":")
+		b.budgets[budgetID(source, key)] = sourceBudgets
+	}
+
+	var err error
+	b.usage, err = readBudgetUsage(file)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// readBudgetUsage reads the usage persisted to file, which is empty when the file doesn't exist
+func readBudgetUsage(file string) (map[string]map[string]int, error) {
+	usage := make(map[string]map[string]int)
+	data, err := os.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := jsoniter.Unmarshal(data, &usage); err != nil {
+			return nil, fmt.Errorf("could not read budget usage from %s: %w", file, err)
+		}
+	}
+	return usage, nil
+}
+
+// addUsage adds count requests to the usage of a budget during a period
+func addUsage(usage map[string]map[string]int, id, period string, count int) {
+	if usage[id] == nil {
+		usage[id] = make(map[string]int)
+	}
+	usage[id][period] += count
+}
+
+// budgetID identifies the budget of a source, or of one of its keys by a hash
+// of its provider config entry, as two keys can have the same masked value
+func budgetID(source, key string) string {
+	if key == "" {
+		return source
+	}
+	hash := sha256.Sum256([]byte(key))
+	return source + ":" + hex.EncodeToString(hash[:16])
+}
+
+// Reached returns the error of the source budget when it was reached
+func (b *Budgets) Reached(source string) (*BudgetError, bool) {
+	if b == nil {
+		return nil, false
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	budgetErr := b.reached(source, "", time.Now())
+	return budgetErr, budgetErr != nil
+}
+
+func (b *Budgets) reached(source, key string, now time.Time) *BudgetError {
+	id := budgetID(source, key)
+	for _, budget := range b.budgets[id] {
+		period, reset := budget.window(now)
+		if b.usage[id][period] >= budget.Limit {
+			budgetErr := &BudgetError{Source: source, Budget: budget, Reset: reset}
+			if key != "" {
+				budgetErr.Key = MaskKey(key)
+			}
+			return budgetErr
+		}
+	}
+	return nil
+}
+
+// take counts a request of a source, sent with the given key when not empty,
+// against the budgets of the source and of the key, unless one of them is reached
+func (b *Budgets) take(source, key string) error {
+	if b == nil {
+		return nil
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	now := time.Now()
+	ids := []string{budgetID(source, "")}
+	if budgetErr := b.reached(source, "", now); budgetErr != nil {
+		return budgetErr
+	}
+	if key != "" {
+		if budgetErr := b.reached(source, key, now); budgetErr != nil {
+			return budgetErr
+		}
+		ids = append(ids, budgetID(source, key))
+	}
+	for _, id := range ids {
+		for _, budget := range b.budgets[id] {
+			period, _ := budget.window(now)
+			addUsage(b.usage, id, period, 1)
+			addUsage(b.unsaved, id, period, 1)
+		}
+	}
+	return nil
+}
+
+// Save adds the requests sent since the last save to the usage persisted to
+// the file, so that processes sharing the file don't bypass the budgets, and
+// reloads the usage of the other processes. Only the current periods are kept.
+func (b *Budgets) Save() error {
+	if b == nil {
+		return nil
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	usage, err := readBudgetUsage(b.file)
+	if err != nil {
+		return err
+	}
+	for id, periods := range b.unsaved {
+		for period, count := range periods {
+			addUsage(usage, id, period, count)
+		}
+	}
+
+	now := time.Now()
+	day, _ := Budget{Period: BudgetDaily}.window(now)
+	month, _ := Budget{Period: BudgetMonthly}.window(now)
+	for id, periods := range usage {
+		for period := range periods {
+			if period != day && period != month {
+				delete(periods, period)
+			}
+		}
+		if len(periods) == 0 {
+			delete(usage, id)
+		}
+	}
+
+	data, err := jsoniter.Marshal(usage)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(b.file), os.ModePerm); err != nil {
+		return err
+	}
+	// Write to a temporary file of this process first so that an interrupted
+	// run doesn't lose the usage and concurrent saves don't mix their writes
+	tmp, err := os.CreateTemp(filepath.Dir(b.file), filepath.Base(b.file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), b.file)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	b.usage = usage
+	b.unsaved = make(map[string]map[string]int)
+	return nil
+}
@@ -0,0 +1,129 @@
+package subscraping
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBudgets(t *testing.T) {
+	budgets, err := ParseBudgets("100/day, 2000/month")
+	require.NoError(t, err)
+	require.Equal(t, []Budget{{Limit: 100, Period: BudgetDaily}, {Limit: 2000, Period: BudgetMonthly}}, budgets)
+
+	for _, invalid := range []string{"100", "-1/day", "100/week", "many/month"} {
+		_, err := ParseBudgets(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestBudgetsPersistAcrossRuns(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "budgets.json")
+	config := map[string][]Budget{"securitytrails": {{Limit: 2, Period: BudgetMonthly}}}
+
+	budgets, err := NewBudgets(file, config)
+	require.NoError(t, err)
+	require.NoError(t, budgets.take("securitytrails", ""))
+	require.NoError(t, budgets.Save())
+
+	// A new run starts from the persisted usage
+	budgets, err = NewBudgets(file, config)
+	require.NoError(t, err)
+	_, reached := budgets.Reached("securitytrails")
+	require.False(t, reached)
+	require.NoError(t, budgets.take("securitytrails", ""))
+
+	err = budgets.take("securitytrails", "")
+	require.ErrorIs(t, err, ErrBudgetReached)
+	budgetErr, reached := budgets.Reached("securitytrails")
+	require.True(t, reached)
+	require.Equal(t, "2/month", budgetErr.Describe())
+
+	require.NoError(t, budgets.take("shodan", ""), "sources without budget are not limited")
+}
+
+func TestBudgetsSaveMergesConcurrentRuns(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "budgets.json")
+	config := map[string][]Budget{"securitytrails": {{Limit: 3, Period: BudgetDaily}}}
+
+	// Two processes sharing the file both start from an empty usage
+	first, err := NewBudgets(file, config)
+	require.NoError(t, err)
+	second, err := NewBudgets(file, config)
+	require.NoError(t, err)
+
+	require.NoError(t, first.take("securitytrails", ""))
+	require.NoError(t, second.take("securitytrails", ""))
+	require.NoError(t, first.Save())
+	require.NoError(t, second.Save())
+
+	// The save of the second process adds to the usage saved by the first and reloads it
+	require.NoError(t, second.take("securitytrails", ""))
+	require.ErrorIs(t, second.take("securitytrails", ""), ErrBudgetReached)
+	require.NoError(t, second.Save())
+
+	budgets, err := NewBudgets(file, config)
+	require.NoError(t, err)
+	_, reached := budgets.Reached("securitytrails")
+	require.True(t, reached)
+}
+
+func TestKeyPoolSkipsKeysOverBudget(t *testing.T) {
+	var failed atomic.Bool
+	var sent []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = append(sent, r.Header.Get("X-Key"))
+		// The first request fails once to be retried
+		if failed.CompareAndSwap(false, true) {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	session := newTestSession(t, "source")
+	session.SourceRetryPolicies = map[string]RetryPolicy{"source": NewRetryPolicy(1)}
+	var err error
+	session.Budget, err = NewBudgets(filepath.Join(t.TempDir(), "budgets.json"), map[string][]Budget{
+		"source":                {{Limit: 4, Period: BudgetDaily}},
+		"source:first-key-0001": {{Limit: 2, Period: BudgetDaily}},
+		"source:other-key-0001": {{Limit: 1, Period: BudgetDaily}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, MaskKey("first-key-0001"), MaskKey("other-key-0001"), "the keys must have the same masked value")
+
+	pool := NewStringKeyPool([]string{"first-key-0001", "other-key-0001"})
+	ctx := context.WithValue(context.Background(), CtxSourceArg, "source")
+	request := func(ctx context.Context, key string) (*http.Response, error) {
+		return session.Get(ctx, server.URL, "", map[string]string{"X-Key": key})
+	}
+
+	key := "first-key-0001"
+	for i := 0; i < 2; i++ {
+		resp, err := pool.Do(ctx, session, "source", &key, request)
+		require.NoError(t, err)
+		session.DiscardHTTPResponse(resp)
+	}
+	require.Equal(t, []string{"first-key-0001", "first-key-0001", "other-key-0001"}, sent, "the retry counts against the budget of the key")
+
+	_, err = pool.Do(ctx, session, "source", &key, request)
+	var budgetErr *BudgetError
+	require.True(t, errors.As(err, &budgetErr))
+	require.Equal(t, MaskKey("other-key-0001"), budgetErr.Key)
+	require.Len(t, sent, 3, "no request must be sent once all keys are over budget")
+
+	results := make(chan Result, 1)
+	_, ok := PickKey(pool, "source", session, results)
+	require.False(t, ok)
+	require.ErrorIs(t, (<-results).Error, ErrBudgetReached)
+
+	require.NoError(t, session.Budget.take("source", ""))
+	_, reached := session.Budget.Reached("source")
+	require.True(t, reached, "the requests sent with the keys count against the budget of the source")
+}
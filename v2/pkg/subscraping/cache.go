@@ -0,0 +1,144 @@
+package subscraping
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// uncachedHeaders are the request headers that do not change a response
+var uncachedHeaders = map[string]struct{}{
+	"User-Agent":      {},
+	"Accept-Language": {},
+	"Connection":      {},
+}
+
+// ResponseCache stores the successful responses of the sources on disk
+// so that identical queries within their TTL don't hit the providers again.
+// Only the sources with a TTL are cached.
+type ResponseCache struct {
+	// Directory is where the responses are stored
+	Directory string
+	// TTLs holds how long the responses of each source stay valid
+	TTLs map[string]time.Duration
+	// Refresh ignores the stored responses while still storing new ones
+	Refresh bool
+}
+
+type cachedResponse struct {
+	StoredAt   time.Time   `json:"stored_at"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+}
+
+// NewResponseCache creates a response cache in the given directory
+func NewResponseCache(directory string, ttls map[string]time.Duration, refresh bool) (*ResponseCache, error) {
+	if err := os.MkdirAll(directory, os.ModePerm); err != nil {
+		return nil, err
+	}
+	return &ResponseCache{Directory: directory, TTLs: ttls, Refresh: refresh}, nil
+}
+
+// enabled returns true if the responses of the source are cached
+func (c *ResponseCache) enabled(source string) bool {
+	return c != nil && c.TTLs[source] > 0
+}
+
+// get returns the stored response of a request if it is still valid
+func (c *ResponseCache) get(source string, request *http.Request, body []byte) (*http.Response, bool) {
+	if c.Refresh {
+		return nil, false
+	}
+	data, err := os.ReadFile(c.path(source, request, body))
+	if err != nil {
+		return nil, false
+	}
+	var cached cachedResponse
+	if err := jsoniter.Unmarshal(data, &cached); err != nil {
+		return nil, false
+	}
+	if time.Since(cached.StoredAt) > c.TTLs[source] {
+		return nil, false
+	}
+	return &http.Response{
+		Status:        http.StatusText(cached.StatusCode),
+		StatusCode:    cached.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        cached.Header,
+		Body:          io.NopCloser(bytes.NewReader(cached.Body)),
+		ContentLength: int64(len(cached.Body)),
+		Request:       request,
+	}, true
+}
+
+// put stores a response and returns it with a body that can still be read
+func (c *ResponseCache) put(source string, request *http.Request, body []byte, response *http.Response) (*http.Response, error) {
+	responseBody, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	response.Body = io.NopCloser(bytes.NewReader(responseBody))
+
+	data, err := jsoniter.Marshal(cachedResponse{
+		StoredAt:   time.Now(),
+		StatusCode: response.StatusCode,
+		Header:     response.Header,
+		Body:       responseBody,
+	})
+	if err != nil {
+		return response, err
+	}
+
+	// Write to a temporary file first so that readers never see a partial entry
+	path := c.path(source, request, body)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return response, err
+	}
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return response, err
+	}
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return response, err
+	}
+	tmpFile.Close()
+	return response, os.Rename(tmpFile.Name(), path)
+}
+
+// path returns the file of a request, keyed by its method, URL,
+// relevant headers and body
+func (c *ResponseCache) path(source string, request *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(request.Method + "\n" + request.URL.String() + "\n"))
+
+	headerNames := make([]string, 0, len(request.Header))
+	for name := range request.Header {
+		if _, ok := uncachedHeaders[name]; !ok {
+			headerNames = append(headerNames, name)
+		}
+	}
+	sort.Strings(headerNames)
+	for _, name := range headerNames {
+		hash.Write([]byte(name + ": " + strings.Join(request.Header.Values(name), ",") + "\n"))
+	}
+
+	bodyHash := sha256.Sum256(body)
+	hash.Write(bodyHash[:])
+
+	return filepath.Join(c.Directory, source, hex.EncodeToString(hash.Sum(nil))+".json")
+}
@@ -0,0 +1,60 @@
+package subscraping
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseCache(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_, _ = w.Write([]byte("a.example.com " + r.Header.Get("X-Api-Key")))
+	}))
+	defer server.Close()
+
+	cache, err := NewResponseCache(t.TempDir(), map[string]time.Duration{"test": time.Hour}, false)
+	require.NoError(t, err)
+
+	session := newTestSession(t, "test")
+	session.Cache = cache
+	ctx := context.WithValue(context.Background(), CtxSourceArg, "test")
+
+	get := func(key string) string {
+		resp, err := session.Get(ctx, server.URL, "", map[string]string{"X-Api-Key": key})
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	require.Equal(t, "a.example.com first", get("first"))
+	require.Equal(t, "a.example.com first", get("first"))
+	require.EqualValues(t, 1, requests.Load())
+
+	// a different key is a different query
+	require.Equal(t, "a.example.com second", get("second"))
+	require.EqualValues(t, 2, requests.Load())
+
+	stats, ok := session.Statistics.Get("test")
+	require.True(t, ok)
+	require.Equal(t, 1, stats.CacheHits)
+	require.Equal(t, 2, stats.CacheMisses)
+
+	cache.Refresh = true
+	require.Equal(t, "a.example.com first", get("first"))
+	require.EqualValues(t, 3, requests.Load())
+
+	cache.TTLs["test"] = 0
+	cache.Refresh = false
+	get("first")
+	require.EqualValues(t, 4, requests.Load(), "sources without a TTL must not be cached")
+}
@@ -0,0 +1,303 @@
+package subscraping
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+	"golang.org/x/exp/slices"
+)
+
+// CassetteMode is the mode a cassette is used in
+type CassetteMode int
+
+// Modes of a cassette
+const (
+	// CassetteRecord writes every request and response of the sources
+	CassetteRecord CassetteMode = iota
+	// CassetteReplay serves the recorded responses without network access
+	CassetteReplay
+)
+
+const redactedValue = "REDACTED"
+
+// ErrNotRecorded is returned when replaying a request missing from the cassette
+var ErrNotRecorded = errors.New("no recorded interaction")
+
+// sensitiveParams are the header and query parameter names whose values
+// are redacted from the recorded interactions, compared in lower case
+var sensitiveParams = map[string]struct{}{
+	"authorization":  {},
+	"cookie":         {},
+	"key":            {},
+	"apikey":         {},
+	"api_key":        {},
+	"api-key":        {},
+	"x-api-key":      {},
+	"x-apikey":       {},
+	"x-key":          {},
+	"x-access-token": {},
+	"x-quaketoken":   {},
+	"x-blobr-key":    {},
+	"private-token":  {},
+	"token":          {},
+	"access_token":   {},
+	"secret":         {},
+	"client_secret":  {},
+	"email":          {},
+}
+
+// minSecretLength is the length under which a key value is too common to be
+// redacted wherever it appears
+const minSecretLength = 4
+
+// secrets are the values of the keys given to the key pools, and of the
+// tokens the sources obtain with them, which are redacted wherever they
+// appear in the recorded interactions, as in the path of a URL
+var secrets struct {
+	sync.RWMutex
+	// values are sorted by decreasing length so that a secret containing
+	// another one is redacted first
+	values []string
+}
+
+// RegisterSecret adds values to the ones redacted from the recorded
+// interactions, as the access tokens a source exchanges its keys for
+func RegisterSecret(values ...string) {
+	secrets.Lock()
+	defer secrets.Unlock()
+
+	for _, value := range values {
+		if len(value) < minSecretLength || slices.Contains(secrets.values, value) {
+			continue
+		}
+		secrets.values = append(secrets.values, value)
+		if escaped := url.QueryEscape(value); escaped != value {
+			secrets.values = append(secrets.values, escaped)
+		}
+	}
+	sort.SliceStable(secrets.values, func(i, j int) bool {
+		return len(secrets.values[i]) > len(secrets.values[j])
+	})
+}
+
+// registerKey registers the values of a key of a pool. The token of a string
+// key given with a host or username, as host:token, is registered on its own
+// and every string field of a composite key is.
+func registerKey(key any) {
+	if value, ok := key.(string); ok {
+		RegisterSecret(value)
+		if separator := strings.LastIndex(value, ":"); separator >= 0 {
+			RegisterSecret(value[separator+1:])
+		}
+		return
+	}
+	value := reflect.ValueOf(key)
+	if value.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < value.NumField(); i++ {
+		if field := value.Field(i); field.Kind() == reflect.String {
+			RegisterSecret(field.String())
+		}
+	}
+}
+
+// redactSecrets replaces the registered secrets found in a value
+func redactSecrets(value string) string {
+	secrets.RLock()
+	defer secrets.RUnlock()
+
+	for _, secret := range secrets.values {
+		value = strings.ReplaceAll(value, secret, redactedValue)
+	}
+	return value
+}
+
+// Cassette records the HTTP interactions of the sources to a directory, one
+// JSON lines file per source, and replays them. Credentials are redacted from
+// the recorded interactions so that cassettes can be shared, by the name of
+// the parameters and headers carrying them and by the values of the keys.
+type Cassette struct {
+	Directory string
+	Mode      CassetteMode
+
+	mutex        sync.Mutex
+	interactions map[string][]*interaction
+}
+
+type interaction struct {
+	Method          string      `json:"method"`
+	URL             string      `json:"url"`
+	RequestHeader   http.Header `json:"request_header,omitempty"`
+	RequestBody     []byte      `json:"request_body,omitempty"`
+	StatusCode      int         `json:"status_code"`
+	ResponseHeader  http.Header `json:"response_header,omitempty"`
+	ResponseBody    []byte      `json:"response_body,omitempty"`
+	replayed        bool
+	urlWithoutQuery string
+}
+
+// NewCassette creates a cassette in the given directory. In replay mode,
+// the interactions recorded in the directory are loaded.
+func NewCassette(directory string, mode CassetteMode) (*Cassette, error) {
+	cassette := &Cassette{Directory: directory, Mode: mode, interactions: make(map[string][]*interaction)}
+	if mode == CassetteRecord {
+		return cassette, os.MkdirAll(directory, os.ModePerm)
+	}
+
+	files, err := filepath.Glob(filepath.Join(directory, "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		source := strings.TrimSuffix(filepath.Base(file), ".jsonl")
+		if err := cassette.load(source, file); err != nil {
+			return nil, err
+		}
+	}
+	return cassette, nil
+}
+
+func (c *Cassette) load(source, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 256*1024*1024)
+	for scanner.Scan() {
+		var recorded interaction
+		if err := jsoniter.Unmarshal(scanner.Bytes(), &recorded); err != nil {
+			return fmt.Errorf("invalid interaction in %s: %s", file, err)
+		}
+		recorded.urlWithoutQuery = stripQuery(recorded.URL)
+		c.interactions[source] = append(c.interactions[source], &recorded)
+	}
+	return scanner.Err()
+}
+
+// record writes an interaction and returns the response with a body that can still be read
+func (c *Cassette) record(source string, request *http.Request, body []byte, response *http.Response) (*http.Response, error) {
+	responseBody, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	response.Body = io.NopCloser(bytes.NewReader(responseBody))
+
+	data, err := jsoniter.Marshal(&interaction{
+		Method:         request.Method,
+		URL:            redactURL(request.URL),
+		RequestHeader:  redactHeader(request.Header),
+		RequestBody:    []byte(redactSecrets(string(body))),
+		StatusCode:     response.StatusCode,
+		ResponseHeader: redactHeader(response.Header),
+		ResponseBody:   []byte(redactSecrets(string(responseBody))),
+	})
+	if err != nil {
+		return response, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	file, err := os.OpenFile(filepath.Join(c.Directory, source+".jsonl"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return response, err
+	}
+	defer file.Close()
+	_, err = file.Write(append(data, '\n'))
+	return response, err
+}
+
+// replay returns the recorded response of a request. Interactions are matched on
+// the method and redacted URL, falling back to the URL without query so that
+// paginated requests are served in their recorded order.
+func (c *Cassette) replay(source string, request *http.Request) (*http.Response, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	requestURL := redactURL(request.URL)
+	requestURLNoQuery := stripQuery(requestURL)
+
+	var match *interaction
+	for _, recorded := range c.interactions[source] {
+		if recorded.replayed || recorded.Method != request.Method {
+			continue
+		}
+		if recorded.URL == requestURL {
+			match = recorded
+			break
+		}
+		if match == nil && recorded.urlWithoutQuery == requestURLNoQuery {
+			match = recorded
+		}
+	}
+	if match == nil {
+		return nil, fmt.Errorf("%w for %s %s", ErrNotRecorded, request.Method, requestURL)
+	}
+	match.replayed = true
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", match.StatusCode, http.StatusText(match.StatusCode)),
+		StatusCode:    match.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        match.ResponseHeader.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(match.ResponseBody)),
+		ContentLength: int64(len(match.ResponseBody)),
+		Request:       request,
+	}, nil
+}
+
+func redactURL(u *url.URL) string {
+	redacted := *u
+	redacted.User = nil
+	query := redacted.Query()
+	for name := range query {
+		if isSensitive(name) {
+			query.Set(name, redactedValue)
+		}
+	}
+	redacted.RawQuery = query.Encode()
+	return redactSecrets(redacted.String())
+}
+
+func redactHeader(header http.Header) http.Header {
+	redacted := header.Clone()
+	for name, values := range redacted {
+		if isSensitive(name) {
+			redacted.Set(name, redactedValue)
+			continue
+		}
+		for i := range values {
+			values[i] = redactSecrets(values[i])
+		}
+	}
+	return redacted
+}
+
+func isSensitive(name string) bool {
+	_, ok := sensitiveParams[strings.ToLower(name)]
+	return ok
+}
+
+func stripQuery(rawURL string) string {
+	before, _, _ := strings.Cut(rawURL, "?")
+	return before
+}
@@ -0,0 +1,65 @@
+package subscraping
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCassetteRecordReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte("page " + r.URL.Query().Get("page")))
+	}))
+
+	directory := t.TempDir()
+	ctx := context.WithValue(context.Background(), CtxSourceArg, "test")
+
+	run := func(session *Session, key string) []string {
+		var bodies []string
+		for _, page := range []string{"1", "2"} {
+			resp, err := session.Get(ctx, server.URL+"/list?page="+page+"&apikey="+key, "", map[string]string{"Authorization": key})
+			require.NoError(t, err)
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			resp.Body.Close()
+			bodies = append(bodies, string(body))
+		}
+		_, err := session.Get(ctx, server.URL+"/missing", "", nil)
+		var statusErr *StatusCodeError
+		require.True(t, errors.As(err, &statusErr))
+		require.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+		return bodies
+	}
+
+	recorder, err := NewCassette(directory, CassetteRecord)
+	require.NoError(t, err)
+	session := newTestSession(t, "test")
+	session.Cassette = recorder
+	require.Equal(t, []string{"page 1", "page 2"}, run(session, "secret-key"))
+
+	data, err := os.ReadFile(filepath.Join(directory, "test.jsonl"))
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "secret-key", "credentials must be redacted")
+
+	server.Close()
+
+	replayer, err := NewCassette(directory, CassetteReplay)
+	require.NoError(t, err)
+	session = newTestSession(t, "test")
+	session.Cassette = replayer
+	require.Equal(t, []string{"page 1", "page 2"}, run(session, "other-key"))
+
+	_, err = session.Get(ctx, server.URL+"/list?page=1", "", nil)
+	require.Error(t, err, "every recorded interaction is replayed once")
+}
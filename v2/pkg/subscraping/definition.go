@@ -0,0 +1,59 @@
+package subscraping
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProviderBudgetsEntry is the entry of the provider config holding the
+// request budgets rather than the keys of a source
+const ProviderBudgetsEntry = "budgets"
+
+var sourceNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// ValidateSourceName checks the name of a source declared by a definition,
+// which is also its entry in the provider config
+func ValidateSourceName(name string) error {
+	if !sourceNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid name %q, only lower case letters, digits, - and _ are allowed", name)
+	}
+	if name == ProviderBudgetsEntry {
+		return fmt.Errorf("invalid name %q, it is reserved for the budgets of the provider config", name)
+	}
+	return nil
+}
+
+// LoadDefinitions reads the YAML files of a directory, one definition per
+// file in the order of their names, and passes each to load. A missing
+// directory has no definitions. kind names the definitions in the errors.
+func LoadDefinitions[T any](directory, kind string, load func(definition T) error) error {
+	var files []string
+	for _, pattern := range []string{"*.yaml", "*.yml"} {
+		matches, err := filepath.Glob(filepath.Join(directory, pattern))
+		if err != nil {
+			return err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		var definition T
+		if err := yaml.Unmarshal(data, &definition); err != nil {
+			return fmt.Errorf("could not read %s definition %s: %w", kind, file, err)
+		}
+		if err := load(definition); err != nil {
+			return fmt.Errorf("invalid %s definition %s: %w", kind, file, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package subscraping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// KeyStatus is the outcome of the check of an API key
+type KeyStatus string
+
+const (
+	KeyValid     KeyStatus = "valid"
+	KeyInvalid   KeyStatus = "invalid"
+	KeyExhausted KeyStatus = "exhausted"
+	// KeyUnknown is reported when the provider could not tell, for
+	// instance because the request failed
+	KeyUnknown KeyStatus = "unknown"
+)
+
+// KeyCheck is the result of the check of an API key
+type KeyCheck struct {
+	// Key is the masked key
+	Key    string
+	Status KeyStatus
+	// Remaining is the number of credits left, -1 when the provider doesn't report it
+	Remaining int
+	Error     error
+}
+
+// KeyChecker is implemented by the sources able to check their API keys
+// against a cheap account or quota endpoint of their provider
+type KeyChecker interface {
+	// CheckKeys checks every configured key, it returns nothing when the
+	// source has no keys
+	CheckKeys(ctx context.Context, session *Session) []KeyCheck
+}
+
+// KeyCheckUnsupported is implemented by the sources needing a key whose
+// provider has no account or quota endpoint to check it against
+type KeyCheckUnsupported interface {
+	// KeyCheckUnsupported tells why the keys of the source cannot be checked
+	KeyCheckUnsupported() string
+}
+
+// ErrInvalidKey is returned by the remaining func of CheckKeys when the
+// provider answers a rejected key with a successful response
+var ErrInvalidKey = errors.New("the key was rejected")
+
+// ErrRemainingUnknown is returned by the remaining func of CheckKeys when the
+// provider doesn't report the credits of a key, for instance an unlimited one
+var ErrRemainingUnknown = errors.New("the remaining credits are unknown")
+
+// CheckKeys checks every key of a pool with the given request to an account
+// or quota endpoint. remaining reads the credits left from the body of a
+// successful response, it is nil when the provider doesn't report them.
+func CheckKeys[T comparable](pool *KeyPool[T], request func(key T) (*http.Response, error), remaining func(body []byte) (int, error)) []KeyCheck {
+	if pool.Len() == 0 {
+		return nil
+	}
+
+	checks := make([]KeyCheck, 0, len(pool.keys))
+	for _, key := range pool.keys {
+		resp, err := request(key)
+		check := checkKey(resp, err, remaining)
+		check.Key = pool.label(key)
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+func checkKey(resp *http.Response, err error, remaining func(body []byte) (int, error)) KeyCheck {
+	check := KeyCheck{Status: KeyUnknown, Remaining: -1, Error: err}
+	if resp == nil {
+		return check
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		check.Status = KeyValid
+	case http.StatusUnauthorized:
+		check.Status = KeyInvalid
+	case http.StatusPaymentRequired, http.StatusTooManyRequests:
+		check.Status = KeyExhausted
+	case http.StatusForbidden:
+		if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			check.Status = KeyExhausted
+		} else {
+			check.Status = KeyInvalid
+		}
+	}
+	if check.Status != KeyValid || remaining == nil {
+		return check
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err == nil {
+		check.Remaining, err = remaining(body)
+	}
+	if errors.Is(err, ErrRemainingUnknown) {
+		check.Remaining = -1
+		return check
+	}
+	if errors.Is(err, ErrInvalidKey) {
+		check.Status = KeyInvalid
+		check.Remaining = -1
+		check.Error = err
+		return check
+	}
+	if err != nil {
+		check.Remaining = -1
+		check.Error = fmt.Errorf("could not read the remaining credits: %w", err)
+		return check
+	}
+	if check.Remaining <= 0 {
+		check.Status = KeyExhausted
+	}
+	return check
+}
@@ -0,0 +1,77 @@
+package subscraping
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckKeys(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("X-Key") {
+		case "valid-key-0001":
+			_, _ = w.Write([]byte(`{"credits":42}`))
+		case "empty-key-0002":
+			_, _ = w.Write([]byte(`{"credits":0}`))
+		case "limited-key-003":
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.WriteHeader(http.StatusForbidden)
+		case "rejected-key-05":
+			_, _ = w.Write([]byte(`{"error":true}`))
+		case "unlimited-key-6":
+			_, _ = w.Write([]byte(`{"credits":"n/a"}`))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	session := newTestSession(t, "source")
+	ctx := context.WithValue(context.Background(), CtxSourceArg, "source")
+	pool := NewStringKeyPool([]string{"valid-key-0001", "empty-key-0002", "limited-key-003", "wrong-key-0004", "rejected-key-05", "unlimited-key-6"})
+
+	checks := CheckKeys(pool, func(key string) (*http.Response, error) {
+		return session.Get(ctx, server.URL, "", map[string]string{"X-Key": key})
+	}, func(body []byte) (int, error) {
+		var account struct {
+			Credits json.RawMessage `json:"credits"`
+			Error   bool            `json:"error"`
+		}
+		if err := json.Unmarshal(body, &account); err != nil {
+			return 0, err
+		}
+		if account.Error {
+			return 0, ErrInvalidKey
+		}
+		credits, err := strconv.Atoi(string(account.Credits))
+		if err != nil {
+			return 0, ErrRemainingUnknown
+		}
+		return credits, nil
+	})
+
+	require.Len(t, checks, 6)
+	expected := []struct {
+		status    KeyStatus
+		remaining int
+	}{
+		{KeyValid, 42},
+		{KeyExhausted, 0},
+		{KeyExhausted, -1},
+		{KeyInvalid, -1},
+		{KeyInvalid, -1},
+		{KeyValid, -1},
+	}
+	for i, check := range checks {
+		require.Equal(t, MaskKey(pool.keys[i]), check.Key)
+		require.Equal(t, expected[i].status, check.Status, check.Key)
+		require.Equal(t, expected[i].remaining, check.Remaining, check.Key)
+	}
+
+	require.Nil(t, CheckKeys[string](nil, nil, nil), "a source without keys has nothing to check")
+}
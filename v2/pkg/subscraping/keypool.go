@@ -0,0 +1,284 @@
+package subscraping
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/projectdiscovery/gologger"
+)
+
+// DefaultKeyBenchTime is how long a key rejected for its quota is benched
+// when the provider doesn't tell when the quota resets
+const DefaultKeyBenchTime = time.Minute
+
+// ErrKeysExhausted is returned when every key of a source is benched
+var ErrKeysExhausted = errors.New("all api keys are exhausted")
+
+// KeyUsage is the usage of an API key during an enumeration run
+type KeyUsage struct {
+	Requests int
+	// Rejected counts the requests the provider refused for the key
+	Rejected int
+	// Exhausted is set once the key has been benched
+	Exhausted bool
+}
+
+// KeyPool holds the API keys of a source. Keys rejected by the provider are
+// benched, for the rest of the process when they are invalid or until their
+// quota resets otherwise, and the rejected request is sent again with the
+// next available key. It is safe for concurrent use.
+type KeyPool[T comparable] struct {
+	keys  []T
+	entry func(T) string
+
+	mutex        sync.Mutex
+	invalid      []bool
+	benchedUntil []time.Time
+	// overBudget holds the budget error of the keys benched for their budget
+	overBudget []*BudgetError
+}
+
+// NewKeyPool creates a pool of keys, entry gives the provider config entry of
+// a key, which the key budgets refer to. The usage of a key is reported under
+// its masked entry. The values of the keys are redacted from the recorded
+// interactions.
+func NewKeyPool[T comparable](keys []T, entry func(T) string) *KeyPool[T] {
+	for _, key := range keys {
+		registerKey(key)
+	}
+	return &KeyPool[T]{
+		keys:         keys,
+		entry:        entry,
+		invalid:      make([]bool, len(keys)),
+		benchedUntil: make([]time.Time, len(keys)),
+		overBudget:   make([]*BudgetError, len(keys)),
+	}
+}
+
+// NewStringKeyPool creates a pool of plain API keys
+func NewStringKeyPool(keys []string) *KeyPool[string] {
+	return NewKeyPool(keys, func(key string) string {
+		return key
+	})
+}
+
+// MaskKey hides all but the last characters of a key
+func MaskKey(key string) string {
+	const visible = 4
+	if len(key) <= 2*visible {
+		return strings.Repeat("*", len(key))
+	}
+	return strings.Repeat("*", len(key)-visible) + key[len(key)-visible:]
+}
+
+// label returns the name under which the usage of a key is reported
+func (p *KeyPool[T]) label(key T) string {
+	return MaskKey(p.entry(key))
+}
+
+// Len returns the number of keys in the pool
+func (p *KeyPool[T]) Len() int {
+	if p == nil {
+		return 0
+	}
+	return len(p.keys)
+}
+
+// Pick returns a random key among the ones that are not benched
+func (p *KeyPool[T]) Pick() (T, bool) {
+	var key T
+	if p == nil {
+		return key, false
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := time.Now()
+	available := make([]int, 0, len(p.keys))
+	for i := range p.keys {
+		if !p.invalid[i] && !now.Before(p.benchedUntil[i]) {
+			available = append(available, i)
+		}
+	}
+	if len(available) == 0 {
+		return key, false
+	}
+	return p.keys[available[rand.Intn(len(available))]], true
+}
+
+// Do sends a request with the given key. The request must be sent with the
+// given context, which charges its attempts to the budget of the key along
+// with the budget of the source. When the provider rejects the key, or the
+// key reached its budget, it is benched and the request is sent again with
+// another key, which then replaces the given one for the following requests
+// of the caller. The last response is returned once no key is left.
+func (p *KeyPool[T]) Do(ctx context.Context, session *Session, source string, key *T, request func(ctx context.Context, key T) (*http.Response, error)) (*http.Response, error) {
+	for {
+		label := p.label(*key)
+		resp, err := request(context.WithValue(ctx, ctxKeyArg, p.entry(*key)), *key)
+
+		var budgetErr *BudgetError
+		if errors.As(err, &budgetErr) {
+			if budgetErr.Key == "" {
+				return resp, err
+			}
+			p.benchOverBudget(*key, budgetErr)
+			next, ok := p.Pick()
+			if !ok {
+				return resp, err
+			}
+			gologger.Debug().Msgf("Key %s of %s reached its budget, switching to %s", label, source, p.label(next))
+			session.DiscardHTTPResponse(resp)
+			*key = next
+			continue
+		}
+
+		rejected := keyRejected(resp)
+		recordKeyUsage(session, source, label, rejected)
+		if !rejected {
+			return resp, err
+		}
+
+		p.bench(*key, resp)
+		next, ok := p.Pick()
+		if !ok {
+			return resp, err
+		}
+		gologger.Debug().Msgf("Key %s of %s was rejected with status %d, switching to %s", label, source, resp.StatusCode, p.label(next))
+		session.DiscardHTTPResponse(resp)
+		*key = next
+	}
+}
+
+// Run is Do for the sources not sending HTTP requests, such as plugins. run
+// returns KeyInvalid or KeyExhausted when the provider refused the key, which
+// is then benched and run is called again with the next available key. The
+// error of the last run is returned once no key is left.
+func (p *KeyPool[T]) Run(ctx context.Context, session *Session, source string, key *T, run func(ctx context.Context, key T) (KeyStatus, error)) error {
+	for {
+		label := p.label(*key)
+		status, err := run(context.WithValue(ctx, ctxKeyArg, p.entry(*key)), *key)
+
+		rejected := status == KeyInvalid || status == KeyExhausted
+		recordKeyUsage(session, source, label, rejected)
+		if !rejected {
+			return err
+		}
+
+		p.benchKey(*key, status == KeyInvalid, DefaultKeyBenchTime)
+		next, ok := p.Pick()
+		if !ok {
+			return err
+		}
+		gologger.Debug().Msgf("Key %s of %s was reported %s, switching to %s", label, source, status, p.label(next))
+		*key = next
+	}
+}
+
+// recordKeyUsage counts a request sent with a key in the statistics of a source
+func recordKeyUsage(session *Session, source, label string, rejected bool) {
+	session.Statistics.Update(source, func(stats *Statistics) {
+		if stats.Keys == nil {
+			stats.Keys = make(map[string]KeyUsage)
+		}
+		usage := stats.Keys[label]
+		usage.Requests++
+		if rejected {
+			usage.Rejected++
+			usage.Exhausted = true
+		}
+		stats.Keys[label] = usage
+	})
+}
+
+func (p *KeyPool[T]) benchOverBudget(key T, budgetErr *BudgetError) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for i := range p.keys {
+		if p.keys[i] == key {
+			p.benchedUntil[i] = budgetErr.Reset
+			p.overBudget[i] = budgetErr
+		}
+	}
+}
+
+// budgetReached returns the budget error of a key benched for its budget, if any
+func (p *KeyPool[T]) budgetReached() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := time.Now()
+	for i := range p.keys {
+		if p.overBudget[i] != nil && now.Before(p.benchedUntil[i]) {
+			return p.overBudget[i]
+		}
+	}
+	return nil
+}
+
+func (p *KeyPool[T]) bench(key T, resp *http.Response) {
+	wait, ok := headerWait(resp.Header, time.Now())
+	if !ok {
+		wait = DefaultKeyBenchTime
+	}
+	p.benchKey(key, resp.StatusCode == http.StatusUnauthorized, wait)
+}
+
+// benchKey benches a key for the rest of the process when it is invalid, for wait otherwise
+func (p *KeyPool[T]) benchKey(key T, invalid bool, wait time.Duration) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for i := range p.keys {
+		if p.keys[i] != key {
+			continue
+		}
+		if invalid {
+			p.invalid[i] = true
+			continue
+		}
+		p.benchedUntil[i] = time.Now().Add(wait)
+		p.overBudget[i] = nil
+	}
+}
+
+// keyRejected tells whether a response refuses the key it was sent with,
+// either as invalid, out of credits or over its rate limit
+func keyRejected(resp *http.Response) bool {
+	if resp == nil {
+		return false
+	}
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusPaymentRequired, http.StatusTooManyRequests:
+		return true
+	case http.StatusForbidden:
+		return resp.Header.Get("X-RateLimit-Remaining") == "0"
+	}
+	return false
+}
+
+// PickKey returns a key of the pool for a run of a source. The source is
+// marked as skipped when it has no keys, and ErrKeysExhausted, or the budget
+// error of a key, is reported when all of them are benched.
+func PickKey[T comparable](pool *KeyPool[T], source string, session *Session, results chan<- Result) (T, bool) {
+	key, ok := pool.Pick()
+	if ok {
+		return key, true
+	}
+	if pool.Len() == 0 {
+		gologger.Debug().Msgf("Cannot use the %s source because there was no API key/secret defined for it.", source)
+		session.Statistics.Skip(source)
+	} else if err := pool.budgetReached(); err != nil {
+		results <- Result{Source: source, Type: Error, Error: err}
+	} else {
+		results <- Result{Source: source, Type: Error, Error: ErrKeysExhausted}
+	}
+	return key, false
+}
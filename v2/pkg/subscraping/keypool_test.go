@@ -0,0 +1,110 @@
+package subscraping
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func keyResponse(status int, header http.Header) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(strings.NewReader(""))}
+}
+
+func TestKeyPoolRotatesRejectedKeys(t *testing.T) {
+	session := newTestSession(t, "source")
+	pool := NewStringKeyPool([]string{"invalid-key-0001", "limited-key-0002", "working-key-0003"})
+
+	statuses := map[string]int{
+		"invalid-key-0001": http.StatusUnauthorized,
+		"limited-key-0002": http.StatusTooManyRequests,
+		"working-key-0003": http.StatusOK,
+	}
+	var sent []string
+	request := func(_ context.Context, key string) (*http.Response, error) {
+		sent = append(sent, key)
+		return keyResponse(statuses[key], nil), nil
+	}
+
+	key := "invalid-key-0001"
+	resp, err := pool.Do(context.Background(), session, "source", &key, request)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "working-key-0003", key, "the working key must replace the rejected ones")
+	require.Equal(t, "invalid-key-0001", sent[0])
+	require.LessOrEqual(t, len(sent), 3)
+
+	for i := 0; i < 10; i++ {
+		picked, ok := pool.Pick()
+		require.True(t, ok)
+		require.NotContains(t, sent[:len(sent)-1], picked, "benched keys must not be picked")
+	}
+
+	stats, ok := session.Statistics.Get("source")
+	require.True(t, ok)
+	usage := stats.Keys[MaskKey("working-key-0003")]
+	require.Equal(t, 1, usage.Requests)
+	require.False(t, usage.Exhausted)
+	for _, rejected := range sent[:len(sent)-1] {
+		usage := stats.Keys[MaskKey(rejected)]
+		require.Equal(t, 1, usage.Rejected)
+		require.True(t, usage.Exhausted)
+	}
+}
+
+func TestKeyPoolExhausted(t *testing.T) {
+	session := newTestSession(t, "source")
+	pool := NewStringKeyPool([]string{"first-key", "second-key"})
+
+	key, ok := pool.Pick()
+	require.True(t, ok)
+	resp, err := pool.Do(context.Background(), session, "source", &key, func(context.Context, string) (*http.Response, error) {
+		return keyResponse(http.StatusForbidden, http.Header{"X-Ratelimit-Remaining": {"0"}, "Retry-After": {"3600"}}), nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode, "the last rejection must be returned")
+
+	_, ok = pool.Pick()
+	require.False(t, ok)
+
+	results := make(chan Result, 1)
+	_, ok = PickKey(pool, "source", session, results)
+	require.False(t, ok)
+	require.ErrorIs(t, (<-results).Error, ErrKeysExhausted)
+
+	_, ok = PickKey[string](nil, "other", session, results)
+	require.False(t, ok)
+	stats, _ := session.Statistics.Get("other")
+	require.True(t, stats.Skipped, "a source without keys must be skipped")
+}
+
+func TestMaskKey(t *testing.T) {
+	require.Equal(t, "********", MaskKey("abcdefgh"))
+	require.Equal(t, "*****fghi", MaskKey("abcdefghi"))
+}
+
+func TestCreateInstanceKeys(t *testing.T) {
+	instances := CreateInstanceKeys([]string{
+		"public-token-1",
+		"https://git.example.com:8443/:internal-token",
+		"https://git.example.com:8443",
+		"public-token-2",
+	})
+	require.Len(t, instances, 2)
+
+	require.Empty(t, instances[0].BaseURL)
+	require.Equal(t, 2, instances[0].Keys.Len())
+
+	require.Equal(t, "https://git.example.com:8443", instances[1].BaseURL)
+	require.Equal(t, 1, instances[1].Keys.Len())
+	token, ok := instances[1].Keys.Pick()
+	require.True(t, ok)
+	require.Equal(t, "internal-token", token)
+	require.Equal(t, MaskKey("https://git.example.com:8443/:internal-token"), instances[1].Keys.label(token), "keys are labelled by their config entry")
+}
@@ -0,0 +1,110 @@
+package subscraping
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultRetryMinWait is the first backoff delay between two attempts
+	DefaultRetryMinWait = time.Second
+	// DefaultRetryMaxWait is the longest delay a request is retried after
+	DefaultRetryMaxWait = 30 * time.Second
+)
+
+// RetryPolicy controls how the failed requests of a source are retried
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt
+	MaxRetries int
+	// MinWait and MaxWait bound the exponential backoff between attempts.
+	// A server asking to wait longer than MaxWait is not retried.
+	MinWait time.Duration
+	MaxWait time.Duration
+}
+
+// NewRetryPolicy creates a retry policy with the default backoff bounds
+func NewRetryPolicy(maxRetries int) RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: maxRetries,
+		MinWait:    DefaultRetryMinWait,
+		MaxWait:    DefaultRetryMaxWait,
+	}
+}
+
+// isRetryable returns true if the outcome of a request is transient
+func isRetryable(ctx context.Context, response *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if response != nil {
+		switch response.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	return isTransient(err)
+}
+
+// isTransient returns true for the network errors worth another attempt.
+// Failures that would happen again, such as DNS, TLS and URL errors or a
+// request missing from a replayed cassette, are not.
+func isTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrNotRecorded) {
+		return false
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// wait returns the delay before the next attempt. It honors the Retry-After and
+// X-RateLimit-Reset headers and falls back to exponential backoff with jitter.
+// False is returned when the server asks to wait longer than MaxWait.
+func (p RetryPolicy) wait(attempt int, response *http.Response) (time.Duration, bool) {
+	if response != nil {
+		if wait, ok := headerWait(response.Header, time.Now()); ok {
+			return wait, wait <= p.MaxWait
+		}
+	}
+
+	backoff := p.MinWait << attempt
+	if backoff <= 0 || backoff > p.MaxWait {
+		backoff = p.MaxWait
+	}
+	if backoff <= 0 {
+		return 0, true
+	}
+	return time.Duration(rand.Int63n(int64(backoff) + 1)), true
+}
+
+// headerWait parses the delay requested by the server, if any
+func headerWait(header http.Header, now time.Time) (time.Duration, bool) {
+	if retryAfter := header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.ParseInt(retryAfter, 10, 64); err == nil {
+			return max(time.Duration(seconds)*time.Second, 0), true
+		}
+		if date, err := http.ParseTime(retryAfter); err == nil {
+			return max(date.Sub(now), 0), true
+		}
+	}
+	if reset := header.Get("X-RateLimit-Reset"); reset != "" {
+		if value, err := strconv.ParseInt(reset, 10, 64); err == nil {
+			// Providers either send an epoch timestamp or a number of seconds
+			if value > now.Unix()/2 {
+				return max(time.Unix(value, 0).Sub(now), 0), true
+			}
+			return max(time.Duration(value)*time.Second, 0), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,139 @@
+package subscraping
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/projectdiscovery/ratelimit"
+)
+
+func newTestSession(t *testing.T, source string) *Session {
+	t.Helper()
+
+	multiRateLimiter, err := ratelimit.NewMultiLimiter(context.Background(), &ratelimit.Options{
+		Key:         source,
+		IsUnlimited: true,
+		MaxCount:    math.MaxUint32,
+		Duration:    time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	session, err := NewSession("example.com", "", multiRateLimiter, 10)
+	require.NoError(t, err)
+	t.Cleanup(session.Close)
+	return session
+}
+
+func TestHTTPRequestRetry(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := make([]byte, r.ContentLength)
+		_, _ = r.Body.Read(body)
+		require.Equal(t, "query", string(body), "body must be sent again on retries")
+
+		if attempts.Add(1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	session := newTestSession(t, "test")
+	ctx := context.WithValue(context.Background(), CtxSourceArg, "test")
+
+	t.Run("Without retries", func(t *testing.T) {
+		resp, err := session.Post(ctx, server.URL, "", nil, strings.NewReader("query"))
+		require.Error(t, err)
+		session.DiscardHTTPResponse(resp)
+	})
+
+	t.Run("With retries", func(t *testing.T) {
+		attempts.Store(0)
+		session.SourceRetryPolicies = map[string]RetryPolicy{"test": NewRetryPolicy(2)}
+		resp, err := session.Post(ctx, server.URL, "", nil, strings.NewReader("query"))
+		require.NoError(t, err)
+		session.DiscardHTTPResponse(resp)
+		require.EqualValues(t, 3, attempts.Load())
+
+		stats, ok := session.Statistics.Get("test")
+		require.True(t, ok)
+		require.Equal(t, 2, stats.Retries)
+	})
+
+	t.Run("Retry-After beyond max wait", func(t *testing.T) {
+		attempts.Store(0)
+		server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts.Add(1)
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+		resp, err := session.Post(ctx, server.URL, "", nil, strings.NewReader("query"))
+		require.Error(t, err)
+		session.DiscardHTTPResponse(resp)
+		require.EqualValues(t, 1, attempts.Load())
+	})
+}
+
+func TestHeaderWait(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		header   http.Header
+		expected time.Duration
+		found    bool
+	}{
+		{http.Header{"Retry-After": []string{"5"}}, 5 * time.Second, true},
+		{http.Header{"Retry-After": []string{now.Add(10 * time.Second).UTC().Format(http.TimeFormat)}}, 10 * time.Second, true},
+		{http.Header{"X-Ratelimit-Reset": []string{"1700000030"}}, 30 * time.Second, true},
+		{http.Header{"X-Ratelimit-Reset": []string{"20"}}, 20 * time.Second, true},
+		{http.Header{}, 0, false},
+	}
+	for _, test := range tests {
+		wait, found := headerWait(test.header, now)
+		require.Equal(t, test.found, found)
+		require.Equal(t, test.expected, wait)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	urlError := func(err error) error {
+		return &url.Error{Op: "Get", URL: "https://example.com", Err: err}
+	}
+
+	tests := []struct {
+		err       error
+		transient bool
+	}{
+		{urlError(&net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}), true},
+		{urlError(&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}), true},
+		{urlError(io.ErrUnexpectedEOF), true},
+		{urlError(&net.DNSError{Err: "i/o timeout", IsTimeout: true}), true},
+		{urlError(&net.DNSError{Err: "no such host", IsNotFound: true}), false},
+		{urlError(x509.UnknownAuthorityError{}), false},
+		{urlError(errors.New("unsupported protocol scheme")), false},
+		{fmt.Errorf("%w for GET https://example.com", ErrNotRecorded), false},
+		{urlError(context.DeadlineExceeded), false},
+		{nil, false},
+	}
+	for _, test := range tests {
+		require.Equal(t, test.transient, isTransient(test.err), "%v", test.err)
+	}
+}
@@ -0,0 +1,261 @@
+// Package axfr logic
+package axfr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/projectdiscovery/gologger"
+
+	"github.com/projectdiscovery/subfinder/v2/pkg/resolve"
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
+)
+
+// timeout is the time allowed to each DNS query and to each step of a transfer
+const timeout = 10 * time.Second
+
+// nameServer is an authoritative server of a zone with its addresses
+type nameServer struct {
+	name      string
+	addresses []string
+}
+
+// zoneTransfer is a transfer a nameserver allowed
+type zoneTransfer struct {
+	server string
+	// kind is the type of the transfer, AXFR or IXFR
+	kind  string
+	names []string
+}
+
+// Source is the active agent trying zone transfers. The nameservers of the
+// domain are looked up with the resolvers and each one is asked for an AXFR
+// and, when IXFR is set and the AXFR is refused, for an IXFR.
+type Source struct {
+	// IXFR makes the source try an IXFR when a nameserver refuses the AXFR
+	IXFR bool
+
+	resolver *resolve.Resolver
+	// port is the port of the nameservers, 53 when empty
+	port string
+}
+
+// Run function returns all subdomains found with the service
+func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Session) <-chan subscraping.Result {
+	results := make(chan subscraping.Result)
+
+	go func() {
+		defer close(results)
+
+		transfers, err := s.zoneTransfers(ctx, domain)
+		if err != nil {
+			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
+			return
+		}
+
+		seen := make(map[string]struct{})
+		for _, transfer := range transfers {
+			// An allowed transfer is a misconfiguration worth reporting along the subdomains
+			finding := fmt.Sprintf("nameserver %s allowed a zone transfer (%s) of %s", transfer.server, transfer.kind, domain)
+			session.Statistics.Update(s.Name(), func(stats *subscraping.Statistics) {
+				stats.Findings = append(stats.Findings, finding)
+			})
+			gologger.Verbose().Label(s.Name()).Msg(finding)
+			for _, name := range transfer.names {
+				if _, ok := seen[name]; !ok {
+					seen[name] = struct{}{}
+					results <- subscraping.Result{Source: s.Name(), Type: subscraping.Subdomain, Value: name}
+				}
+			}
+		}
+	}()
+
+	return results
+}
+
+// zoneTransfers returns the transfers of a domain the nameservers allowed.
+// Refused transfers are the common case and not errors.
+func (s *Source) zoneTransfers(ctx context.Context, domain string) ([]zoneTransfer, error) {
+	if s.resolver == nil {
+		return nil, errors.New("no resolver configured")
+	}
+
+	servers, err := s.nameServers(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	kinds := []uint16{dns.TypeAXFR}
+	if s.IXFR {
+		kinds = append(kinds, dns.TypeIXFR)
+	}
+
+	var transfers []zoneTransfer
+	for _, server := range servers {
+	transfer:
+		for _, kind := range kinds {
+			for _, address := range server.addresses {
+				names, err := s.transfer(ctx, domain, address, kind)
+				if err != nil {
+					if ctx.Err() != nil {
+						return transfers, ctx.Err()
+					}
+					gologger.Debug().Msgf("Zone transfer (%s) of %s refused by %s (%s): %s\n", dns.TypeToString[kind], domain, server.name, address, err)
+					continue
+				}
+				transfers = append(transfers, zoneTransfer{server: server.name, kind: dns.TypeToString[kind], names: names})
+				break transfer
+			}
+		}
+	}
+	return transfers, nil
+}
+
+// nameServers returns the nameservers of a domain, which has none when it
+// isn't the apex of a zone
+func (s *Source) nameServers(ctx context.Context, domain string) ([]nameServer, error) {
+	answer, err := s.query(ctx, domain, dns.TypeNS)
+	if err != nil {
+		return nil, err
+	}
+
+	port := s.port
+	if port == "" {
+		port = "53"
+	}
+	var servers []nameServer
+	for _, record := range answer {
+		ns, ok := record.(*dns.NS)
+		if !ok {
+			continue
+		}
+		server := nameServer{name: strings.TrimSuffix(strings.ToLower(ns.Ns), ".")}
+		for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+			addresses, err := s.query(ctx, ns.Ns, qtype)
+			if err != nil {
+				gologger.Debug().Msgf("Could not resolve nameserver %s: %s\n", server.name, err)
+				continue
+			}
+			for _, address := range addresses {
+				switch record := address.(type) {
+				case *dns.A:
+					server.addresses = append(server.addresses, net.JoinHostPort(record.A.String(), port))
+				case *dns.AAAA:
+					server.addresses = append(server.addresses, net.JoinHostPort(record.AAAA.String(), port))
+				}
+			}
+		}
+		servers = append(servers, server)
+	}
+	return servers, nil
+}
+
+// query returns the answer of the first resolver answering a question
+func (s *Source) query(ctx context.Context, name string, qtype uint16) ([]dns.RR, error) {
+	resolvers := s.resolver.Resolvers
+	if len(resolvers) == 0 {
+		resolvers = resolve.DefaultResolvers
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), qtype)
+	var err error
+	for _, resolver := range resolvers {
+		var response *dns.Msg
+		response, _, err = (&dns.Client{Timeout: timeout}).ExchangeContext(ctx, msg, resolver)
+		if err == nil && response.Truncated {
+			response, _, err = (&dns.Client{Net: "tcp", Timeout: timeout}).ExchangeContext(ctx, msg, resolver)
+		}
+		if err != nil {
+			continue
+		}
+		switch response.Rcode {
+		case dns.RcodeSuccess, dns.RcodeNameError:
+			return response.Answer, nil
+		default:
+			err = fmt.Errorf("%s answered %s for %s", resolver, dns.RcodeToString[response.Rcode], name)
+		}
+	}
+	return nil, err
+}
+
+// transfer returns the owner names under the domain of a zone transfer
+func (s *Source) transfer(ctx context.Context, domain, address string, kind uint16) ([]string, error) {
+	msg := new(dns.Msg)
+	if kind == dns.TypeIXFR {
+		// Serial 0 asks for all the changes, usually answered with the full zone
+		msg.SetIxfr(dns.Fqdn(domain), 0, ".", ".")
+	} else {
+		msg.SetAxfr(dns.Fqdn(domain))
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	transfer := &dns.Transfer{Conn: &dns.Conn{Conn: conn}, ReadTimeout: timeout, WriteTimeout: timeout}
+	envelopes, err := transfer.In(msg, address)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	suffix := "." + domain
+	seen := make(map[string]struct{})
+	var names []string
+	for envelope := range envelopes {
+		if envelope.Error != nil {
+			err = envelope.Error
+			continue
+		}
+		for _, record := range envelope.RR {
+			name := strings.TrimPrefix(strings.TrimSuffix(strings.ToLower(record.Header().Name), "."), "*.")
+			if !strings.HasSuffix(name, suffix) {
+				continue
+			}
+			if _, ok := seen[name]; !ok {
+				seen[name] = struct{}{}
+				names = append(names, name)
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// Name returns the name of the source
+func (s *Source) Name() string {
+	return "axfr"
+}
+
+func (s *Source) IsDefault() bool {
+	return false
+}
+
+func (s *Source) HasRecursiveSupport() bool {
+	return true
+}
+
+func (s *Source) NeedsKey() bool {
+	return false
+}
+
+func (s *Source) AddApiKeys(_ []string) {
+	// no key needed
+}
+
+// SetResolver sets the resolvers used to look up the nameservers
+func (s *Source) SetResolver(resolver *resolve.Resolver) {
+	s.resolver = resolver
+}
@@ -0,0 +1,126 @@
+package axfr
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+
+	"github.com/projectdiscovery/subfinder/v2/pkg/resolve"
+	"github.com/projectdiscovery/subfinder/v2/pkg/testutils"
+)
+
+// zone is served by the local server to the transfers it allows
+var zone = []string{
+	"example.com. 3600 IN SOA ns1.example.com. admin.example.com. 7 3600 600 86400 60",
+	"example.com. 3600 IN NS ns1.example.com.",
+	"example.com. 3600 IN NS ns2.example.com.",
+	"ns1.example.com. 3600 IN A 127.0.0.1",
+	"ns2.example.com. 3600 IN A 127.0.0.2",
+	"www.example.com. 3600 IN CNAME web.example.com.",
+	"Web.Example.com. 3600 IN A 192.0.2.1",
+	"*.dev.example.com. 3600 IN A 192.0.2.2",
+	"_sip._tcp.example.com. 3600 IN SRV 0 0 5060 web.example.com.",
+	"example.com. 3600 IN SOA ns1.example.com. admin.example.com. 7 3600 600 86400 60",
+}
+
+// startServer starts a DNS server on 127.0.0.1 resolving the zone over UDP
+// and serving it over TCP to the transfers of the allowed types. ns2 has no
+// server, its transfers are refused.
+func startServer(t *testing.T, allowed ...uint16) string {
+	t.Helper()
+
+	records := make([]dns.RR, 0, len(zone))
+	for _, record := range zone {
+		rr, err := dns.NewRR(record)
+		require.NoError(t, err)
+		records = append(records, rr)
+	}
+
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		msg := new(dns.Msg)
+		msg.SetReply(r)
+		question := r.Question[0]
+		switch question.Qtype {
+		case dns.TypeAXFR, dns.TypeIXFR:
+			msg.Rcode = dns.RcodeRefused
+			for _, qtype := range allowed {
+				if qtype == question.Qtype {
+					msg.Rcode = dns.RcodeSuccess
+					msg.Answer = records
+				}
+			}
+		default:
+			for _, record := range records[1 : len(records)-1] {
+				if record.Header().Rrtype == question.Qtype && dns.CanonicalName(record.Header().Name) == dns.CanonicalName(question.Name) {
+					msg.Answer = append(msg.Answer, record)
+				}
+			}
+		}
+		_ = w.WriteMsg(msg)
+	})
+
+	packetConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	listener, err := net.Listen("tcp", packetConn.LocalAddr().String())
+	require.NoError(t, err)
+
+	udpServer := &dns.Server{PacketConn: packetConn, Handler: handler}
+	tcpServer := &dns.Server{Listener: listener, Handler: handler}
+	go func() { _ = udpServer.ActivateAndServe() }()
+	go func() { _ = tcpServer.ActivateAndServe() }()
+	t.Cleanup(func() {
+		_ = udpServer.Shutdown()
+		_ = tcpServer.Shutdown()
+	})
+	return packetConn.LocalAddr().String()
+}
+
+func newSource(address string) *Source {
+	_, port, _ := net.SplitHostPort(address)
+	source := &Source{port: port}
+	source.SetResolver(&resolve.Resolver{Resolvers: []string{address}})
+	return source
+}
+
+func TestSourceAXFR(t *testing.T) {
+	source := newSource(startServer(t, dns.TypeAXFR))
+
+	subdomains, errs := testutils.RunSource(context.Background(), t, source, "example.com")
+	require.Empty(t, errs)
+	require.Equal(t, []string{"_sip._tcp.example.com", "dev.example.com", "ns1.example.com", "ns2.example.com", "web.example.com", "www.example.com"}, subdomains)
+
+	transfers, err := source.zoneTransfers(context.Background(), "example.com")
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	require.Equal(t, "ns1.example.com", transfers[0].server)
+	require.Equal(t, "AXFR", transfers[0].kind)
+}
+
+func TestSourceIXFR(t *testing.T) {
+	address := startServer(t, dns.TypeIXFR)
+
+	subdomains, errs := testutils.RunSource(context.Background(), t, newSource(address), "example.com")
+	require.Empty(t, errs)
+	require.Empty(t, subdomains, "IXFR is only tried when enabled")
+
+	source := newSource(address)
+	source.IXFR = true
+	transfers, err := source.zoneTransfers(context.Background(), "example.com")
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	require.Equal(t, "ns1.example.com", transfers[0].server)
+	require.Equal(t, "IXFR", transfers[0].kind)
+	require.Contains(t, transfers[0].names, "www.example.com")
+}
+
+func TestSourceRefused(t *testing.T) {
+	subdomains, errs := testutils.RunSource(context.Background(), t, newSource(startServer(t)), "example.com")
+	require.Empty(t, errs, "refused transfers are not errors")
+	require.Empty(t, subdomains)
+
+	_, errs = testutils.RunSource(context.Background(), t, &Source{}, "example.com")
+	require.Len(t, errs, 1)
+}
@@ -4,7 +4,7 @@ package bevigil
 import (
 	"context"
 	"fmt"
-	"time"
+	"net/http"
 
 	jsoniter "github.com/json-iterator/go"
 
@@ -17,34 +17,26 @@ type Response struct {
 }
 
 type Source struct {
-	apiKeys   []string
-	timeTaken time.Duration
-	errors    int
-	results   int
-	skipped   bool
+	keys *subscraping.KeyPool[string]
 }
 
 func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Session) <-chan subscraping.Result {
 	results := make(chan subscraping.Result)
-	s.errors = 0
-	s.results = 0
 
 	go func() {
-		defer func(startTime time.Time) {
-			s.timeTaken = time.Since(startTime)
-			close(results)
-		}(time.Now())
-
-		randomApiKey := subscraping.PickRandom(s.apiKeys, s.Name())
-		if randomApiKey == "" {
-			s.skipped = true
+		defer close(results)
+
+		randomApiKey, ok := subscraping.PickKey(s.keys, s.Name(), session, results)
+		if !ok {
 			return
 		}
 
 		getUrl := fmt.Sprintf("https://osint.bevigil.com/api/%s/subdomains/", domain)
 
-		resp, err := session.Get(ctx, getUrl, "", map[string]string{
-			"X-Access-Token": randomApiKey, "User-Agent": "subfinder",
+		resp, err := s.keys.Do(ctx, session, s.Name(), &randomApiKey, func(ctx context.Context, apiKey string) (*http.Response, error) {
+			return session.Get(ctx, getUrl, "", map[string]string{
+				"X-Access-Token": apiKey, "User-Agent": "subfinder",
+			})
 		})
 		if err != nil {
 			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
@@ -92,14 +84,10 @@ func (s *Source) NeedsKey() bool {
 }
 
 func (s *Source) AddApiKeys(keys []string) {
-	s.apiKeys = keys
+	s.keys = subscraping.NewStringKeyPool(keys)
 }
 
-func (s *Source) Statistics() subscraping.Statistics {
-	return subscraping.Statistics{
-		Errors:    s.errors,
-		Results:   s.results,
-		TimeTaken: s.timeTaken,
-		Skipped:   s.skipped,
-	}
+// KeyCheckUnsupported tells why the keys cannot be checked
+func (s *Source) KeyCheckUnsupported() string {
+	return "the BeVigil API has no account or quota endpoint"
 }
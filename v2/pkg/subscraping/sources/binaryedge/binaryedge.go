@@ -5,9 +5,9 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"net/http"
 	"net/url"
 	"strconv"
-	"time"
 
 	jsoniter "github.com/json-iterator/go"
 
@@ -35,45 +35,38 @@ type subdomainsResponse struct {
 	Total      int         `json:"total"`
 }
 
+type subscriptionResponse struct {
+	RequestsLeft int `json:"requests_left"`
+}
+
 // Source is the passive scraping agent
 type Source struct {
-	apiKeys   []string
-	timeTaken time.Duration
-	errors    int
-	results   int
-	skipped   bool
+	keys *subscraping.KeyPool[string]
 }
 
 // Run function returns all subdomains found with the service
 func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Session) <-chan subscraping.Result {
 	results := make(chan subscraping.Result)
-	s.errors = 0
-	s.results = 0
 
 	go func() {
-		defer func(startTime time.Time) {
-			s.timeTaken = time.Since(startTime)
-			close(results)
-		}(time.Now())
-
-		randomApiKey := subscraping.PickRandom(s.apiKeys, s.Name())
-		if randomApiKey == "" {
-			s.skipped = true
+		defer close(results)
+
+		randomApiKey, ok := subscraping.PickKey(s.keys, s.Name(), session, results)
+		if !ok {
 			return
 		}
 
 		var baseURL string
 
-		authHeader := map[string]string{"X-Key": randomApiKey}
+		authHeader := "X-Key"
 
-		if isV2(ctx, session, authHeader) {
+		if isV2(ctx, session, map[string]string{authHeader: randomApiKey}) {
 			baseURL = fmt.Sprintf(baseAPIURLFmt, v2, domain)
 		} else {
-			authHeader = map[string]string{"X-Token": randomApiKey}
+			authHeader = "X-Token"
 			v1URLWithPageSize, err := addURLParam(fmt.Sprintf(baseAPIURLFmt, v1, domain), v1PageSizeParam, strconv.Itoa(maxV1PageSize))
 			if err != nil {
 				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-				s.errors++
 				return
 			}
 			baseURL = v1URLWithPageSize.String()
@@ -83,27 +76,26 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 			results <- subscraping.Result{
 				Source: s.Name(), Type: subscraping.Error, Error: fmt.Errorf("can't get API URL"),
 			}
-			s.errors++
 			return
 		}
 
-		s.enumerate(ctx, session, baseURL, firstPage, authHeader, results)
+		s.enumerate(ctx, session, baseURL, firstPage, authHeader, &randomApiKey, results)
 	}()
 	return results
 }
 
-func (s *Source) enumerate(ctx context.Context, session *subscraping.Session, baseURL string, page int, authHeader map[string]string, results chan subscraping.Result) {
+func (s *Source) enumerate(ctx context.Context, session *subscraping.Session, baseURL string, page int, authHeader string, apiKey *string, results chan subscraping.Result) {
 	pageURL, err := addURLParam(baseURL, pageParam, strconv.Itoa(page))
 	if err != nil {
 		results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-		s.errors++
 		return
 	}
 
-	resp, err := session.Get(ctx, pageURL.String(), "", authHeader)
+	resp, err := s.keys.Do(ctx, session, s.Name(), apiKey, func(ctx context.Context, apiKey string) (*http.Response, error) {
+		return session.Get(ctx, pageURL.String(), "", map[string]string{authHeader: apiKey})
+	})
 	if err != nil {
 		results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-		s.errors++
 		session.DiscardHTTPResponse(resp)
 		return
 	}
@@ -112,7 +104,6 @@ func (s *Source) enumerate(ctx context.Context, session *subscraping.Session, ba
 	err = jsoniter.NewDecoder(resp.Body).Decode(&response)
 	if err != nil {
 		results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-		s.errors++
 		resp.Body.Close()
 		return
 	}
@@ -120,7 +111,6 @@ func (s *Source) enumerate(ctx context.Context, session *subscraping.Session, ba
 	// Check error messages
 	if response.Message != "" && response.Status != nil {
 		results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: fmt.Errorf(response.Message)}
-		s.errors++
 		return
 	}
 
@@ -128,13 +118,12 @@ func (s *Source) enumerate(ctx context.Context, session *subscraping.Session, ba
 
 	for _, subdomain := range response.Subdomains {
 		results <- subscraping.Result{Source: s.Name(), Type: subscraping.Subdomain, Value: subdomain}
-		s.results++
 	}
 
 	totalPages := int(math.Ceil(float64(response.Total) / float64(response.PageSize)))
 	nextPage := response.Page + 1
 	if nextPage <= totalPages {
-		s.enumerate(ctx, session, baseURL, nextPage, authHeader, results)
+		s.enumerate(ctx, session, baseURL, nextPage, authHeader, apiKey, results)
 	}
 }
 
@@ -156,16 +145,18 @@ func (s *Source) NeedsKey() bool {
 }
 
 func (s *Source) AddApiKeys(keys []string) {
-	s.apiKeys = keys
+	s.keys = subscraping.NewStringKeyPool(keys)
 }
 
-func (s *Source) Statistics() subscraping.Statistics {
-	return subscraping.Statistics{
-		Errors:    s.errors,
-		Results:   s.results,
-		TimeTaken: s.timeTaken,
-		Skipped:   s.skipped,
-	}
+// CheckKeys checks the keys against the subscription endpoint of the v2 API
+func (s *Source) CheckKeys(ctx context.Context, session *subscraping.Session) []subscraping.KeyCheck {
+	return subscraping.CheckKeys(s.keys, func(apiKey string) (*http.Response, error) {
+		return session.Get(ctx, v2SubscriptionURL, "", map[string]string{"X-Key": apiKey})
+	}, func(body []byte) (int, error) {
+		var subscription subscriptionResponse
+		err := jsoniter.Unmarshal(body, &subscription)
+		return subscription.RequestsLeft, err
+	})
 }
 
 func isV2(ctx context.Context, session *subscraping.Session, authHeader map[string]string) bool {
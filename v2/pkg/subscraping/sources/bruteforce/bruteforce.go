@@ -0,0 +1,126 @@
+// Package bruteforce logic
+package bruteforce
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/projectdiscovery/subfinder/v2/pkg/resolve"
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
+)
+
+// DefaultThreads is the number of concurrent resolutions when Threads is zero
+const DefaultThreads = 10
+
+// Source is the active agent resolving the candidates made of the words of
+// a wordlist under the domain. The candidates answered by a wildcard of
+// their parent zones are filtered.
+type Source struct {
+	// Wordlist is the file holding a word per line
+	Wordlist string
+	// Threads is the number of concurrent resolutions, DefaultThreads when zero
+	Threads int
+
+	resolver *resolve.Resolver
+
+	wordsOnce sync.Once
+	words     []string
+	wordsErr  error
+}
+
+// Run function returns all subdomains found with the service
+func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Session) <-chan subscraping.Result {
+	results := make(chan subscraping.Result)
+
+	go func() {
+		defer close(results)
+
+		words, err := s.loadWords()
+		if err != nil {
+			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
+			return
+		}
+		if s.resolver == nil {
+			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: errors.New("no resolver configured")}
+			return
+		}
+
+		threads := s.Threads
+		if threads <= 0 {
+			threads = DefaultThreads
+		}
+		candidates := make([]string, len(words))
+		for i, word := range words {
+			candidates[i] = word + "." + domain
+		}
+		for host := range s.resolver.ResolveCandidates(ctx, domain, s.Name(), candidates, threads) {
+			select {
+			case <-ctx.Done():
+			case results <- subscraping.Result{Source: s.Name(), Type: subscraping.Subdomain, Value: host}:
+			}
+		}
+	}()
+
+	return results
+}
+
+// loadWords reads the unique words of the wordlist once
+func (s *Source) loadWords() ([]string, error) {
+	s.wordsOnce.Do(func() {
+		if s.Wordlist == "" {
+			s.wordsErr = errors.New("no wordlist given")
+			return
+		}
+		file, err := os.Open(s.Wordlist)
+		if err != nil {
+			s.wordsErr = err
+			return
+		}
+		defer file.Close()
+
+		seen := make(map[string]struct{})
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			word := strings.Trim(strings.ToLower(strings.TrimSpace(scanner.Text())), ".")
+			if word == "" || strings.HasPrefix(word, "#") || strings.ContainsAny(word, " \t*") {
+				continue
+			}
+			if _, ok := seen[word]; !ok {
+				seen[word] = struct{}{}
+				s.words = append(s.words, word)
+			}
+		}
+		s.wordsErr = scanner.Err()
+	})
+	return s.words, s.wordsErr
+}
+
+// Name returns the name of the source
+func (s *Source) Name() string {
+	return "bruteforce"
+}
+
+func (s *Source) IsDefault() bool {
+	return false
+}
+
+func (s *Source) HasRecursiveSupport() bool {
+	return true
+}
+
+func (s *Source) NeedsKey() bool {
+	return false
+}
+
+func (s *Source) AddApiKeys(_ []string) {
+	// no key needed
+}
+
+// SetResolver sets the resolver the candidates are resolved with
+func (s *Source) SetResolver(resolver *resolve.Resolver) {
+	s.resolver = resolver
+}
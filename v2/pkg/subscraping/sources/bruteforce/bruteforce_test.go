@@ -0,0 +1,98 @@
+package bruteforce
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/projectdiscovery/dnsx/libs/dnsx"
+	"github.com/stretchr/testify/require"
+
+	"github.com/projectdiscovery/subfinder/v2/pkg/resolve"
+	"github.com/projectdiscovery/subfinder/v2/pkg/testutils"
+)
+
+// hosts are the names resolved by the local server, any name under
+// dev.example.com is answered by its wildcard
+var hosts = map[string]string{
+	"www.example.com.":     "192.0.2.1",
+	"api.example.com.":     "192.0.2.2",
+	"app.dev.example.com.": "192.0.2.3",
+}
+
+// startServer starts a DNS server on 127.0.0.1 answering the A questions of
+// the hosts and of the wildcard
+func startServer(t *testing.T) string {
+	t.Helper()
+
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		msg := new(dns.Msg)
+		msg.SetReply(r)
+		question := r.Question[0]
+		name := strings.ToLower(question.Name)
+		ip, ok := hosts[name]
+		if !ok && strings.HasSuffix(name, ".dev.example.com.") {
+			ip, ok = "192.0.2.100", true
+		}
+		switch {
+		case !ok:
+			msg.Rcode = dns.RcodeNameError
+		case question.Qtype == dns.TypeA:
+			msg.Answer = append(msg.Answer, &dns.A{
+				Hdr: dns.RR_Header{Name: question.Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+				A:   net.ParseIP(ip),
+			})
+		}
+		_ = w.WriteMsg(msg)
+	})
+
+	packetConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &dns.Server{PacketConn: packetConn, Handler: handler}
+	go func() { _ = server.ActivateAndServe() }()
+	t.Cleanup(func() { _ = server.Shutdown() })
+	return packetConn.LocalAddr().String()
+}
+
+func newSource(t *testing.T, words ...string) *Source {
+	t.Helper()
+
+	client, err := dnsx.New(dnsx.Options{BaseResolvers: []string{startServer(t)}, MaxRetries: 1, QuestionTypes: []uint16{dns.TypeA}})
+	require.NoError(t, err)
+
+	wordlist := filepath.Join(t.TempDir(), "words.txt")
+	require.NoError(t, os.WriteFile(wordlist, []byte(strings.Join(words, "\n")), 0o600))
+
+	source := &Source{Wordlist: wordlist, Threads: 2}
+	source.SetResolver(&resolve.Resolver{DNSClient: client})
+	return source
+}
+
+func TestSourceBruteforce(t *testing.T) {
+	source := newSource(t, "# comment", "www", "WWW", "", "api", "missing", "app.dev", "test.dev", "*.dev")
+
+	subdomains, errs := testutils.RunSource(context.Background(), t, source, "example.com")
+	require.Empty(t, errs)
+	require.Equal(t, []string{"api.example.com", "app.dev.example.com", "www.example.com"}, subdomains, "test.dev.example.com is answered by the wildcard")
+
+	words, err := source.loadWords()
+	require.NoError(t, err)
+	require.Equal(t, []string{"www", "api", "missing", "app.dev", "test.dev"}, words)
+}
+
+func TestSourceBruteforceErrors(t *testing.T) {
+	_, errs := testutils.RunSource(context.Background(), t, &Source{}, "example.com")
+	require.Len(t, errs, 1, "no wordlist")
+
+	_, errs = testutils.RunSource(context.Background(), t, &Source{Wordlist: filepath.Join(t.TempDir(), "missing.txt")}, "example.com")
+	require.Len(t, errs, 1)
+
+	source := newSource(t, "www")
+	source.SetResolver(nil)
+	_, errs = testutils.RunSource(context.Background(), t, source, "example.com")
+	require.Len(t, errs, 1, "no resolver")
+}
@@ -4,8 +4,8 @@ package bufferover
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strings"
-	"time"
 
 	jsoniter "github.com/json-iterator/go"
 
@@ -23,52 +23,41 @@ type response struct {
 
 // Source is the passive scraping agent
 type Source struct {
-	apiKeys   []string
-	timeTaken time.Duration
-	errors    int
-	results   int
-	skipped   bool
+	keys *subscraping.KeyPool[string]
 }
 
 // Run function returns all subdomains found with the service
 func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Session) <-chan subscraping.Result {
 	results := make(chan subscraping.Result)
-	s.errors = 0
-	s.results = 0
 
 	go func() {
-		defer func(startTime time.Time) {
-			s.timeTaken = time.Since(startTime)
-			close(results)
-		}(time.Now())
-
-		randomApiKey := subscraping.PickRandom(s.apiKeys, s.Name())
-		if randomApiKey == "" {
-			s.skipped = true
+		defer close(results)
+
+		randomApiKey, ok := subscraping.PickKey(s.keys, s.Name(), session, results)
+		if !ok {
 			return
 		}
 
-		s.getData(ctx, fmt.Sprintf("https://tls.bufferover.run/dns?q=.%s", domain), randomApiKey, session, results)
+		s.getData(ctx, fmt.Sprintf("https://tls.bufferover.run/dns?q=.%s", domain), &randomApiKey, session, results)
 	}()
 
 	return results
 }
 
-func (s *Source) getData(ctx context.Context, sourceURL string, apiKey string, session *subscraping.Session, results chan subscraping.Result) {
-	resp, err := session.Get(ctx, sourceURL, "", map[string]string{"x-api-key": apiKey})
+func (s *Source) getData(ctx context.Context, sourceURL string, apiKey *string, session *subscraping.Session, results chan subscraping.Result) {
+	resp, err := s.keys.Do(ctx, session, s.Name(), apiKey, func(ctx context.Context, apiKey string) (*http.Response, error) {
+		return session.Get(ctx, sourceURL, "", map[string]string{"x-api-key": apiKey})
+	})
 
 	if err != nil && resp == nil {
 		results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-		s.errors++
 		session.DiscardHTTPResponse(resp)
 		return
 	}
 
 	var bufforesponse response
-	err = jsoniter.NewDecoder(resp.Body).Decode(&bufforesponse)
-	if err != nil {
+	if err := jsoniter.NewDecoder(resp.Body).Decode(&bufforesponse); err != nil {
 		results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-		s.errors++
 		resp.Body.Close()
 		return
 	}
@@ -81,7 +70,12 @@ func (s *Source) getData(ctx context.Context, sourceURL string, apiKey string, s
 		results <- subscraping.Result{
 			Source: s.Name(), Type: subscraping.Error, Error: fmt.Errorf("%s", strings.Join(metaErrors, ", ")),
 		}
-		s.errors++
+		return
+	}
+
+	// A failed request without error details in its body
+	if err != nil {
+		results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
 		return
 	}
 
@@ -98,7 +92,6 @@ func (s *Source) getData(ctx context.Context, sourceURL string, apiKey string, s
 		for _, value := range session.Extractor.Extract(subdomain) {
 			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Subdomain, Value: value}
 		}
-		s.results++
 	}
 }
 
@@ -120,14 +113,10 @@ func (s *Source) NeedsKey() bool {
 }
 
 func (s *Source) AddApiKeys(keys []string) {
-	s.apiKeys = keys
+	s.keys = subscraping.NewStringKeyPool(keys)
 }
 
-func (s *Source) Statistics() subscraping.Statistics {
-	return subscraping.Statistics{
-		Errors:    s.errors,
-		Results:   s.results,
-		TimeTaken: s.timeTaken,
-		Skipped:   s.skipped,
-	}
+// KeyCheckUnsupported tells why the keys cannot be checked
+func (s *Source) KeyCheckUnsupported() string {
+	return "the TLS BufferOver API has no account or quota endpoint"
 }
@@ -4,7 +4,7 @@ package builtwith
 import (
 	"context"
 	"fmt"
-	"time"
+	"net/http"
 
 	jsoniter "github.com/json-iterator/go"
 
@@ -31,34 +31,26 @@ type path struct {
 
 // Source is the passive scraping agent
 type Source struct {
-	apiKeys   []string
-	timeTaken time.Duration
-	errors    int
-	results   int
-	skipped   bool
+	keys *subscraping.KeyPool[string]
 }
 
 // Run function returns all subdomains found with the service
 func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Session) <-chan subscraping.Result {
 	results := make(chan subscraping.Result)
-	s.errors = 0
-	s.results = 0
 
 	go func() {
-		defer func(startTime time.Time) {
-			s.timeTaken = time.Since(startTime)
-			close(results)
-		}(time.Now())
+		defer close(results)
 
-		randomApiKey := subscraping.PickRandom(s.apiKeys, s.Name())
-		if randomApiKey == "" {
+		randomApiKey, ok := subscraping.PickKey(s.keys, s.Name(), session, results)
+		if !ok {
 			return
 		}
 
-		resp, err := session.SimpleGet(ctx, fmt.Sprintf("https://api.builtwith.com/v21/api.json?KEY=%s&HIDETEXT=yes&HIDEDL=yes&NOLIVE=yes&NOMETA=yes&NOPII=yes&NOATTR=yes&LOOKUP=%s", randomApiKey, domain))
+		resp, err := s.keys.Do(ctx, session, s.Name(), &randomApiKey, func(ctx context.Context, apiKey string) (*http.Response, error) {
+			return session.SimpleGet(ctx, fmt.Sprintf("https://api.builtwith.com/v21/api.json?KEY=%s&HIDETEXT=yes&HIDEDL=yes&NOLIVE=yes&NOMETA=yes&NOPII=yes&NOATTR=yes&LOOKUP=%s", apiKey, domain))
+		})
 		if err != nil {
 			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-			s.errors++
 			session.DiscardHTTPResponse(resp)
 			return
 		}
@@ -67,7 +59,6 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 		err = jsoniter.NewDecoder(resp.Body).Decode(&data)
 		if err != nil {
 			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-			s.errors++
 			resp.Body.Close()
 			return
 		}
@@ -75,7 +66,6 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 		for _, result := range data.Results {
 			for _, path := range result.Result.Paths {
 				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Subdomain, Value: fmt.Sprintf("%s.%s", path.SubDomain, path.Domain)}
-				s.results++
 			}
 		}
 	}()
@@ -101,14 +91,10 @@ func (s *Source) NeedsKey() bool {
 }
 
 func (s *Source) AddApiKeys(keys []string) {
-	s.apiKeys = keys
+	s.keys = subscraping.NewStringKeyPool(keys)
 }
 
-func (s *Source) Statistics() subscraping.Statistics {
-	return subscraping.Statistics{
-		Errors:    s.errors,
-		Results:   s.results,
-		TimeTaken: s.timeTaken,
-		Skipped:   s.skipped,
-	}
+// KeyCheckUnsupported tells why the keys cannot be checked
+func (s *Source) KeyCheckUnsupported() string {
+	return "the BuiltWith API has no account or quota endpoint"
 }
@@ -4,8 +4,8 @@ package c99
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strings"
-	"time"
 
 	jsoniter "github.com/json-iterator/go"
 
@@ -14,11 +14,7 @@ import (
 
 // Source is the passive scraping agent
 type Source struct {
-	apiKeys   []string
-	timeTaken time.Duration
-	errors    int
-	results   int
-	skipped   bool
+	keys *subscraping.KeyPool[string]
 }
 
 type dnsdbLookupResponse struct {
@@ -34,24 +30,20 @@ type dnsdbLookupResponse struct {
 // Run function returns all subdomains found with the service
 func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Session) <-chan subscraping.Result {
 	results := make(chan subscraping.Result)
-	s.errors = 0
-	s.results = 0
 
 	go func() {
-		defer func(startTime time.Time) {
-			s.timeTaken = time.Since(startTime)
-			close(results)
-		}(time.Now())
-
-		randomApiKey := subscraping.PickRandom(s.apiKeys, s.Name())
-		if randomApiKey == "" {
-			s.skipped = true
+		defer close(results)
+
+		randomApiKey, ok := subscraping.PickKey(s.keys, s.Name(), session, results)
+		if !ok {
 			return
 		}
 
-		searchURL := fmt.Sprintf("https://api.c99.nl/subdomainfinder?key=%s&domain=%s&json", randomApiKey, domain)
-		resp, err := session.SimpleGet(ctx, searchURL)
+		resp, err := s.keys.Do(ctx, session, s.Name(), &randomApiKey, func(ctx context.Context, apiKey string) (*http.Response, error) {
+			return session.SimpleGet(ctx, fmt.Sprintf("https://api.c99.nl/subdomainfinder?key=%s&domain=%s&json", apiKey, domain))
+		})
 		if err != nil {
+			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
 			session.DiscardHTTPResponse(resp)
 			return
 		}
@@ -62,7 +54,6 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 		err = jsoniter.NewDecoder(resp.Body).Decode(&response)
 		if err != nil {
 			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-			s.errors++
 			return
 		}
 
@@ -70,14 +61,12 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 			results <- subscraping.Result{
 				Source: s.Name(), Type: subscraping.Error, Error: fmt.Errorf("%v", response.Error),
 			}
-			s.errors++
 			return
 		}
 
 		for _, data := range response.Subdomains {
 			if !strings.HasPrefix(data.Subdomain, ".") {
 				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Subdomain, Value: data.Subdomain}
-				s.results++
 			}
 		}
 	}()
@@ -103,14 +92,10 @@ func (s *Source) NeedsKey() bool {
 }
 
 func (s *Source) AddApiKeys(keys []string) {
-	s.apiKeys = keys
+	s.keys = subscraping.NewStringKeyPool(keys)
 }
 
-func (s *Source) Statistics() subscraping.Statistics {
-	return subscraping.Statistics{
-		Errors:    s.errors,
-		Results:   s.results,
-		TimeTaken: s.timeTaken,
-		Skipped:   s.skipped,
-	}
+// KeyCheckUnsupported tells why the keys cannot be checked
+func (s *Source) KeyCheckUnsupported() string {
+	return "the C99 API has no account or quota endpoint"
 }
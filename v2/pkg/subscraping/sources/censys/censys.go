@@ -3,8 +3,8 @@ package censys
 
 import (
 	"context"
+	"net/http"
 	"strconv"
-	"time"
 
 	jsoniter "github.com/json-iterator/go"
 
@@ -55,11 +55,7 @@ type links struct {
 
 // Source is the passive scraping agent
 type Source struct {
-	apiKeys   []apiKey
-	timeTaken time.Duration
-	errors    int
-	results   int
-	skipped   bool
+	keys *subscraping.KeyPool[apiKey]
 }
 
 type apiKey struct {
@@ -70,18 +66,16 @@ type apiKey struct {
 // Run function returns all subdomains found with the service
 func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Session) <-chan subscraping.Result {
 	results := make(chan subscraping.Result)
-	s.errors = 0
-	s.results = 0
 
 	go func() {
-		defer func(startTime time.Time) {
-			s.timeTaken = time.Since(startTime)
-			close(results)
-		}(time.Now())
+		defer close(results)
 
-		randomApiKey := subscraping.PickRandom(s.apiKeys, s.Name())
+		randomApiKey, ok := subscraping.PickKey(s.keys, s.Name(), session, results)
+		if !ok {
+			return
+		}
 		if randomApiKey.token == "" || randomApiKey.secret == "" {
-			s.skipped = true
+			session.Statistics.Skip(s.Name())
 			return
 		}
 
@@ -92,7 +86,6 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 			certSearchEndpointUrl, err := urlutil.Parse(certSearchEndpoint)
 			if err != nil {
 				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-				s.errors++
 				return
 			}
 
@@ -102,19 +95,20 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 				certSearchEndpointUrl.Params.Add("cursor", cursor)
 			}
 
-			resp, err := session.HTTPRequest(
-				ctx,
-				"GET",
-				certSearchEndpointUrl.String(),
-				"",
-				nil,
-				nil,
-				subscraping.BasicAuth{Username: randomApiKey.token, Password: randomApiKey.secret},
-			)
+			resp, err := s.keys.Do(ctx, session, s.Name(), &randomApiKey, func(ctx context.Context, randomApiKey apiKey) (*http.Response, error) {
+				return session.HTTPRequest(
+					ctx,
+					"GET",
+					certSearchEndpointUrl.String(),
+					"",
+					nil,
+					nil,
+					subscraping.BasicAuth{Username: randomApiKey.token, Password: randomApiKey.secret},
+				)
+			})
 
 			if err != nil {
 				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-				s.errors++
 				session.DiscardHTTPResponse(resp)
 				return
 			}
@@ -123,7 +117,6 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 			err = jsoniter.NewDecoder(resp.Body).Decode(&censysResponse)
 			if err != nil {
 				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-				s.errors++
 				resp.Body.Close()
 				return
 			}
@@ -133,7 +126,6 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 			for _, hit := range censysResponse.Result.Hits {
 				for _, name := range hit.Names {
 					results <- subscraping.Result{Source: s.Name(), Type: subscraping.Subdomain, Value: name}
-					s.results++
 				}
 			}
 
@@ -167,16 +159,28 @@ func (s *Source) NeedsKey() bool {
 }
 
 func (s *Source) AddApiKeys(keys []string) {
-	s.apiKeys = subscraping.CreateApiKeys(keys, func(k, v string) apiKey {
+	s.keys = subscraping.NewKeyPool(subscraping.CreateApiKeys(keys, func(k, v string) apiKey {
 		return apiKey{k, v}
+	}), func(key apiKey) string {
+		return key.token + ":" + key.secret
 	})
 }
 
-func (s *Source) Statistics() subscraping.Statistics {
-	return subscraping.Statistics{
-		Errors:    s.errors,
-		Results:   s.results,
-		TimeTaken: s.timeTaken,
-		Skipped:   s.skipped,
-	}
+type accountResponse struct {
+	Quota struct {
+		Used      int `json:"used"`
+		Allowance int `json:"allowance"`
+	} `json:"quota"`
+}
+
+// CheckKeys checks the keys against the account endpoint reporting the query quota
+func (s *Source) CheckKeys(ctx context.Context, session *subscraping.Session) []subscraping.KeyCheck {
+	return subscraping.CheckKeys(s.keys, func(apiKey apiKey) (*http.Response, error) {
+		return session.HTTPRequest(ctx, http.MethodGet, "https://search.censys.io/api/v1/account", "", nil, nil,
+			subscraping.BasicAuth{Username: apiKey.token, Password: apiKey.secret})
+	}, func(body []byte) (int, error) {
+		var account accountResponse
+		err := jsoniter.Unmarshal(body, &account)
+		return account.Quota.Allowance - account.Quota.Used, err
+	})
 }
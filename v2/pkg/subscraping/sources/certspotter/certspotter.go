@@ -4,7 +4,7 @@ package certspotter
 import (
 	"context"
 	"fmt"
-	"time"
+	"net/http"
 
 	jsoniter "github.com/json-iterator/go"
 
@@ -18,38 +18,31 @@ type certspotterObject struct {
 
 // Source is the passive scraping agent
 type Source struct {
-	apiKeys   []string
-	timeTaken time.Duration
-	errors    int
-	results   int
-	skipped   bool
+	keys *subscraping.KeyPool[string]
 }
 
 // Run function returns all subdomains found with the service
 func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Session) <-chan subscraping.Result {
 	results := make(chan subscraping.Result)
-	s.errors = 0
-	s.results = 0
 
 	go func() {
-		defer func(startTime time.Time) {
-			s.timeTaken = time.Since(startTime)
-			close(results)
-		}(time.Now())
-
-		randomApiKey := subscraping.PickRandom(s.apiKeys, s.Name())
-		if randomApiKey == "" {
-			s.skipped = true
+		defer close(results)
+
+		randomApiKey, ok := subscraping.PickKey(s.keys, s.Name(), session, results)
+		if !ok {
 			return
 		}
 
-		headers := map[string]string{"Authorization": "Bearer " + randomApiKey}
 		cookies := ""
+		get := func(reqURL string) (*http.Response, error) {
+			return s.keys.Do(ctx, session, s.Name(), &randomApiKey, func(ctx context.Context, apiKey string) (*http.Response, error) {
+				return session.Get(ctx, reqURL, cookies, map[string]string{"Authorization": "Bearer " + apiKey})
+			})
+		}
 
-		resp, err := session.Get(ctx, fmt.Sprintf("https://api.certspotter.com/v1/issuances?domain=%s&include_subdomains=true&expand=dns_names", domain), cookies, headers)
+		resp, err := get(fmt.Sprintf("https://api.certspotter.com/v1/issuances?domain=%s&include_subdomains=true&expand=dns_names", domain))
 		if err != nil {
 			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-			s.errors++
 			session.DiscardHTTPResponse(resp)
 			return
 		}
@@ -58,7 +51,6 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 		err = jsoniter.NewDecoder(resp.Body).Decode(&response)
 		if err != nil {
 			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-			s.errors++
 			resp.Body.Close()
 			return
 		}
@@ -67,7 +59,6 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 		for _, cert := range response {
 			for _, subdomain := range cert.DNSNames {
 				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Subdomain, Value: subdomain}
-				s.results++
 			}
 		}
 
@@ -80,10 +71,9 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 		for {
 			reqURL := fmt.Sprintf("https://api.certspotter.com/v1/issuances?domain=%s&include_subdomains=true&expand=dns_names&after=%s", domain, id)
 
-			resp, err := session.Get(ctx, reqURL, cookies, headers)
+			resp, err := get(reqURL)
 			if err != nil {
 				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-				s.errors++
 				return
 			}
 
@@ -91,7 +81,6 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 			err = jsoniter.NewDecoder(resp.Body).Decode(&response)
 			if err != nil {
 				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-				s.errors++
 				resp.Body.Close()
 				return
 			}
@@ -104,7 +93,6 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 			for _, cert := range response {
 				for _, subdomain := range cert.DNSNames {
 					results <- subscraping.Result{Source: s.Name(), Type: subscraping.Subdomain, Value: subdomain}
-					s.results++
 				}
 			}
 
@@ -133,14 +121,10 @@ func (s *Source) NeedsKey() bool {
 }
 
 func (s *Source) AddApiKeys(keys []string) {
-	s.apiKeys = keys
+	s.keys = subscraping.NewStringKeyPool(keys)
 }
 
-func (s *Source) Statistics() subscraping.Statistics {
-	return subscraping.Statistics{
-		Errors:    s.errors,
-		Results:   s.results,
-		TimeTaken: s.timeTaken,
-		Skipped:   s.skipped,
-	}
+// KeyCheckUnsupported tells why the keys cannot be checked
+func (s *Source) KeyCheckUnsupported() string {
+	return "the Cert Spotter API has no account or quota endpoint"
 }
@@ -4,52 +4,58 @@ package chaos
 import (
 	"context"
 	"fmt"
-	"time"
+	"net/http"
+
+	jsoniter "github.com/json-iterator/go"
 
-	"github.com/projectdiscovery/chaos-client/pkg/chaos"
 	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
 )
 
+type response struct {
+	Domain     string   `json:"domain"`
+	Subdomains []string `json:"subdomains"`
+}
+
 // Source is the passive scraping agent
 type Source struct {
-	apiKeys   []string
-	timeTaken time.Duration
-	errors    int
-	results   int
-	skipped   bool
+	keys *subscraping.KeyPool[string]
 }
 
 // Run function returns all subdomains found with the service
-func (s *Source) Run(_ context.Context, domain string, _ *subscraping.Session) <-chan subscraping.Result {
+func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Session) <-chan subscraping.Result {
 	results := make(chan subscraping.Result)
-	s.errors = 0
-	s.results = 0
 
 	go func() {
-		defer func(startTime time.Time) {
-			s.timeTaken = time.Since(startTime)
-			close(results)
-		}(time.Now())
-
-		randomApiKey := subscraping.PickRandom(s.apiKeys, s.Name())
-		if randomApiKey == "" {
-			s.skipped = true
+		defer close(results)
+
+		randomApiKey, ok := subscraping.PickKey(s.keys, s.Name(), session, results)
+		if !ok {
 			return
 		}
 
-		chaosClient := chaos.New(randomApiKey)
-		for result := range chaosClient.GetSubdomains(&chaos.SubdomainsRequest{
-			Domain: domain,
-		}) {
-			if result.Error != nil {
-				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: result.Error}
-				s.errors++
-				break
-			}
+		// The API of the chaos client is queried through the session so that
+		// the requests are recorded and replayed with the other sources
+		resp, err := s.keys.Do(ctx, session, s.Name(), &randomApiKey, func(ctx context.Context, apiKey string) (*http.Response, error) {
+			return session.Get(ctx, fmt.Sprintf("https://dns.projectdiscovery.io/dns/%s/subdomains", domain), "", map[string]string{
+				"Authorization": apiKey,
+			})
+		})
+		if err != nil {
+			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
+			session.DiscardHTTPResponse(resp)
+			return
+		}
+		defer resp.Body.Close()
+
+		var chaosResponse response
+		if err := jsoniter.NewDecoder(resp.Body).Decode(&chaosResponse); err != nil {
+			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
+			return
+		}
+		for _, subdomain := range chaosResponse.Subdomains {
 			results <- subscraping.Result{
-				Source: s.Name(), Type: subscraping.Subdomain, Value: fmt.Sprintf("%s.%s", result.Subdomain, domain),
+				Source: s.Name(), Type: subscraping.Subdomain, Value: fmt.Sprintf("%s.%s", subdomain, domain),
 			}
-			s.results++
 		}
 	}()
 
@@ -74,14 +80,10 @@ func (s *Source) NeedsKey() bool {
 }
 
 func (s *Source) AddApiKeys(keys []string) {
-	s.apiKeys = keys
+	s.keys = subscraping.NewStringKeyPool(keys)
 }
 
-func (s *Source) Statistics() subscraping.Statistics {
-	return subscraping.Statistics{
-		Errors:    s.errors,
-		Results:   s.results,
-		TimeTaken: s.timeTaken,
-		Skipped:   s.skipped,
-	}
+// KeyCheckUnsupported tells why the keys cannot be checked
+func (s *Source) KeyCheckUnsupported() string {
+	return "the Chaos API has no account or quota endpoint"
 }
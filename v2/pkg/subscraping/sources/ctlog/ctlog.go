@@ -0,0 +1,259 @@
+// Package ctlog logic
+package ctlog
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+	folderutil "github.com/projectdiscovery/utils/folder"
+
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
+)
+
+const (
+	// DefaultMaxEntries is the number of entries fetched from each log per run
+	DefaultMaxEntries = 10000
+	// batchSize is the number of entries asked per get-entries request, logs may return fewer
+	batchSize = 256
+)
+
+// DefaultIndexDirectory is where the index of the logs is kept by default
+var DefaultIndexDirectory = filepath.Join(folderutil.AppConfigDirOrDefault(".", "subfinder"), "ctlog")
+
+type signedTreeHead struct {
+	TreeSize int64 `json:"tree_size"`
+}
+
+type entriesResponse struct {
+	Entries []struct {
+		LeafInput []byte `json:"leaf_input"`
+	} `json:"entries"`
+}
+
+// ctLog is an RFC 6962 log, its index is updated once per run
+type ctLog struct {
+	url   string
+	mutex sync.Mutex
+	index *logIndex
+	// updated is set once an update succeeded, a failed one is tried again by the next search
+	updated bool
+	// update is the update in progress, nil when none is running
+	update *logUpdate
+}
+
+// logUpdate is an update of the index of a log shared by the searches waiting for it
+type logUpdate struct {
+	// done is closed once the update is over
+	done chan struct{}
+	err  error
+}
+
+// Source is the passive scraping agent reading Certificate Transparency logs
+// directly. The logs are the URLs given as keys in the provider config. The
+// hostnames of the newest entries not fetched yet are added to an on-disk
+// index on each run, the subdomains are then searched in the index.
+type Source struct {
+	// IndexDirectory holds the index of the logs, DefaultIndexDirectory when empty
+	IndexDirectory string
+	// MaxEntries is the number of entries fetched from each log per run, DefaultMaxEntries when zero
+	MaxEntries int64
+
+	logs []*ctLog
+}
+
+// Run function returns all subdomains found with the service
+func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Session) <-chan subscraping.Result {
+	results := make(chan subscraping.Result)
+
+	go func() {
+		defer close(results)
+
+		if len(s.logs) == 0 {
+			subscraping.PickKey[string](nil, s.Name(), session, results)
+			return
+		}
+
+		seen := make(map[string]struct{})
+		for _, log := range s.logs {
+			subdomains, err := s.search(ctx, session, log)
+			if err != nil {
+				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: fmt.Errorf("%s: %w", log.url, err)}
+			}
+			for _, subdomain := range subdomains {
+				if _, ok := seen[subdomain]; !ok {
+					seen[subdomain] = struct{}{}
+					results <- subscraping.Result{Source: s.Name(), Type: subscraping.Subdomain, Value: subdomain}
+				}
+			}
+		}
+	}()
+
+	return results
+}
+
+// search updates the index of a log and returns the subdomains it holds. The
+// update runs in the background, without the deadline of the domain of the
+// search starting it, so that a search giving up on it doesn't make it fail
+// for the following ones. The index is searched even when the update failed.
+func (s *Source) search(ctx context.Context, session *subscraping.Session, log *ctLog) ([]string, error) {
+	update, err := s.startUpdate(ctx, session, log)
+	if err != nil {
+		return nil, err
+	}
+
+	var updateErr error
+	if update != nil {
+		select {
+		case <-update.done:
+			updateErr = update.err
+		case <-ctx.Done():
+			updateErr = ctx.Err()
+		}
+	}
+
+	subdomains, err := log.index.match(session.Extractor.Extract)
+	if err != nil {
+		return subdomains, err
+	}
+	return subdomains, updateErr
+}
+
+// startUpdate opens the index of a log and starts its update unless it is
+// already up to date. The update in progress is returned, if any.
+func (s *Source) startUpdate(ctx context.Context, session *subscraping.Session, log *ctLog) (*logUpdate, error) {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+
+	if log.index == nil {
+		directory := s.IndexDirectory
+		if directory == "" {
+			directory = DefaultIndexDirectory
+		}
+		index, err := openIndex(directory, log.url)
+		if err != nil {
+			return nil, err
+		}
+		log.index = index
+	}
+
+	if log.updated || log.update != nil {
+		return log.update, nil
+	}
+	update := &logUpdate{done: make(chan struct{})}
+	log.update = update
+	go func() {
+		update.err = s.update(context.WithoutCancel(ctx), session, log)
+
+		log.mutex.Lock()
+		log.updated = update.err == nil
+		log.update = nil
+		log.mutex.Unlock()
+		close(update.done)
+	}()
+	return update, nil
+}
+
+// update fetches the newest entries of a log missing from its index
+func (s *Source) update(ctx context.Context, session *subscraping.Session, log *ctLog) error {
+	var sth signedTreeHead
+	if err := s.getJSON(ctx, session, log.url+"/ct/v1/get-sth", &sth); err != nil {
+		return err
+	}
+
+	remaining := s.MaxEntries
+	if remaining <= 0 {
+		remaining = DefaultMaxEntries
+	}
+	missing := log.index.missing(sth.TreeSize)
+	for i := len(missing) - 1; i >= 0 && remaining > 0; i-- {
+		start, end := missing[i][0], missing[i][1]
+		if end-start > remaining {
+			start = end - remaining
+		}
+
+		for start < end {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+
+			last := min(start+batchSize, end) - 1
+			var entries entriesResponse
+			if err := s.getJSON(ctx, session, fmt.Sprintf("%s/ct/v1/get-entries?start=%d&end=%d", log.url, start, last), &entries); err != nil {
+				return err
+			}
+			if len(entries.Entries) == 0 {
+				return fmt.Errorf("no entries returned from %d", start)
+			}
+
+			unique := make(map[string]struct{})
+			var names []string
+			for _, entry := range entries.Entries {
+				// Entries which can't be parsed hold no hostnames worth failing for
+				hostnames, _ := leafNames(entry.LeafInput)
+				for _, name := range hostnames {
+					name = strings.ToLower(name)
+					if _, ok := unique[name]; !ok {
+						unique[name] = struct{}{}
+						names = append(names, name)
+					}
+				}
+			}
+
+			fetched := min(start+int64(len(entries.Entries)), end)
+			if err := log.index.add(start, fetched, names); err != nil {
+				return err
+			}
+			remaining -= fetched - start
+			start = fetched
+		}
+	}
+	return nil
+}
+
+func (s *Source) getJSON(ctx context.Context, session *subscraping.Session, url string, value interface{}) error {
+	resp, err := session.SimpleGet(ctx, url)
+	if err != nil {
+		session.DiscardHTTPResponse(resp)
+		return err
+	}
+	defer resp.Body.Close()
+
+	return jsoniter.NewDecoder(resp.Body).Decode(value)
+}
+
+// Name returns the name of the source
+func (s *Source) Name() string {
+	return "ctlog"
+}
+
+func (s *Source) IsDefault() bool {
+	return false
+}
+
+func (s *Source) HasRecursiveSupport() bool {
+	return true
+}
+
+// NeedsKey returns true as the logs to read are configured as keys
+func (s *Source) NeedsKey() bool {
+	return true
+}
+
+// AddApiKeys adds the URLs of the logs, such as https://ct.example.com/logs/2025
+func (s *Source) AddApiKeys(keys []string) {
+	s.logs = nil
+	for _, key := range keys {
+		s.logs = append(s.logs, &ctLog{url: strings.TrimRight(key, "/")})
+	}
+}
+
+// KeyCheckUnsupported tells why the keys cannot be checked
+func (s *Source) KeyCheckUnsupported() string {
+	return "the keys are the URLs of the logs to search, not credentials"
+}
@@ -0,0 +1,248 @@
+package ctlog
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/projectdiscovery/subfinder/v2/pkg/testutils"
+)
+
+// newCertificate returns a certificate for a common name and DNS names
+func newCertificate(t *testing.T, commonName string, dnsNames ...string) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return certificate
+}
+
+// merkleTreeLeaf encodes a timestamped entry of a certificate or, for
+// precertificates, of its TBSCertificate
+func merkleTreeLeaf(certificate *x509.Certificate, precert bool) []byte {
+	leaf := []byte{0, 0}
+	leaf = binary.BigEndian.AppendUint64(leaf, uint64(time.Now().UnixMilli()))
+	data := certificate.Raw
+	if precert {
+		leaf = binary.BigEndian.AppendUint16(leaf, precertEntry)
+		leaf = append(leaf, make([]byte, 32)...)
+		data = certificate.RawTBSCertificate
+	} else {
+		leaf = binary.BigEndian.AppendUint16(leaf, x509Entry)
+	}
+	leaf = append(leaf, byte(len(data)>>16), byte(len(data)>>8), byte(len(data)))
+	leaf = append(leaf, data...)
+	// no extensions
+	return append(leaf, 0, 0)
+}
+
+// stubLog is a RFC 6962 log serving its leaves a few at a time
+type stubLog struct {
+	mutex    sync.Mutex
+	leaves   [][]byte
+	requests []string
+}
+
+func (l *stubLog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	switch r.URL.Path {
+	case "/log/ct/v1/get-sth":
+		l.requests = append(l.requests, "sth")
+		_, _ = fmt.Fprintf(w, `{"tree_size":%d}`, len(l.leaves))
+	case "/log/ct/v1/get-entries":
+		start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+		end, _ := strconv.Atoi(r.URL.Query().Get("end"))
+		l.requests = append(l.requests, fmt.Sprintf("%d-%d", start, end))
+		// Like real logs, fewer entries than asked are returned
+		end = min(end, start+1, len(l.leaves)-1)
+		var response entriesResponse
+		for _, leaf := range l.leaves[start : end+1] {
+			response.Entries = append(response.Entries, struct {
+				LeafInput []byte `json:"leaf_input"`
+			}{leaf})
+		}
+		_ = jsoniter.NewEncoder(w).Encode(response)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (l *stubLog) add(leaves ...[]byte) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.leaves = append(l.leaves, leaves...)
+}
+
+func (l *stubLog) fetched() []string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	requests := l.requests
+	l.requests = nil
+	return requests
+}
+
+func TestLeafNames(t *testing.T) {
+	certificate := newCertificate(t, "www.example.com", "api.example.com", "*.dev.example.com")
+	for _, precert := range []bool{false, true} {
+		names, err := leafNames(merkleTreeLeaf(certificate, precert))
+		require.NoError(t, err)
+		require.Equal(t, []string{"www.example.com", "api.example.com", "*.dev.example.com"}, names)
+	}
+
+	_, err := leafNames([]byte{0, 0, 1})
+	require.Error(t, err)
+}
+
+func TestSourceIncrementalIndex(t *testing.T) {
+	log := &stubLog{}
+	log.add(
+		merkleTreeLeaf(newCertificate(t, "www.example.com"), false),
+		merkleTreeLeaf(newCertificate(t, "other.org", "mail.example.com"), true),
+		merkleTreeLeaf(newCertificate(t, "www.other.org"), false),
+	)
+	server := httptest.NewServer(log)
+	defer server.Close()
+
+	directory := t.TempDir()
+	newSource := func() *Source {
+		source := &Source{IndexDirectory: directory, MaxEntries: 2}
+		source.AddApiKeys([]string{server.URL + "/log/"})
+		return source
+	}
+
+	// Only the newest entries within the limit are fetched
+	subdomains, errs := testutils.RunSource(context.Background(), t, newSource(), "example.com")
+	require.Empty(t, errs)
+	require.Equal(t, []string{"mail.example.com"}, subdomains)
+	require.Equal(t, []string{"sth", "1-2"}, log.fetched())
+
+	// The next run fetches the new entries first and then the older ones
+	log.add(merkleTreeLeaf(newCertificate(t, "new.example.com"), false))
+	source := newSource()
+	subdomains, errs = testutils.RunSource(context.Background(), t, source, "example.com")
+	require.Empty(t, errs)
+	require.Equal(t, []string{"mail.example.com", "new.example.com", "www.example.com"}, subdomains)
+	require.Equal(t, []string{"sth", "3-3", "0-0"}, log.fetched())
+
+	// The index of a log is updated once per source
+	subdomains, errs = testutils.RunSource(context.Background(), t, source, "other.org")
+	require.Empty(t, errs)
+	require.Equal(t, []string{"www.other.org"}, subdomains)
+	require.Empty(t, log.fetched())
+
+	// A complete index is only checked against the tree size
+	_, errs = testutils.RunSource(context.Background(), t, newSource(), "example.com")
+	require.Empty(t, errs)
+	require.Equal(t, []string{"sth"}, log.fetched())
+}
+
+func TestSourceUnreachableLogUsesIndex(t *testing.T) {
+	log := &stubLog{}
+	log.add(merkleTreeLeaf(newCertificate(t, "www.example.com"), false))
+	server := httptest.NewServer(log)
+
+	source := &Source{IndexDirectory: t.TempDir()}
+	source.AddApiKeys([]string{server.URL + "/log"})
+	_, errs := testutils.RunSource(context.Background(), t, source, "example.com")
+	require.Empty(t, errs)
+	server.Close()
+
+	source = &Source{IndexDirectory: source.IndexDirectory}
+	source.AddApiKeys([]string{server.URL + "/log"})
+	subdomains, errs := testutils.RunSource(context.Background(), t, source, "example.com")
+	require.Len(t, errs, 1)
+	require.Equal(t, []string{"www.example.com"}, subdomains)
+}
+
+func TestSourceUpdateOutlivesDeadline(t *testing.T) {
+	log := &stubLog{}
+	log.add(merkleTreeLeaf(newCertificate(t, "www.example.com"), false))
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/get-entries") {
+			<-release
+		}
+		log.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	source := &Source{IndexDirectory: t.TempDir()}
+	source.AddApiKeys([]string{server.URL + "/log"})
+
+	// The first search gives up on the update at its deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	subdomains, errs := testutils.RunSource(ctx, t, source, "example.com")
+	require.Empty(t, subdomains)
+	require.Len(t, errs, 1)
+	require.ErrorIs(t, errs[0], context.DeadlineExceeded)
+
+	// The update goes on and the next search waits for it instead of starting another one
+	close(release)
+	subdomains, errs = testutils.RunSource(context.Background(), t, source, "example.com")
+	require.Empty(t, errs)
+	require.Equal(t, []string{"www.example.com"}, subdomains)
+	require.Equal(t, []string{"sth", "0-0"}, log.fetched())
+}
+
+func TestSourceRetriesFailedUpdate(t *testing.T) {
+	log := &stubLog{}
+	log.add(merkleTreeLeaf(newCertificate(t, "www.example.com"), false))
+	var failing atomic.Bool
+	failing.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		log.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	source := &Source{IndexDirectory: t.TempDir()}
+	source.AddApiKeys([]string{server.URL + "/log"})
+	subdomains, errs := testutils.RunSource(context.Background(), t, source, "example.com")
+	require.Empty(t, subdomains)
+	require.Len(t, errs, 1)
+
+	failing.Store(false)
+	subdomains, errs = testutils.RunSource(context.Background(), t, source, "example.com")
+	require.Empty(t, errs)
+	require.Equal(t, []string{"www.example.com"}, subdomains)
+}
+
+func TestMissingRanges(t *testing.T) {
+	index := &logIndex{Ranges: mergeRanges([][2]int64{{5, 10}, {0, 2}, {10, 12}, {20, 30}})}
+	require.Equal(t, [][2]int64{{0, 2}, {5, 12}, {20, 30}}, index.Ranges)
+	require.Equal(t, [][2]int64{{2, 5}, {12, 20}, {30, 40}}, index.missing(40))
+	require.Equal(t, [][2]int64{{2, 5}, {12, 15}}, index.missing(15))
+}
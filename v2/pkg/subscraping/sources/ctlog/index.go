@@ -0,0 +1,151 @@
+package ctlog
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// logIndex is the on-disk index of a log, holding the ranges of entries
+// already fetched and the hostnames found in them
+type logIndex struct {
+	directory string
+	URL       string `json:"url"`
+	// Ranges are the fetched entries as sorted, disjoint [start, end) ranges
+	Ranges [][2]int64 `json:"ranges"`
+}
+
+// openIndex reads the index of a log, an index is created for new logs
+func openIndex(root, logURL string) (*logIndex, error) {
+	hash := sha256.Sum256([]byte(logURL))
+	index := &logIndex{directory: filepath.Join(root, hex.EncodeToString(hash[:8])), URL: logURL}
+
+	data, err := os.ReadFile(index.rangesFile())
+	if errors.Is(err, os.ErrNotExist) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := jsoniter.Unmarshal(data, index); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+func (i *logIndex) rangesFile() string {
+	return filepath.Join(i.directory, "ranges.json")
+}
+
+func (i *logIndex) namesFile() string {
+	return filepath.Join(i.directory, "names.txt")
+}
+
+// missing returns the ranges of entries of a tree not fetched yet
+func (i *logIndex) missing(treeSize int64) [][2]int64 {
+	var missing [][2]int64
+	var next int64
+	for _, fetched := range i.Ranges {
+		if fetched[0] >= treeSize {
+			break
+		}
+		if fetched[0] > next {
+			missing = append(missing, [2]int64{next, fetched[0]})
+		}
+		next = fetched[1]
+	}
+	if next < treeSize {
+		missing = append(missing, [2]int64{next, treeSize})
+	}
+	return missing
+}
+
+// add records the hostnames of a range of entries. The hostnames are written
+// before the range, so that an interrupted write only leads to duplicates.
+func (i *logIndex) add(start, end int64, names []string) error {
+	if err := os.MkdirAll(i.directory, 0700); err != nil {
+		return err
+	}
+
+	if len(names) > 0 {
+		file, err := os.OpenFile(i.namesFile(), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return err
+		}
+		_, err = file.WriteString(strings.Join(names, "\n") + "\n")
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	i.Ranges = mergeRanges(append(i.Ranges, [2]int64{start, end}))
+	data, err := jsoniter.Marshal(i)
+	if err != nil {
+		return err
+	}
+	temporary := i.rangesFile() + ".tmp"
+	if err := os.WriteFile(temporary, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(temporary, i.rangesFile())
+}
+
+// match returns the indexed hostnames extracted by a function
+func (i *logIndex) match(extract func(string) []string) ([]string, error) {
+	file, err := os.Open(i.namesFile())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	seen := make(map[string]struct{})
+	var matches []string
+	reader := bufio.NewReader(file)
+	for {
+		// The index may be searched while an update appends to it, the last
+		// line is only complete once its newline is written
+		line, err := reader.ReadString('\n')
+		if errors.Is(err, io.EOF) {
+			return matches, nil
+		}
+		if err != nil {
+			return matches, err
+		}
+		for _, match := range extract(strings.TrimSuffix(line, "\n")) {
+			if _, ok := seen[match]; !ok {
+				seen[match] = struct{}{}
+				matches = append(matches, match)
+			}
+		}
+	}
+}
+
+// mergeRanges sorts ranges and merges the overlapping or adjacent ones
+func mergeRanges(ranges [][2]int64) [][2]int64 {
+	sort.Slice(ranges, func(a, b int) bool { return ranges[a][0] < ranges[b][0] })
+	merged := ranges[:0]
+	for _, current := range ranges {
+		if last := len(merged) - 1; last >= 0 && current[0] <= merged[last][1] {
+			if current[1] > merged[last][1] {
+				merged[last][1] = current[1]
+			}
+			continue
+		}
+		merged = append(merged, current)
+	}
+	return merged
+}
@@ -0,0 +1,153 @@
+package ctlog
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Entry types of a timestamped entry, RFC 6962 section 3.4
+const (
+	x509Entry    = 0
+	precertEntry = 1
+)
+
+var oidSubjectAltName = asn1.ObjectIdentifier{2, 5, 29, 17}
+
+// errShortLeaf is returned for a leaf shorter than its structure
+var errShortLeaf = errors.New("truncated merkle tree leaf")
+
+// leafNames returns the hostnames of the certificate or precertificate of a
+// MerkleTreeLeaf, its subject alternative DNS names and common name
+func leafNames(leaf []byte) ([]string, error) {
+	// version, leaf type, timestamp and entry type
+	const header = 1 + 1 + 8 + 2
+	if len(leaf) < header {
+		return nil, errShortLeaf
+	}
+	if leaf[0] != 0 || leaf[1] != 0 {
+		return nil, fmt.Errorf("unsupported leaf version %d or type %d", leaf[0], leaf[1])
+	}
+
+	entryType := binary.BigEndian.Uint16(leaf[10:12])
+	data := leaf[header:]
+	switch entryType {
+	case x509Entry:
+		cert, err := readOpaque24(data)
+		if err != nil {
+			return nil, err
+		}
+		// The TBSCertificate is the first element of the certificate
+		var certificate struct {
+			TBS asn1.RawValue
+		}
+		if _, err := asn1.Unmarshal(cert, &certificate); err != nil {
+			return nil, err
+		}
+		return tbsNames(certificate.TBS.FullBytes)
+	case precertEntry:
+		// The TBSCertificate follows the hash of the issuer key
+		const issuerKeyHash = 32
+		if len(data) < issuerKeyHash {
+			return nil, errShortLeaf
+		}
+		tbs, err := readOpaque24(data[issuerKeyHash:])
+		if err != nil {
+			return nil, err
+		}
+		return tbsNames(tbs)
+	default:
+		return nil, fmt.Errorf("unsupported entry type %d", entryType)
+	}
+}
+
+// readOpaque24 reads a TLS opaque vector with a 24 bits length
+func readOpaque24(data []byte) ([]byte, error) {
+	if len(data) < 3 {
+		return nil, errShortLeaf
+	}
+	length := int(data[0])<<16 | int(data[1])<<8 | int(data[2])
+	if len(data) < 3+length {
+		return nil, errShortLeaf
+	}
+	return data[3 : 3+length], nil
+}
+
+// tbsNames returns the hostnames of a TBSCertificate. Only its subject and
+// extensions are decoded, as many logged certificates are rejected by the
+// stricter parsing of crypto/x509.
+func tbsNames(tbs []byte) ([]string, error) {
+	var sequence asn1.RawValue
+	if _, err := asn1.Unmarshal(tbs, &sequence); err != nil {
+		return nil, err
+	}
+
+	var fields []asn1.RawValue
+	for rest := sequence.Bytes; len(rest) > 0; {
+		var field asn1.RawValue
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &field); err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+	}
+	// The version is optional, the serial number, signature, issuer and
+	// validity precede the subject
+	if len(fields) > 0 && fields[0].Class == asn1.ClassContextSpecific && fields[0].Tag == 0 {
+		fields = fields[1:]
+	}
+	const subjectField = 4
+	if len(fields) <= subjectField {
+		return nil, errors.New("truncated tbs certificate")
+	}
+
+	var names []string
+	var subject pkix.RDNSequence
+	if _, err := asn1.Unmarshal(fields[subjectField].FullBytes, &subject); err == nil {
+		var name pkix.Name
+		name.FillFromRDNSequence(&subject)
+		if name.CommonName != "" {
+			names = append(names, name.CommonName)
+		}
+	}
+
+	for _, field := range fields[subjectField+1:] {
+		if field.Class != asn1.ClassContextSpecific || field.Tag != 3 {
+			continue
+		}
+		var extensions []pkix.Extension
+		if _, err := asn1.Unmarshal(field.Bytes, &extensions); err != nil {
+			return names, err
+		}
+		for _, extension := range extensions {
+			if extension.Id.Equal(oidSubjectAltName) {
+				names = append(names, dnsNames(extension.Value)...)
+			}
+		}
+	}
+	return names, nil
+}
+
+// dnsNames returns the DNS names of a GeneralNames value
+func dnsNames(value []byte) []string {
+	var sequence asn1.RawValue
+	if _, err := asn1.Unmarshal(value, &sequence); err != nil {
+		return nil
+	}
+
+	const dnsNameTag = 2
+	var names []string
+	for rest := sequence.Bytes; len(rest) > 0; {
+		var generalName asn1.RawValue
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &generalName); err != nil {
+			break
+		}
+		if generalName.Class == asn1.ClassContextSpecific && generalName.Tag == dnsNameTag {
+			names = append(names, string(generalName.Bytes))
+		}
+	}
+	return names
+}
@@ -0,0 +1,234 @@
+// Package custom logic
+package custom
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/tomnomnom/linkheader"
+
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
+)
+
+// Source is a passive scraping agent declared by a definition
+type Source struct {
+	definition Definition
+	extract    []jsonPathStep
+	cursor     []jsonPathStep
+	regex      *regexp.Regexp
+	keys       *subscraping.KeyPool[string]
+}
+
+// New creates the source of a definition
+func New(definition Definition) (*Source, error) {
+	if err := definition.validate(); err != nil {
+		return nil, err
+	}
+
+	s := &Source{definition: definition}
+	var err error
+	if definition.Extract.JSONPath != "" {
+		s.extract, _ = parseJSONPath(definition.Extract.JSONPath)
+	}
+	if definition.Pagination.Type == PaginationCursor {
+		s.cursor, _ = parseJSONPath(definition.Pagination.Cursor)
+	}
+	if definition.Extract.Regex != "" {
+		s.regex, err = regexp.Compile(definition.Extract.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// Run function returns all subdomains found with the service
+func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Session) <-chan subscraping.Result {
+	results := make(chan subscraping.Result)
+
+	go func() {
+		defer close(results)
+
+		var apiKey string
+		if s.NeedsKey() {
+			var ok bool
+			apiKey, ok = subscraping.PickKey(s.keys, s.Name(), session, results)
+			if !ok {
+				return
+			}
+		}
+
+		pagination := s.definition.Pagination
+		page := pagination.Start
+		var cursor, nextURL string
+		for requests := 0; requests < pagination.MaxPages; requests++ {
+			variables := strings.NewReplacer("{{domain}}", domain, "{{page}}", strconv.Itoa(page), "{{cursor}}", url.QueryEscape(cursor))
+			requestURL := variables.Replace(s.definition.Request.URL)
+			if nextURL != "" {
+				requestURL = nextURL
+			}
+
+			var resp *http.Response
+			var err error
+			if s.NeedsKey() {
+				resp, err = s.keys.Do(ctx, session, s.Name(), &apiKey, func(ctx context.Context, apiKey string) (*http.Response, error) {
+					return s.request(ctx, session, variables, requestURL, apiKey)
+				})
+			} else {
+				resp, err = s.request(ctx, session, variables, requestURL, "")
+			}
+			if err != nil {
+				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
+				session.DiscardHTTPResponse(resp)
+				return
+			}
+
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
+				return
+			}
+
+			var document interface{}
+			if s.extract != nil || s.cursor != nil {
+				if err := jsoniter.Unmarshal(body, &document); err != nil {
+					results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
+					return
+				}
+			}
+
+			hostnames := s.hostnames(session, body, document)
+			for _, hostname := range hostnames {
+				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Subdomain, Value: hostname}
+			}
+
+			switch pagination.Type {
+			case PaginationPage:
+				// An empty page is past the last one
+				if len(hostnames) == 0 {
+					return
+				}
+				page++
+			case PaginationCursor:
+				cursors := jsonPathStrings(s.cursor, document)
+				if len(cursors) == 0 || cursors[0] == "" || cursors[0] == cursor {
+					return
+				}
+				cursor = cursors[0]
+			case PaginationLink:
+				nextURL = nextLink(resp, requestURL)
+				if nextURL == "" {
+					return
+				}
+			default:
+				return
+			}
+		}
+	}()
+
+	return results
+}
+
+// request sends the templated request of the definition with an API key
+func (s *Source) request(ctx context.Context, session *subscraping.Session, variables *strings.Replacer, requestURL, apiKey string) (*http.Response, error) {
+	headers := make(map[string]string, len(s.definition.Request.Headers)+1)
+	for name, value := range s.definition.Request.Headers {
+		headers[name] = variables.Replace(value)
+	}
+
+	var basicAuth subscraping.BasicAuth
+	switch s.definition.Auth.Type {
+	case AuthHeader:
+		headers[s.definition.Auth.Name] = s.definition.Auth.Prefix + apiKey
+	case AuthQuery:
+		parsedURL, err := url.Parse(requestURL)
+		if err != nil {
+			return nil, err
+		}
+		query := parsedURL.Query()
+		query.Set(s.definition.Auth.Name, s.definition.Auth.Prefix+apiKey)
+		parsedURL.RawQuery = query.Encode()
+		requestURL = parsedURL.String()
+	case AuthBasic:
+		basicAuth.Username, basicAuth.Password, _ = strings.Cut(apiKey, ":")
+	}
+
+	var body io.Reader
+	if s.definition.Request.Body != "" {
+		body = strings.NewReader(variables.Replace(s.definition.Request.Body))
+	}
+	return session.HTTPRequest(ctx, s.definition.Request.Method, requestURL, "", headers, body, basicAuth)
+}
+
+// hostnames extracts the hostnames of a response following the definition
+func (s *Source) hostnames(session *subscraping.Session, body []byte, document interface{}) []string {
+	switch {
+	case s.extract != nil:
+		return jsonPathStrings(s.extract, document)
+	case s.regex != nil:
+		var hostnames []string
+		for _, match := range s.regex.FindAllSubmatch(body, -1) {
+			if len(match) > 1 {
+				hostnames = append(hostnames, string(match[1]))
+			} else {
+				hostnames = append(hostnames, string(match[0]))
+			}
+		}
+		return hostnames
+	default:
+		return session.Extractor.Extract(string(body))
+	}
+}
+
+// nextLink returns the absolute URL of the next link of the Link header, if any
+func nextLink(resp *http.Response, requestURL string) string {
+	for _, link := range linkheader.Parse(resp.Header.Get("Link")) {
+		if link.Rel != "next" {
+			continue
+		}
+		base, err := url.Parse(requestURL)
+		if err != nil {
+			return ""
+		}
+		next, err := base.Parse(link.URL)
+		if err != nil {
+			return ""
+		}
+		return next.String()
+	}
+	return ""
+}
+
+// Name returns the name of the source
+func (s *Source) Name() string {
+	return s.definition.Name
+}
+
+func (s *Source) IsDefault() bool {
+	return s.definition.Default
+}
+
+func (s *Source) HasRecursiveSupport() bool {
+	return s.definition.Recursive
+}
+
+func (s *Source) NeedsKey() bool {
+	return s.definition.Auth.Type != ""
+}
+
+func (s *Source) AddApiKeys(keys []string) {
+	s.keys = subscraping.NewStringKeyPool(keys)
+}
+
+// KeyCheckUnsupported tells why the keys cannot be checked
+func (s *Source) KeyCheckUnsupported() string {
+	return "custom source definitions declare no account or quota endpoint"
+}
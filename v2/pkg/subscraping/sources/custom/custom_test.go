@@ -0,0 +1,169 @@
+package custom
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/projectdiscovery/subfinder/v2/pkg/testutils"
+)
+
+func TestJSONPath(t *testing.T) {
+	var document interface{}
+	require.NoError(t, jsoniter.UnmarshalFromString(`{"data":[{"host":"a.example.com"},{"host":"b.example.com","alt":{"host":"c.example.com"}}],"meta":{"next":"abc","count":2}}`, &document))
+
+	tests := map[string][]string{
+		"$.data[*].host":  {"a.example.com", "b.example.com"},
+		"$.data[1].host":  {"b.example.com"},
+		"$.data[-1].host": {"b.example.com"},
+		"$['meta'].next":  {"abc"},
+		"$.meta.count":    {"2"},
+		"$..host":         {"a.example.com", "b.example.com", "c.example.com"},
+		"$.missing[*]":    nil,
+	}
+	for path, expected := range tests {
+		steps, err := parseJSONPath(path)
+		require.NoError(t, err, path)
+		values := jsonPathStrings(steps, document)
+		sort.Strings(values)
+		require.Equal(t, expected, values, path)
+	}
+
+	for _, invalid := range []string{"data.host", "$.data[", "$.data[?(@.host)]", "$.data..", "$x"} {
+		_, err := parseJSONPath(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestSourcePagePagination(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		require.Equal(t, "example.com", r.URL.Query().Get("domain"))
+		switch r.URL.Query().Get("page") {
+		case "1":
+			_, _ = w.Write([]byte(`{"hosts":[{"name":"a.example.com"},{"name":"b.example.com"}]}`))
+		case "2":
+			_, _ = w.Write([]byte(`{"hosts":[{"name":"c.example.com"}]}`))
+		default:
+			_, _ = w.Write([]byte(`{"hosts":[]}`))
+		}
+	}))
+	defer server.Close()
+
+	source, err := New(Definition{
+		Name:       "internal",
+		Request:    Request{URL: server.URL + "/hosts?domain={{domain}}&page={{page}}"},
+		Auth:       Auth{Type: AuthHeader, Name: "Authorization", Prefix: "Bearer "},
+		Pagination: Pagination{Type: PaginationPage},
+		Extract:    Extract{JSONPath: "$.hosts[*].name"},
+	})
+	require.NoError(t, err)
+	require.True(t, source.NeedsKey())
+	source.AddApiKeys([]string{"secret"})
+
+	subdomains, errs := testutils.RunSource(context.Background(), t, source, "example.com")
+	require.Empty(t, errs)
+	require.Equal(t, []string{"a.example.com", "b.example.com", "c.example.com"}, subdomains)
+}
+
+func TestSourceCursorPagination(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "secret", r.URL.Query().Get("apikey"))
+		if r.URL.Query().Get("cursor") == "" {
+			_, _ = w.Write([]byte(`{"items":["a.example.com"],"next":"page 2"}`))
+			return
+		}
+		require.Equal(t, "page 2", r.URL.Query().Get("cursor"))
+		_, _ = w.Write([]byte(`{"items":["b.example.com"],"next":""}`))
+	}))
+	defer server.Close()
+
+	source, err := New(Definition{
+		Name:       "cursored",
+		Request:    Request{URL: server.URL + "/search?q={{domain}}&cursor={{cursor}}"},
+		Auth:       Auth{Type: AuthQuery, Name: "apikey"},
+		Pagination: Pagination{Type: PaginationCursor, Cursor: "$.next"},
+		Extract:    Extract{JSONPath: "$.items[*]"},
+	})
+	require.NoError(t, err)
+	source.AddApiKeys([]string{"secret"})
+
+	subdomains, errs := testutils.RunSource(context.Background(), t, source, "example.com")
+	require.Empty(t, errs)
+	require.Equal(t, []string{"a.example.com", "b.example.com"}, subdomains)
+}
+
+func TestSourceLinkPaginationAndRegex(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, _ := r.BasicAuth()
+		require.Equal(t, "user:pass", user+":"+password)
+		if r.URL.Path == "/first" {
+			w.Header().Set("Link", `</second>; rel="next"`)
+			_, _ = w.Write([]byte("host=a.example.com\nhost=b.example.com\n"))
+			return
+		}
+		_, _ = w.Write([]byte("host=c.example.com\n"))
+	}))
+	defer server.Close()
+
+	source, err := New(Definition{
+		Name:       "linked",
+		Request:    Request{URL: server.URL + "/first"},
+		Auth:       Auth{Type: AuthBasic},
+		Pagination: Pagination{Type: PaginationLink},
+		Extract:    Extract{Regex: `host=(\S+)`},
+	})
+	require.NoError(t, err)
+	source.AddApiKeys([]string{"user:pass"})
+
+	subdomains, errs := testutils.RunSource(context.Background(), t, source, "example.com")
+	require.Empty(t, errs)
+	require.Equal(t, []string{"a.example.com", "b.example.com", "c.example.com"}, subdomains)
+}
+
+func TestLoadDefinitions(t *testing.T) {
+	directory := t.TempDir()
+	definition := `
+name: Internal-Assets
+default: true
+request:
+  url: https://assets.internal/api/hosts?domain={{domain}}
+auth:
+  type: header
+  name: X-API-Key
+extract:
+  jsonpath: $.hosts[*]
+`
+	require.NoError(t, os.WriteFile(filepath.Join(directory, "assets.yaml"), []byte(definition), 0600))
+
+	sources, err := LoadDefinitions(directory)
+	require.NoError(t, err)
+	require.Len(t, sources, 1)
+	require.Equal(t, "internal-assets", sources[0].Name())
+	require.True(t, sources[0].IsDefault())
+	require.True(t, sources[0].NeedsKey())
+	require.Equal(t, DefaultMaxPages, sources[0].definition.Pagination.MaxPages)
+
+	sources, err = LoadDefinitions(filepath.Join(directory, "missing"))
+	require.NoError(t, err)
+	require.Empty(t, sources)
+
+	for name, invalid := range map[string]string{
+		"no url":     "name: broken\n",
+		"bad auth":   "name: broken\nrequest:\n  url: https://x\nauth:\n  type: header\n",
+		"bad paging": "name: broken\nrequest:\n  url: https://x\npagination:\n  type: offset\n",
+		"bad name":   "name: bro ken\nrequest:\n  url: https://x\n",
+		"reserved":   "name: budgets\nrequest:\n  url: https://x\n",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(directory, "assets.yaml"), []byte(invalid), 0600))
+		_, err := LoadDefinitions(directory)
+		require.Error(t, err, name)
+	}
+}
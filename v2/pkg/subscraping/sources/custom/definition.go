@@ -0,0 +1,152 @@
+package custom
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
+)
+
+// Auth styles of the API key of a source
+const (
+	AuthHeader = "header"
+	AuthQuery  = "query"
+	AuthBasic  = "basic"
+)
+
+// Pagination styles of a source
+const (
+	PaginationPage   = "page"
+	PaginationCursor = "cursor"
+	PaginationLink   = "link"
+)
+
+// DefaultMaxPages is the number of pages fetched when a paginated definition doesn't set it
+const DefaultMaxPages = 100
+
+// Definition declares a source querying an HTTP API. The URL, body and
+// header values are templates where {{domain}}, {{page}} and {{cursor}}
+// are replaced by the domain, page number and cursor of the request.
+type Definition struct {
+	// Name of the source, used by -s, -es and in the provider config
+	Name string `yaml:"name"`
+	// Default makes the source part of the default sources
+	Default bool `yaml:"default"`
+	// Recursive tells that the API accepts subdomains, not just root domains
+	Recursive  bool       `yaml:"recursive"`
+	Request    Request    `yaml:"request"`
+	Auth       Auth       `yaml:"auth"`
+	Pagination Pagination `yaml:"pagination"`
+	Extract    Extract    `yaml:"extract"`
+}
+
+// Request is the templated HTTP request sent for each page
+type Request struct {
+	URL     string            `yaml:"url"`
+	Method  string            `yaml:"method"`
+	Body    string            `yaml:"body"`
+	Headers map[string]string `yaml:"headers"`
+}
+
+// Auth tells how the API key of the provider config is sent. Without type
+// the source doesn't need a key.
+type Auth struct {
+	// Type is header, query or basic, the key is then user:password
+	Type string `yaml:"type"`
+	// Name is the header or query parameter holding the key
+	Name string `yaml:"name"`
+	// Prefix is prepended to the key, such as "Bearer "
+	Prefix string `yaml:"prefix"`
+}
+
+// Pagination tells how the following pages are requested. Without type
+// only one request is sent.
+type Pagination struct {
+	// Type is page, cursor or link for the next link of the Link header
+	Type string `yaml:"type"`
+	// Start is the first page number, 1 by default
+	Start int `yaml:"start"`
+	// Cursor is the JSONPath of the next cursor in the responses
+	Cursor string `yaml:"cursor"`
+	// MaxPages caps the number of requests, DefaultMaxPages by default
+	MaxPages int `yaml:"max-pages"`
+}
+
+// Extract tells how hostnames are read from the responses. Without JSONPath
+// nor regex, the subdomains of the domain are extracted from the whole body.
+type Extract struct {
+	JSONPath string `yaml:"jsonpath"`
+	// Regex matches the hostnames, or its first group when it has one
+	Regex string `yaml:"regex"`
+}
+
+// LoadDefinitions creates the sources defined by the YAML files of a
+// directory, one definition per file. A missing directory has no sources.
+func LoadDefinitions(directory string) ([]*Source, error) {
+	var sources []*Source
+	err := subscraping.LoadDefinitions(directory, "source", func(definition Definition) error {
+		source, err := New(definition)
+		if err != nil {
+			return err
+		}
+		sources = append(sources, source)
+		return nil
+	})
+	return sources, err
+}
+
+// validate checks a definition and fills its defaults
+func (d *Definition) validate() error {
+	d.Name = strings.ToLower(d.Name)
+	if err := subscraping.ValidateSourceName(d.Name); err != nil {
+		return err
+	}
+	if d.Request.URL == "" {
+		return fmt.Errorf("no request url")
+	}
+	if d.Request.Method == "" {
+		d.Request.Method = http.MethodGet
+	}
+	d.Request.Method = strings.ToUpper(d.Request.Method)
+
+	switch d.Auth.Type {
+	case "", AuthBasic:
+	case AuthHeader, AuthQuery:
+		if d.Auth.Name == "" {
+			return fmt.Errorf("no name for the %s auth", d.Auth.Type)
+		}
+	default:
+		return fmt.Errorf("unknown auth type %q, expected header, query or basic", d.Auth.Type)
+	}
+
+	switch d.Pagination.Type {
+	case "", PaginationLink:
+	case PaginationPage:
+		if d.Pagination.Start == 0 {
+			d.Pagination.Start = 1
+		}
+	case PaginationCursor:
+		if d.Pagination.Cursor == "" {
+			return fmt.Errorf("no cursor path for the cursor pagination")
+		}
+		if _, err := parseJSONPath(d.Pagination.Cursor); err != nil {
+			return fmt.Errorf("invalid cursor path: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown pagination type %q, expected page, cursor or link", d.Pagination.Type)
+	}
+	if d.Pagination.MaxPages <= 0 {
+		d.Pagination.MaxPages = DefaultMaxPages
+	}
+
+	if d.Extract.JSONPath != "" && d.Extract.Regex != "" {
+		return fmt.Errorf("both jsonpath and regex extraction specified")
+	}
+	if d.Extract.JSONPath != "" {
+		if _, err := parseJSONPath(d.Extract.JSONPath); err != nil {
+			return fmt.Errorf("invalid jsonpath: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package custom
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonPathStep is a step of a JSONPath, selecting children of the current values
+type jsonPathStep struct {
+	// field is the member selected, empty with wildcard or index
+	field    string
+	index    int
+	isIndex  bool
+	wildcard bool
+	// recursive selects among all the descendants, as with ..
+	recursive bool
+}
+
+// parseJSONPath parses the subset of JSONPath made of $, .name, ..name,
+// .*, [*], [n] and ['name']
+func parseJSONPath(path string) ([]jsonPathStep, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("%q must start with $", path)
+	}
+
+	var steps []jsonPathStep
+	rest := path[1:]
+	for rest != "" {
+		var step jsonPathStep
+		dotted := true
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			step.recursive = true
+			rest = rest[2:]
+		case rest[0] == '.':
+			rest = rest[1:]
+		default:
+			dotted = false
+		}
+		if strings.HasPrefix(rest, "[") {
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("%q has an unclosed [", path)
+			}
+			selector := rest[1:end]
+			rest = rest[end+1:]
+			switch {
+			case selector == "*":
+				step.wildcard = true
+			case len(selector) >= 2 && (selector[0] == '\'' || selector[0] == '"') && selector[len(selector)-1] == selector[0]:
+				step.field = selector[1 : len(selector)-1]
+			default:
+				index, err := strconv.Atoi(selector)
+				if err != nil {
+					return nil, fmt.Errorf("%q has an unsupported selector [%s]", path, selector)
+				}
+				step.index, step.isIndex = index, true
+			}
+			steps = append(steps, step)
+			continue
+		}
+		if !dotted {
+			return nil, fmt.Errorf("%q has an unexpected %q", path, rest[0])
+		}
+
+		// A dot is followed by a member name or a wildcard
+		end := strings.IndexAny(rest, ".[")
+		if end < 0 {
+			end = len(rest)
+		}
+		name := rest[:end]
+		rest = rest[end:]
+		if name == "" {
+			return nil, fmt.Errorf("%q has an empty member name", path)
+		}
+		if name == "*" {
+			step.wildcard = true
+		} else {
+			step.field = name
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// evalJSONPath returns the values selected by the steps in a decoded JSON document
+func evalJSONPath(steps []jsonPathStep, document interface{}) []interface{} {
+	values := []interface{}{document}
+	for _, step := range steps {
+		var selected []interface{}
+		for _, value := range values {
+			if step.recursive {
+				for _, descendant := range descendants(value) {
+					selected = append(selected, step.selectChildren(descendant)...)
+				}
+			} else {
+				selected = append(selected, step.selectChildren(value)...)
+			}
+		}
+		values = selected
+	}
+	return values
+}
+
+func (step jsonPathStep) selectChildren(value interface{}) []interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		if step.wildcard {
+			children := make([]interface{}, 0, len(typed))
+			for _, child := range typed {
+				children = append(children, child)
+			}
+			return children
+		}
+		if child, ok := typed[step.field]; ok && !step.isIndex {
+			return []interface{}{child}
+		}
+	case []interface{}:
+		if step.wildcard {
+			return typed
+		}
+		if step.isIndex {
+			index := step.index
+			if index < 0 {
+				index += len(typed)
+			}
+			if index >= 0 && index < len(typed) {
+				return []interface{}{typed[index]}
+			}
+		}
+	}
+	return nil
+}
+
+// descendants returns a value and all the values it contains
+func descendants(value interface{}) []interface{} {
+	all := []interface{}{value}
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for _, child := range typed {
+			all = append(all, descendants(child)...)
+		}
+	case []interface{}:
+		for _, child := range typed {
+			all = append(all, descendants(child)...)
+		}
+	}
+	return all
+}
+
+// jsonPathStrings returns the string and number values selected by a path
+func jsonPathStrings(steps []jsonPathStep, document interface{}) []string {
+	var values []string
+	for _, value := range evalJSONPath(steps, document) {
+		switch typed := value.(type) {
+		case string:
+			values = append(values, typed)
+		case float64:
+			values = append(values, strconv.FormatFloat(typed, 'f', -1, 64))
+		}
+	}
+	return values
+}
@@ -0,0 +1,118 @@
+// Package dataset logic
+package dataset
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+
+	folderutil "github.com/projectdiscovery/utils/folder"
+
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
+)
+
+// DefaultIndexDirectory is where the index of the datasets is kept by default
+var DefaultIndexDirectory = filepath.Join(folderutil.AppConfigDirOrDefault(".", "subfinder"), "dataset")
+
+// Source is the passive scraping agent searching local datasets, such as
+// forward DNS dumps, zone files or the output of previous scans. The paths
+// or globs of the files are given as keys in the provider config. The files
+// are indexed by registrable domain on the first run, the index is then
+// reused as long as the files don't change.
+type Source struct {
+	// IndexDirectory holds the index of the datasets, DefaultIndexDirectory when empty
+	IndexDirectory string
+
+	patterns []string
+	// indexOnce starts the index build on the first run
+	indexOnce sync.Once
+	// indexBuilt is closed once the index is built
+	indexBuilt chan struct{}
+	index      *index
+	// indexErr is the error of the index build, which is only tried once
+	indexErr error
+}
+
+// Run function returns all subdomains found with the service
+func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Session) <-chan subscraping.Result {
+	results := make(chan subscraping.Result)
+
+	go func() {
+		defer close(results)
+
+		if len(s.patterns) == 0 {
+			subscraping.PickKey[string](nil, s.Name(), session, results)
+			return
+		}
+
+		idx, err := s.getIndex(ctx)
+		if err != nil {
+			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
+			return
+		}
+
+		subdomains, err := idx.lookup(domain)
+		if err != nil {
+			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
+			return
+		}
+		for _, subdomain := range subdomains {
+			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Subdomain, Value: subdomain}
+		}
+	}()
+
+	return results
+}
+
+// getIndex builds or reuses the index on the first run, the other runs wait
+// for it. The index is built in the background, without the deadline of the
+// domain of the first run, so that a run giving up on the index doesn't make
+// it fail for the following ones.
+func (s *Source) getIndex(ctx context.Context) (*index, error) {
+	s.indexOnce.Do(func() {
+		s.indexBuilt = make(chan struct{})
+		root := s.IndexDirectory
+		if root == "" {
+			root = DefaultIndexDirectory
+		}
+		go func() {
+			defer close(s.indexBuilt)
+			s.index, s.indexErr = buildIndex(context.WithoutCancel(ctx), indexDirectory(root, s.patterns), s.patterns)
+		}()
+	})
+
+	select {
+	case <-s.indexBuilt:
+		return s.index, s.indexErr
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Name returns the name of the source
+func (s *Source) Name() string {
+	return "dataset"
+}
+
+func (s *Source) IsDefault() bool {
+	return false
+}
+
+func (s *Source) HasRecursiveSupport() bool {
+	return true
+}
+
+// NeedsKey returns true as the files to search are configured as keys
+func (s *Source) NeedsKey() bool {
+	return true
+}
+
+// AddApiKeys adds the paths or globs of the files, such as /data/fdns/*.json.gz
+func (s *Source) AddApiKeys(keys []string) {
+	s.patterns = keys
+}
+
+// KeyCheckUnsupported tells why the keys cannot be checked
+func (s *Source) KeyCheckUnsupported() string {
+	return "the keys are the paths of local dataset files, not credentials"
+}
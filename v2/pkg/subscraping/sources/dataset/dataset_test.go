@@ -0,0 +1,121 @@
+package dataset
+
+import (
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/projectdiscovery/subfinder/v2/pkg/testutils"
+)
+
+func writeFile(t *testing.T, file, content string) {
+	t.Helper()
+	if filepath.Ext(file) != ".gz" {
+		require.NoError(t, os.WriteFile(file, []byte(content), 0600))
+		return
+	}
+	handle, err := os.Create(file)
+	require.NoError(t, err)
+	writer := gzip.NewWriter(handle)
+	_, err = writer.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	require.NoError(t, handle.Close())
+}
+
+func TestSourceFormats(t *testing.T) {
+	data := t.TempDir()
+	writeFile(t, filepath.Join(data, "fdns.json.gz"), `{"timestamp":"1700000000","name":"www.example.com","type":"cname","value":"cdn.example.net"}
+{"timestamp":"1700000000","name":"api.example.com","type":"a","value":"192.0.2.1"}
+`)
+	writeFile(t, filepath.Join(data, "example.co.uk.zone"), `$ORIGIN example.co.uk.
+mail.example.co.uk.	3600	IN	A	192.0.2.2
+example.co.uk.	3600	IN	NS	ns1.example.com.
+`)
+	writeFile(t, filepath.Join(data, "massdns.txt"), "dev.example.com. A 192.0.2.3\n*.wild.example.com. CNAME www.example.com.\n")
+	writeFile(t, filepath.Join(data, "hosts.txt"), "WWW.Example.Com\nexample.com\nnotexample.com\n")
+
+	source := &Source{IndexDirectory: t.TempDir()}
+	source.AddApiKeys([]string{filepath.Join(data, "*.gz"), filepath.Join(data, "*.zone"), filepath.Join(data, "*.txt")})
+
+	subdomains, errs := testutils.RunSource(context.Background(), t, source, "example.com")
+	require.Empty(t, errs)
+	require.Equal(t, []string{"api.example.com", "dev.example.com", "ns1.example.com", "wild.example.com", "www.example.com"}, subdomains)
+
+	subdomains, errs = testutils.RunSource(context.Background(), t, source, "example.co.uk")
+	require.Empty(t, errs)
+	require.Equal(t, []string{"mail.example.co.uk"}, subdomains)
+
+	subdomains, errs = testutils.RunSource(context.Background(), t, source, "dev.example.com")
+	require.Empty(t, errs)
+	require.Empty(t, subdomains)
+}
+
+func TestSourceIndexReuse(t *testing.T) {
+	data := t.TempDir()
+	indexes := t.TempDir()
+	writeFile(t, filepath.Join(data, "first.txt"), "a.example.com\n")
+	patterns := []string{filepath.Join(data, "*.txt")}
+	newSource := func() *Source {
+		source := &Source{IndexDirectory: indexes}
+		source.AddApiKeys(patterns)
+		return source
+	}
+
+	subdomains, errs := testutils.RunSource(context.Background(), t, newSource(), "example.com")
+	require.Empty(t, errs)
+	require.Equal(t, []string{"a.example.com"}, subdomains)
+	manifest := filepath.Join(indexDirectory(indexes, patterns), "manifest.json")
+	built, err := os.Stat(manifest)
+	require.NoError(t, err)
+
+	// Unchanged files reuse the index
+	_, errs = testutils.RunSource(context.Background(), t, newSource(), "example.com")
+	require.Empty(t, errs)
+	reused, err := os.Stat(manifest)
+	require.NoError(t, err)
+	require.Equal(t, built.ModTime(), reused.ModTime())
+
+	// New files are added to the index
+	writeFile(t, filepath.Join(data, "second.txt"), "b.example.com\n")
+	subdomains, errs = testutils.RunSource(context.Background(), t, newSource(), "example.com")
+	require.Empty(t, errs)
+	require.Equal(t, []string{"a.example.com", "b.example.com"}, subdomains)
+
+	// Modified files rebuild it without their stale hostnames
+	writeFile(t, filepath.Join(data, "first.txt"), "c.example.com\nd.example.com\n")
+	subdomains, errs = testutils.RunSource(context.Background(), t, newSource(), "example.com")
+	require.Empty(t, errs)
+	require.Equal(t, []string{"b.example.com", "c.example.com", "d.example.com"}, subdomains)
+}
+
+func TestSourceIndexOutlivesFirstRun(t *testing.T) {
+	data := t.TempDir()
+	// Enough lines for the build to check the context
+	writeFile(t, filepath.Join(data, "large.txt"), strings.Repeat("a.example.com\n", 200000))
+	source := &Source{IndexDirectory: t.TempDir()}
+	source.AddApiKeys([]string{filepath.Join(data, "*.txt")})
+
+	// The first domain gives up on the index
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	testutils.RunSource(ctx, t, source, "example.com")
+
+	subdomains, errs := testutils.RunSource(context.Background(), t, source, "example.com")
+	require.Empty(t, errs, "the index must not fail with the context of the first domain")
+	require.Equal(t, []string{"a.example.com"}, subdomains)
+}
+
+func TestSourceMissingFiles(t *testing.T) {
+	source := &Source{IndexDirectory: t.TempDir()}
+	source.AddApiKeys([]string{filepath.Join(t.TempDir(), "*.json.gz")})
+
+	_, errs := testutils.RunSource(context.Background(), t, source, "example.com")
+	require.Len(t, errs, 1)
+	require.ErrorContains(t, errs[0], "no file matches")
+}
@@ -0,0 +1,311 @@
+package dataset
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"golang.org/x/exp/maps"
+	"golang.org/x/net/publicsuffix"
+)
+
+// shards is the number of files the hostnames are spread over by registrable domain
+const shards = 256
+
+// fileState identifies the content of an indexed file
+type fileState struct {
+	Size    int64 `json:"size"`
+	ModTime int64 `json:"mod_time"`
+}
+
+// manifest lists the files an index was built from
+type manifest struct {
+	Files map[string]fileState `json:"files"`
+}
+
+// index holds the hostnames of the files of a dataset in shards keyed by
+// registrable domain, so that a lookup only reads the shard of its domain
+type index struct {
+	directory string
+}
+
+// indexDirectory returns the directory of the index of a set of patterns
+func indexDirectory(root string, patterns []string) string {
+	sorted := append([]string(nil), patterns...)
+	sort.Strings(sorted)
+	hash := sha256.Sum256([]byte(strings.Join(sorted, "\n")))
+	return filepath.Join(root, hex.EncodeToString(hash[:8]))
+}
+
+// buildIndex returns the index of the files matching the patterns. The index
+// is reused when the files didn't change, new files are added to it and it
+// is rebuilt when files were modified or removed.
+func buildIndex(ctx context.Context, directory string, patterns []string) (*index, error) {
+	files, err := resolveFiles(patterns)
+	if err != nil {
+		return nil, err
+	}
+	idx := &index{directory: directory}
+
+	previous, err := idx.readManifest()
+	if err != nil {
+		return nil, err
+	}
+	var added []string
+	var rebuild bool
+	for file, current := range files.Files {
+		state, ok := previous.Files[file]
+		switch {
+		case !ok:
+			added = append(added, file)
+		case state != current:
+			rebuild = true
+		}
+	}
+	for file := range previous.Files {
+		if _, ok := files.Files[file]; !ok {
+			rebuild = true
+		}
+	}
+	if rebuild {
+		// Modified or removed files leave stale hostnames, the index is rebuilt
+		if err := os.RemoveAll(directory); err != nil {
+			return nil, err
+		}
+		added = maps.Keys(files.Files)
+	}
+	if len(added) == 0 {
+		return idx, nil
+	}
+	sort.Strings(added)
+
+	if err := os.MkdirAll(directory, 0700); err != nil {
+		return nil, err
+	}
+	// The manifest is removed first so that an interrupted build is not reused
+	if err := os.Remove(idx.manifestFile()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err := idx.add(ctx, added); err != nil {
+		return nil, err
+	}
+	return idx, idx.writeManifest(files)
+}
+
+// resolveFiles expands the patterns into the files they match with their state
+func resolveFiles(patterns []string) (*manifest, error) {
+	files := &manifest{Files: make(map[string]fileState)}
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %s: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no file matches %s", pattern)
+		}
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, err
+			}
+			if info.IsDir() {
+				continue
+			}
+			absolute, err := filepath.Abs(match)
+			if err != nil {
+				return nil, err
+			}
+			files.Files[absolute] = fileState{Size: info.Size(), ModTime: info.ModTime().UnixNano()}
+		}
+	}
+	return files, nil
+}
+
+func (idx *index) manifestFile() string {
+	return filepath.Join(idx.directory, "manifest.json")
+}
+
+func (idx *index) shardFile(shard uint32) string {
+	return filepath.Join(idx.directory, fmt.Sprintf("%02x.txt", shard))
+}
+
+// readManifest returns the manifest of a complete index, empty without index
+func (idx *index) readManifest() (*manifest, error) {
+	data, err := os.ReadFile(idx.manifestFile())
+	if errors.Is(err, os.ErrNotExist) {
+		return &manifest{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var previous manifest
+	if err := jsoniter.Unmarshal(data, &previous); err != nil {
+		return nil, err
+	}
+	return &previous, nil
+}
+
+func (idx *index) writeManifest(files *manifest) error {
+	data, err := jsoniter.Marshal(files)
+	if err != nil {
+		return err
+	}
+	temporary := idx.manifestFile() + ".tmp"
+	if err := os.WriteFile(temporary, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(temporary, idx.manifestFile())
+}
+
+// add appends the hostnames of files to the shards
+func (idx *index) add(ctx context.Context, files []string) (err error) {
+	writers := make([]*bufio.Writer, shards)
+	handles := make([]*os.File, shards)
+	defer func() {
+		for shard, handle := range handles {
+			if handle == nil {
+				continue
+			}
+			if flushErr := writers[shard].Flush(); err == nil {
+				err = flushErr
+			}
+			if closeErr := handle.Close(); err == nil {
+				err = closeErr
+			}
+		}
+	}()
+
+	for _, file := range files {
+		err := readHostnames(ctx, file, func(hostname, registrable string) error {
+			shard := shardOf(registrable)
+			if writers[shard] == nil {
+				handle, err := os.OpenFile(idx.shardFile(shard), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+				if err != nil {
+					return err
+				}
+				handles[shard] = handle
+				writers[shard] = bufio.NewWriter(handle)
+			}
+			_, err := writers[shard].WriteString(hostname + "\n")
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("could not index %s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+// lookup returns the unique indexed hostnames under a domain
+func (idx *index) lookup(domain string) ([]string, error) {
+	registrable, err := publicsuffix.EffectiveTLDPlusOne(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(idx.shardFile(shardOf(registrable)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	suffix := "." + domain
+	seen := make(map[string]struct{})
+	var hostnames []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		hostname := scanner.Text()
+		if !strings.HasSuffix(hostname, suffix) {
+			continue
+		}
+		if _, ok := seen[hostname]; !ok {
+			seen[hostname] = struct{}{}
+			hostnames = append(hostnames, hostname)
+		}
+	}
+	return hostnames, scanner.Err()
+}
+
+func shardOf(registrable string) uint32 {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(registrable))
+	return hash.Sum32() % shards
+}
+
+// readHostnames calls found for each hostname of a file, with its registrable
+// domain. Files ending with .gz are decompressed. The hostnames are read from
+// any line based format, such as FDNS JSON lines, zone files, massdns or dnsx
+// output and plain lists, by keeping the words looking like hostnames.
+func readHostnames(ctx context.Context, file string, found func(hostname, registrable string) error) error {
+	handle, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer handle.Close()
+
+	var reader io.Reader = handle
+	if strings.HasSuffix(file, ".gz") {
+		gzipReader, err := gzip.NewReader(handle)
+		if err != nil {
+			return err
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lines := 0; scanner.Scan(); lines++ {
+		if lines%100000 == 0 && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		for _, word := range strings.FieldsFunc(scanner.Text(), isSeparator) {
+			hostname := normalizeHostname(word)
+			if hostname == "" {
+				continue
+			}
+			registrable, err := publicsuffix.EffectiveTLDPlusOne(hostname)
+			if err != nil {
+				continue
+			}
+			if err := found(hostname, registrable); err != nil {
+				return err
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+func isSeparator(r rune) bool {
+	return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_' || r == '*')
+}
+
+// normalizeHostname returns a word as a lower case hostname without trailing
+// dot and wildcard label, or an empty string when it isn't a hostname
+func normalizeHostname(word string) string {
+	hostname := strings.ToLower(strings.TrimSuffix(word, "."))
+	hostname = strings.TrimPrefix(hostname, "*.")
+	if len(hostname) > 253 || !strings.Contains(hostname, ".") || strings.ContainsAny(hostname, "*") ||
+		strings.HasPrefix(hostname, ".") || strings.Contains(hostname, "..") {
+		return ""
+	}
+	// IP addresses and numbers aren't hostnames
+	if strings.Trim(hostname, "0123456789.") == "" {
+		return ""
+	}
+	return hostname
+}
@@ -7,10 +7,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
-	"time"
 
 	jsoniter "github.com/json-iterator/go"
 
@@ -39,41 +39,35 @@ type dnsdbObj struct {
 
 // Source is the passive scraping agent
 type Source struct {
-	apiKeys   []string
-	timeTaken time.Duration
-	errors    int
-	results   uint64
-	skipped   bool
+	keys *subscraping.KeyPool[string]
 }
 
 // Run function returns all subdomains found with the service
 func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Session) <-chan subscraping.Result {
 	results := make(chan subscraping.Result)
-	s.errors = 0
-	s.results = 0
 
 	go func() {
-		defer func(startTime time.Time) {
-			s.timeTaken = time.Since(startTime)
-			close(results)
-		}(time.Now())
+		defer close(results)
 
 		sourceName := s.Name()
 
-		randomApiKey := subscraping.PickRandom(s.apiKeys, sourceName)
-		if randomApiKey == "" {
+		randomApiKey, ok := subscraping.PickKey(s.keys, sourceName, session, results)
+		if !ok {
 			return
 		}
 
-		headers := map[string]string{
-			"X-API-KEY": randomApiKey,
-			"Accept":    "application/x-ndjson",
+		get := func(url string) (*http.Response, error) {
+			return s.keys.Do(ctx, session, sourceName, &randomApiKey, func(ctx context.Context, apiKey string) (*http.Response, error) {
+				return session.Get(ctx, url, "", map[string]string{
+					"X-API-KEY": apiKey,
+					"Accept":    "application/x-ndjson",
+				})
+			})
 		}
 
-		offsetMax, err := getMaxOffset(ctx, session, headers)
+		offsetMax, err := getMaxOffset(session, get)
 		if err != nil {
 			results <- subscraping.Result{Source: sourceName, Type: subscraping.Error, Error: err}
-			s.errors++
 			return
 		}
 
@@ -84,13 +78,14 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 		queryParams.Add("limit", "0")
 		queryParams.Add("swclient", "subfinder")
 
+		var resultCount uint64
+
 		for {
 			url := urlTemplate + queryParams.Encode()
 
-			resp, err := session.Get(ctx, url, "", headers)
+			resp, err := get(url)
 			if err != nil {
 				results <- subscraping.Result{Source: sourceName, Type: subscraping.Error, Error: err}
-				s.errors++
 				session.DiscardHTTPResponse(resp)
 				return
 			}
@@ -103,7 +98,6 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 					break
 				} else if err != nil {
 					results <- subscraping.Result{Source: sourceName, Type: subscraping.Error, Error: err}
-					s.errors++
 					resp.Body.Close()
 					return
 				}
@@ -112,7 +106,6 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 				err = jsoniter.Unmarshal(n, &response)
 				if err != nil {
 					results <- subscraping.Result{Source: sourceName, Type: subscraping.Error, Error: err}
-					s.errors++
 					resp.Body.Close()
 					return
 				}
@@ -128,7 +121,7 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 						results <- subscraping.Result{
 							Source: sourceName, Type: subscraping.Subdomain, Value: strings.TrimSuffix(response.Obj.Name, "."),
 						}
-						s.results++
+						resultCount++
 					}
 				} else if respCond != "begin" {
 					// if the respCond is not "", "ongoing", or "begin", then it is a terminating condition, so break out of the loop
@@ -142,16 +135,15 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 			// 3. anything else - This is an error and should be reported to the user. The user can then decide to use the results up to this
 			// point or discard and retry.
 			if respCond == "limited" {
-				if offsetMax != 0 && s.results <= offsetMax {
-					// Reset done to false to get more results with an offset query parameter set to s.results
-					queryParams.Set("offset", strconv.FormatUint(s.results, 10))
+				if offsetMax != 0 && resultCount <= offsetMax {
+					// Reset done to false to get more results with an offset query parameter set to resultCount
+					queryParams.Set("offset", strconv.FormatUint(resultCount, 10))
 					continue
 				}
 			} else if respCond != "succeeded" {
 				// DNSDB's terminating jsonl object's cond is not "limited" or succeeded" (#3), this is an error, notify the user.
 				err = fmt.Errorf("%s terminated with condition: %s", sourceName, respCond)
 				results <- subscraping.Result{Source: sourceName, Type: subscraping.Error, Error: err}
-				s.errors++
 			}
 
 			resp.Body.Close()
@@ -180,22 +172,13 @@ func (s *Source) NeedsKey() bool {
 }
 
 func (s *Source) AddApiKeys(keys []string) {
-	s.apiKeys = keys
-}
-
-func (s *Source) Statistics() subscraping.Statistics {
-	return subscraping.Statistics{
-		Errors:    s.errors,
-		Results:   int(s.results),
-		TimeTaken: s.timeTaken,
-		Skipped:   s.skipped,
-	}
+	s.keys = subscraping.NewStringKeyPool(keys)
 }
 
-func getMaxOffset(ctx context.Context, session *subscraping.Session, headers map[string]string) (uint64, error) {
+func getMaxOffset(session *subscraping.Session, get func(url string) (*http.Response, error)) (uint64, error) {
 	var offsetMax uint64
 	url := fmt.Sprintf("%s/rate_limit", urlBase)
-	resp, err := session.Get(ctx, url, "", headers)
+	resp, err := get(url)
 	defer session.DiscardHTTPResponse(resp)
 	if err != nil {
 		return offsetMax, err
@@ -219,3 +202,29 @@ func getMaxOffset(ctx context.Context, session *subscraping.Session, headers map
 
 	return offsetMax, nil
 }
+
+type rateLimitResponse struct {
+	Rate struct {
+		Remaining json.Number `json:"remaining"`
+	} `json:"rate"`
+}
+
+// CheckKeys checks the keys against the rate limit endpoint, which doesn't
+// count against the quota, the remaining credits are the lookups left or
+// unknown for unlimited keys
+func (s *Source) CheckKeys(ctx context.Context, session *subscraping.Session) []subscraping.KeyCheck {
+	return subscraping.CheckKeys(s.keys, func(apiKey string) (*http.Response, error) {
+		return session.Get(ctx, fmt.Sprintf("%s/rate_limit", urlBase), "", map[string]string{"X-API-KEY": apiKey})
+	}, func(body []byte) (int, error) {
+		var rateLimit rateLimitResponse
+		if err := jsoniter.Unmarshal(body, &rateLimit); err != nil {
+			return 0, err
+		}
+		// the remaining lookups of unlimited keys are "n/a"
+		remaining, err := strconv.Atoi(rateLimit.Rate.Remaining.String())
+		if err != nil {
+			return 0, subscraping.ErrRemainingUnknown
+		}
+		return remaining, nil
+	})
+}
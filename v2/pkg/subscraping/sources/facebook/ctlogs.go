@@ -5,10 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"time"
+	"sync"
 
 	"github.com/projectdiscovery/gologger"
-	"github.com/projectdiscovery/retryablehttp-go"
 	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
 	errorutil "github.com/projectdiscovery/utils/errors"
 	"github.com/projectdiscovery/utils/generic"
@@ -26,89 +25,93 @@ var (
 )
 
 type apiKey struct {
-	AppID       string
-	Secret      string
-	AccessToken string // obtained by calling
+	AppID  string
+	Secret string
+}
+
+// Source is the passive scraping agent
+type Source struct {
+	keys *subscraping.KeyPool[apiKey]
+	// apiKeys holds every configured key for the key checks
+	apiKeys []apiKey
+
+	mutex sync.Mutex
+	// accessTokens are the tokens the keys were exchanged for, obtained by calling
 	// https://graph.facebook.com/oauth/access_token?client_id=APP_ID&client_secret=APP_SECRET&grant_type=client_credentials
-	Error error // error while fetching access token
+	accessTokens map[apiKey]string
 }
 
-// FetchAccessToken fetches the access token for the api key
-// using app id and secret
-func (k *apiKey) FetchAccessToken() {
-	if generic.EqualsAny("", k.AppID, k.Secret) {
-		k.Error = fmt.Errorf("invalid app id or secret")
-		return
+// accessToken returns the access token of a key. The token is fetched through
+// the session on first use, so that the exchange is recorded and replayed
+// with the other requests, and a failed exchange is tried again on next use.
+func (s *Source) accessToken(ctx context.Context, session *subscraping.Session, key apiKey) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if accessToken, ok := s.accessTokens[key]; ok {
+		return accessToken, nil
 	}
-	resp, err := retryablehttp.Get(fmt.Sprintf(authUrl, k.AppID, k.Secret))
+	if generic.EqualsAny("", key.AppID, key.Secret) {
+		return "", fmt.Errorf("invalid app id or secret")
+	}
+	resp, err := session.Get(ctx, fmt.Sprintf(authUrl, key.AppID, key.Secret), "", nil)
 	if err != nil {
-		k.Error = err
-		return
+		session.DiscardHTTPResponse(resp)
+		return "", err
 	}
 	defer resp.Body.Close()
 	bin, err := io.ReadAll(resp.Body)
 	if err != nil {
-		k.Error = err
-		return
+		return "", err
 	}
 	auth := &authResponse{}
 	if err := json.Unmarshal(bin, auth); err != nil {
-		k.Error = err
-		return
+		return "", err
 	}
 	if auth.AccessToken == "" {
-		k.Error = fmt.Errorf("invalid response from facebook got %v", string(bin))
-		return
+		return "", fmt.Errorf("invalid response from facebook got %v", string(bin))
 	}
-	k.AccessToken = auth.AccessToken
-}
-
-// IsValid returns true if the api key is valid
-func (k *apiKey) IsValid() bool {
-	return k.AccessToken != ""
-}
-
-// Source is the passive scraping agent
-type Source struct {
-	apiKeys   []apiKey
-	timeTaken time.Duration
-	errors    int
-	results   int
-	skipped   bool
+	subscraping.RegisterSecret(auth.AccessToken)
+	if s.accessTokens == nil {
+		s.accessTokens = make(map[apiKey]string)
+	}
+	s.accessTokens[key] = auth.AccessToken
+	return auth.AccessToken, nil
 }
 
 // Run function returns all subdomains found with the service
 func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Session) <-chan subscraping.Result {
 	results := make(chan subscraping.Result)
-	s.errors = 0
-	s.results = 0
 
-	if len(s.apiKeys) == 0 {
-		s.skipped = true
+	if s.keys.Len() == 0 {
+		session.Statistics.Skip(s.Name())
 		close(results)
 		return results
 	}
 
 	go func() {
-		defer func(startTime time.Time) {
-			s.timeTaken = time.Since(startTime)
-			close(results)
-		}(time.Now())
+		defer close(results)
 
-		key := subscraping.PickRandom(s.apiKeys, s.Name())
-		domainsURL := fmt.Sprintf(domainsUrl, key.AccessToken, domain)
+		key, ok := subscraping.PickKey(s.keys, s.Name(), session, results)
+		if !ok {
+			return
+		}
+		accessToken, err := s.accessToken(ctx, session, key)
+		if err != nil {
+			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
+			return
+		}
+		domainsURL := fmt.Sprintf(domainsUrl, accessToken, domain)
 
 		for {
 			// unfortunately, this cannot be parllelized since pagination is cursor based
 			resp, err := session.Get(ctx, domainsURL, "", nil)
 			if err != nil {
-				s.errors++
 				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
 				return
 			}
 			bin, err := io.ReadAll(resp.Body)
 			if err != nil {
-				s.errors++
 				gologger.Verbose().Msgf("failed to read response body: %s\n", err)
 				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
 				return
@@ -116,13 +119,11 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 			resp.Body.Close()
 			response := &response{}
 			if err := json.Unmarshal(bin, response); err != nil {
-				s.errors++
 				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: errorutil.NewWithErr(err).Msgf("failed to unmarshal response: %s", string(bin))}
 				return
 			}
 			for _, v := range response.Data {
 				for _, domain := range v.Domains {
-					s.results++
 					results <- subscraping.Result{Source: s.Name(), Type: subscraping.Subdomain, Value: domain}
 				}
 			}
@@ -158,32 +159,28 @@ func (s *Source) NeedsKey() bool {
 	return true
 }
 
-// AddApiKeys adds api keys to the source
+// AddApiKeys adds api keys to the source, as app_id:app_secret
 func (s *Source) AddApiKeys(keys []string) {
-	allapikeys := subscraping.CreateApiKeys(keys, func(k, v string) apiKey {
-		apiKey := apiKey{AppID: k, Secret: v}
-		apiKey.FetchAccessToken()
-		if apiKey.Error != nil {
-			gologger.Warning().Msgf("Could not fetch access token for %s: %s\n", k, apiKey.Error)
-		}
-		return apiKey
+	s.apiKeys = subscraping.CreateApiKeys(keys, func(k, v string) apiKey {
+		return apiKey{AppID: k, Secret: v}
+	})
+	s.keys = subscraping.NewKeyPool(s.apiKeys, func(key apiKey) string {
+		return key.AppID + ":" + key.Secret
 	})
-	// filter out invalid keys
-	for _, key := range allapikeys {
-		if key.IsValid() {
-			s.apiKeys = append(s.apiKeys, key)
-		}
-	}
 }
 
-// Statistics returns the statistics for the source
-func (s *Source) Statistics() subscraping.Statistics {
-	return subscraping.Statistics{
-		Errors:    s.errors,
-		Results:   s.results,
-		TimeTaken: s.timeTaken,
-		Skipped:   s.skipped,
+// CheckKeys exchanges the keys for their access token
+func (s *Source) CheckKeys(ctx context.Context, session *subscraping.Session) []subscraping.KeyCheck {
+	var checks []subscraping.KeyCheck
+	for _, key := range s.apiKeys {
+		check := subscraping.KeyCheck{Key: subscraping.MaskKey(key.AppID + ":" + key.Secret), Status: subscraping.KeyValid, Remaining: -1}
+		if _, err := s.accessToken(ctx, session, key); err != nil {
+			check.Status = subscraping.KeyInvalid
+			check.Error = err
+		}
+		checks = append(checks, check)
 	}
+	return checks
 }
 
 func updateParamInURL(url, param, value string) string {
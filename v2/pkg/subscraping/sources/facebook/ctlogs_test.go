@@ -1,15 +1,21 @@
 package facebook
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/projectdiscovery/ratelimit"
 	"github.com/projectdiscovery/retryablehttp-go"
 	"github.com/projectdiscovery/utils/generic"
+
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
 )
 
 var (
@@ -27,16 +33,29 @@ func TestFacebookSource(t *testing.T) {
 	if generic.EqualsAny("", fb_API_ID, fb_API_SECRET) {
 		t.SkipNow()
 	}
-	k := apiKey{
-		AppID:  fb_API_ID,
-		Secret: fb_API_SECRET,
+	multiRateLimiter, err := ratelimit.NewMultiLimiter(context.Background(), &ratelimit.Options{
+		Key:         "facebook",
+		IsUnlimited: true,
+		MaxCount:    math.MaxUint32,
+		Duration:    time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
 	}
-	k.FetchAccessToken()
-	if k.Error != nil {
-		t.Fatal(k.Error)
+	session, err := subscraping.NewSession("hackerone.com", "", multiRateLimiter, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	source := &Source{}
+	ctx := context.WithValue(context.Background(), subscraping.CtxSourceArg, source.Name())
+	accessToken, err := source.accessToken(ctx, session, apiKey{AppID: fb_API_ID, Secret: fb_API_SECRET})
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	fetchURL := fmt.Sprintf("https://graph.facebook.com/certificates?fields=domains&access_token=%s&query=hackerone.com&limit=5", k.AccessToken)
+	fetchURL := fmt.Sprintf("https://graph.facebook.com/certificates?fields=domains&access_token=%s&query=hackerone.com&limit=5", accessToken)
 	resp, err := retryablehttp.Get(fetchURL)
 	if err != nil {
 		t.Fatal(err)
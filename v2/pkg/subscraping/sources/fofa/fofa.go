@@ -5,9 +5,9 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"net/http"
 	"regexp"
 	"strings"
-	"time"
 
 	jsoniter "github.com/json-iterator/go"
 
@@ -23,11 +23,7 @@ type fofaResponse struct {
 
 // Source is the passive scraping agent
 type Source struct {
-	apiKeys   []apiKey
-	timeTaken time.Duration
-	errors    int
-	results   int
-	skipped   bool
+	keys *subscraping.KeyPool[apiKey]
 }
 
 type apiKey struct {
@@ -38,27 +34,26 @@ type apiKey struct {
 // Run function returns all subdomains found with the service
 func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Session) <-chan subscraping.Result {
 	results := make(chan subscraping.Result)
-	s.errors = 0
-	s.results = 0
 
 	go func() {
-		defer func(startTime time.Time) {
-			s.timeTaken = time.Since(startTime)
-			close(results)
-		}(time.Now())
+		defer close(results)
 
-		randomApiKey := subscraping.PickRandom(s.apiKeys, s.Name())
+		randomApiKey, ok := subscraping.PickKey(s.keys, s.Name(), session, results)
+		if !ok {
+			return
+		}
 		if randomApiKey.username == "" || randomApiKey.secret == "" {
-			s.skipped = true
+			session.Statistics.Skip(s.Name())
 			return
 		}
 
 		// fofa api doc https://fofa.info/static_pages/api_help
 		qbase64 := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("domain=\"%s\"", domain)))
-		resp, err := session.SimpleGet(ctx, fmt.Sprintf("https://fofa.info/api/v1/search/all?full=true&fields=host&page=1&size=10000&email=%s&key=%s&qbase64=%s", randomApiKey.username, randomApiKey.secret, qbase64))
+		resp, err := s.keys.Do(ctx, session, s.Name(), &randomApiKey, func(ctx context.Context, randomApiKey apiKey) (*http.Response, error) {
+			return session.SimpleGet(ctx, fmt.Sprintf("https://fofa.info/api/v1/search/all?full=true&fields=host&page=1&size=10000&email=%s&key=%s&qbase64=%s", randomApiKey.username, randomApiKey.secret, qbase64))
+		})
 		if err != nil && resp == nil {
 			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-			s.errors++
 			session.DiscardHTTPResponse(resp)
 			return
 		}
@@ -67,7 +62,6 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 		err = jsoniter.NewDecoder(resp.Body).Decode(&response)
 		if err != nil {
 			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-			s.errors++
 			resp.Body.Close()
 			return
 		}
@@ -77,7 +71,6 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 			results <- subscraping.Result{
 				Source: s.Name(), Type: subscraping.Error, Error: fmt.Errorf("%s", response.ErrMsg),
 			}
-			s.errors++
 			return
 		}
 
@@ -91,7 +84,6 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 					subdomain = re.ReplaceAllString(subdomain, "")
 				}
 				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Subdomain, Value: subdomain}
-				s.results++
 			}
 		}
 	}()
@@ -117,16 +109,32 @@ func (s *Source) NeedsKey() bool {
 }
 
 func (s *Source) AddApiKeys(keys []string) {
-	s.apiKeys = subscraping.CreateApiKeys(keys, func(k, v string) apiKey {
+	s.keys = subscraping.NewKeyPool(subscraping.CreateApiKeys(keys, func(k, v string) apiKey {
 		return apiKey{k, v}
+	}), func(key apiKey) string {
+		return key.username + ":" + key.secret
 	})
 }
 
-func (s *Source) Statistics() subscraping.Statistics {
-	return subscraping.Statistics{
-		Errors:    s.errors,
-		Results:   s.results,
-		TimeTaken: s.timeTaken,
-		Skipped:   s.skipped,
-	}
+type accountResponse struct {
+	Error          bool `json:"error"`
+	RemainApiQuery int  `json:"remain_api_query"`
+}
+
+// CheckKeys checks the keys against the account endpoint, which answers a
+// rejected key with an error body, the remaining credits are the api queries
+// left for the day
+func (s *Source) CheckKeys(ctx context.Context, session *subscraping.Session) []subscraping.KeyCheck {
+	return subscraping.CheckKeys(s.keys, func(apiKey apiKey) (*http.Response, error) {
+		return session.SimpleGet(ctx, fmt.Sprintf("https://fofa.info/api/v1/info/my?email=%s&key=%s", apiKey.username, apiKey.secret))
+	}, func(body []byte) (int, error) {
+		var account accountResponse
+		if err := jsoniter.Unmarshal(body, &account); err != nil {
+			return 0, err
+		}
+		if account.Error {
+			return 0, subscraping.ErrInvalidKey
+		}
+		return account.RemainApiQuery, nil
+	})
 }
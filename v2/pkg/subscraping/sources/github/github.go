@@ -9,15 +9,13 @@ import (
 	"net/http"
 	"net/url"
 	"regexp"
-	"strconv"
 	"strings"
-	"time"
+	"sync"
 
 	jsoniter "github.com/json-iterator/go"
 
 	"github.com/tomnomnom/linkheader"
 
-	"github.com/projectdiscovery/gologger"
 	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
 )
 
@@ -27,6 +25,7 @@ type textMatch struct {
 
 type item struct {
 	Name        string      `json:"name"`
+	URL         string      `json:"url"`
 	HTMLURL     string      `json:"html_url"`
 	TextMatches []textMatch `json:"text_matches"`
 }
@@ -36,91 +35,91 @@ type response struct {
 	Items      []item `json:"items"`
 }
 
+// publicAPIURL is the API of github.com
+const publicAPIURL = "https://api.github.com"
+
+// instance is a GitHub or GitHub Enterprise Server instance searched with its own tokens
+type instance struct {
+	apiURL string
+	// public tells github.com, whose files are fetched from raw.githubusercontent.com
+	public bool
+	keys   *subscraping.KeyPool[string]
+}
+
 // Source is the passive scraping agent
 type Source struct {
-	apiKeys   []string
-	timeTaken time.Duration
-	errors    int
-	results   int
-	skipped   bool
+	instances []*instance
 }
 
 // Run function returns all subdomains found with the service
 func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Session) <-chan subscraping.Result {
 	results := make(chan subscraping.Result)
-	s.errors = 0
-	s.results = 0
 
 	go func() {
-		defer func(startTime time.Time) {
-			s.timeTaken = time.Since(startTime)
-			close(results)
-		}(time.Now())
-
-		if len(s.apiKeys) == 0 {
-			gologger.Debug().Msgf("Cannot use the %s source because there was no key defined for it.", s.Name())
-			s.skipped = true
+		defer close(results)
+
+		if len(s.instances) == 0 {
+			subscraping.PickKey[string](nil, s.Name(), session, results)
 			return
 		}
 
-		tokens := NewTokenManager(s.apiKeys)
+		var wg sync.WaitGroup
+		for _, server := range s.instances {
+			wg.Add(1)
+			go func(instance *instance) {
+				defer wg.Done()
+
+				token, ok := subscraping.PickKey(instance.keys, s.Name(), session, results)
+				if !ok {
+					return
+				}
 
-		searchURL := fmt.Sprintf("https://api.github.com/search/code?per_page=100&q=%s&sort=created&order=asc", domain)
-		s.enumerate(ctx, searchURL, domainRegexp(domain), tokens, session, results)
+				searchURL := fmt.Sprintf("%s/search/code?per_page=100&q=%s&sort=created&order=asc", instance.apiURL, domain)
+				s.enumerate(ctx, instance, searchURL, domainRegexp(domain), &token, session, results)
+			}(server)
+		}
+		wg.Wait()
 	}()
 
 	return results
 }
 
-func (s *Source) enumerate(ctx context.Context, searchURL string, domainRegexp *regexp.Regexp, tokens *Tokens, session *subscraping.Session, results chan subscraping.Result) {
+func (s *Source) enumerate(ctx context.Context, instance *instance, searchURL string, domainRegexp *regexp.Regexp, token *string, session *subscraping.Session, results chan subscraping.Result) {
 	select {
 	case <-ctx.Done():
 		return
 	default:
 	}
 
-	token := tokens.Get()
-	headers := map[string]string{
-		"Accept": "application/vnd.github.v3.text-match+json", "Authorization": "token " + token.Hash,
-	}
-
-	// Initial request to GitHub search
-	resp, err := session.Get(ctx, searchURL, "", headers)
-	isForbidden := resp != nil && resp.StatusCode == http.StatusForbidden
-	if err != nil && !isForbidden {
+	// Initial request to GitHub search, tokens over their rate limit are
+	// replaced by the key pool
+	resp, err := instance.keys.Do(ctx, session, s.Name(), token, func(ctx context.Context, token string) (*http.Response, error) {
+		headers := map[string]string{
+			"Accept": "application/vnd.github.v3.text-match+json", "Authorization": "token " + token,
+		}
+		return session.Get(ctx, searchURL, "", headers)
+	})
+	if err != nil {
 		results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-		s.errors++
 		session.DiscardHTTPResponse(resp)
 		return
 	}
 
-	// Retry enumerarion after Retry-After seconds on rate limit abuse detected
-	ratelimitRemaining, _ := strconv.ParseInt(resp.Header.Get("X-Ratelimit-Remaining"), 10, 64)
-	if isForbidden && ratelimitRemaining == 0 {
-		retryAfterSeconds, _ := strconv.ParseInt(resp.Header.Get("Retry-After"), 10, 64)
-		tokens.setCurrentTokenExceeded(retryAfterSeconds)
-		resp.Body.Close()
-
-		s.enumerate(ctx, searchURL, domainRegexp, tokens, session, results)
-	}
-
 	var data response
 
 	// Marshall json response
 	err = jsoniter.NewDecoder(resp.Body).Decode(&data)
 	if err != nil {
 		results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-		s.errors++
 		resp.Body.Close()
 		return
 	}
 
 	resp.Body.Close()
 
-	err = s.proccesItems(ctx, data.Items, domainRegexp, s.Name(), session, results)
+	err = s.proccesItems(ctx, instance, *token, data.Items, domainRegexp, s.Name(), session, results)
 	if err != nil {
 		results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-		s.errors++
 		return
 	}
 
@@ -132,19 +131,27 @@ func (s *Source) enumerate(ctx context.Context, searchURL string, domainRegexp *
 			nextURL, err := url.QueryUnescape(link.URL)
 			if err != nil {
 				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-				s.errors++
 				return
 			}
-			s.enumerate(ctx, nextURL, domainRegexp, tokens, session, results)
+			s.enumerate(ctx, instance, nextURL, domainRegexp, token, session, results)
 		}
 	}
 }
 
 // proccesItems process github response items
-func (s *Source) proccesItems(ctx context.Context, items []item, domainRegexp *regexp.Regexp, name string, session *subscraping.Session, results chan subscraping.Result) error {
+func (s *Source) proccesItems(ctx context.Context, instance *instance, token string, items []item, domainRegexp *regexp.Regexp, name string, session *subscraping.Session, results chan subscraping.Result) error {
 	for _, item := range items {
-		// find subdomains in code
-		resp, err := session.SimpleGet(ctx, rawURL(item.HTMLURL))
+		// find subdomains in code, the files of enterprise instances are
+		// private and fetched through the contents API
+		var resp *http.Response
+		var err error
+		if instance.public {
+			resp, err = session.SimpleGet(ctx, rawURL(item.HTMLURL))
+		} else {
+			resp, err = session.Get(ctx, item.URL, "", map[string]string{
+				"Accept": "application/vnd.github.raw", "Authorization": "token " + token,
+			})
+		}
 		if err != nil {
 			if resp != nil && resp.StatusCode != http.StatusNotFound {
 				session.DiscardHTTPResponse(resp)
@@ -161,7 +168,6 @@ func (s *Source) proccesItems(ctx context.Context, items []item, domainRegexp *r
 				}
 				for _, subdomain := range domainRegexp.FindAllString(normalizeContent(line), -1) {
 					results <- subscraping.Result{Source: name, Type: subscraping.Subdomain, Value: subdomain}
-					s.results++
 
 				}
 			}
@@ -172,7 +178,6 @@ func (s *Source) proccesItems(ctx context.Context, items []item, domainRegexp *r
 		for _, textMatch := range item.TextMatches {
 			for _, subdomain := range domainRegexp.FindAllString(normalizeContent(textMatch.Fragment), -1) {
 				results <- subscraping.Result{Source: name, Type: subscraping.Subdomain, Value: subdomain}
-				s.results++
 			}
 		}
 	}
@@ -216,15 +221,42 @@ func (s *Source) NeedsKey() bool {
 	return true
 }
 
+// AddApiKeys adds tokens of github.com, or of GitHub Enterprise Server
+// instances as https://github.example.com:token
 func (s *Source) AddApiKeys(keys []string) {
-	s.apiKeys = keys
+	s.instances = nil
+	for _, instanceKeys := range subscraping.CreateInstanceKeys(keys) {
+		server := &instance{apiURL: publicAPIURL, public: true, keys: instanceKeys.Keys}
+		if instanceKeys.BaseURL != "" && instanceKeys.BaseURL != publicAPIURL && instanceKeys.BaseURL != "https://github.com" {
+			// The API of enterprise instances is under /api/v3
+			server.apiURL = strings.TrimSuffix(instanceKeys.BaseURL, "/api/v3") + "/api/v3"
+			server.public = false
+		}
+		s.instances = append(s.instances, server)
+	}
+}
+
+type rateLimitResponse struct {
+	Resources struct {
+		CodeSearch struct {
+			Remaining int `json:"remaining"`
+		} `json:"code_search"`
+	} `json:"resources"`
 }
 
-func (s *Source) Statistics() subscraping.Statistics {
-	return subscraping.Statistics{
-		Errors:    s.errors,
-		Results:   s.results,
-		TimeTaken: s.timeTaken,
-		Skipped:   s.skipped,
+// CheckKeys checks the tokens against the rate limit API of their instance,
+// which doesn't count against the rate limit, the remaining credits are the
+// code searches left
+func (s *Source) CheckKeys(ctx context.Context, session *subscraping.Session) []subscraping.KeyCheck {
+	var checks []subscraping.KeyCheck
+	for _, server := range s.instances {
+		checks = append(checks, subscraping.CheckKeys(server.keys, func(token string) (*http.Response, error) {
+			return session.Get(ctx, server.apiURL+"/rate_limit", "", map[string]string{"Authorization": "token " + token})
+		}, func(body []byte) (int, error) {
+			var rateLimit rateLimitResponse
+			err := jsoniter.Unmarshal(body, &rateLimit)
+			return rateLimit.Resources.CodeSearch.Remaining, err
+		})...)
 	}
+	return checks
 }
@@ -1,3 +1,4 @@
+// Package gitlab logic
 package gitlab
 
 import (
@@ -15,9 +16,18 @@ import (
 	"github.com/tomnomnom/linkheader"
 )
 
+// publicBaseURL is the URL of gitlab.com
+const publicBaseURL = "https://gitlab.com"
+
+// instance is gitlab.com or a self-managed GitLab instance searched with its own tokens
+type instance struct {
+	apiURL string
+	keys   *subscraping.KeyPool[string]
+}
+
 // Source is the passive scraping agent
 type Source struct {
-	apiKeys []string
+	instances []*instance
 }
 
 type item struct {
@@ -34,30 +44,43 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 	go func() {
 		defer close(results)
 
-		randomApiKey := subscraping.PickRandom(s.apiKeys, s.Name())
-		if randomApiKey == "" {
+		if len(s.instances) == 0 {
+			subscraping.PickKey[string](nil, s.Name(), session, results)
 			return
 		}
 
-		headers := map[string]string{"PRIVATE-TOKEN": randomApiKey}
+		var wg sync.WaitGroup
+		for _, server := range s.instances {
+			wg.Add(1)
+			go func(instance *instance) {
+				defer wg.Done()
 
-		searchURL := fmt.Sprintf("https://gitlab.com/api/v4/search?scope=blobs&search=%s&per_page=100", domain)
-		s.enumerate(ctx, searchURL, domainRegexp(domain), headers, session, results)
+				randomApiKey, ok := subscraping.PickKey(instance.keys, s.Name(), session, results)
+				if !ok {
+					return
+				}
 
+				searchURL := fmt.Sprintf("%s/search?scope=blobs&search=%s&per_page=100", instance.apiURL, domain)
+				s.enumerate(ctx, instance, searchURL, domainRegexp(domain), &randomApiKey, session, results)
+			}(server)
+		}
+		wg.Wait()
 	}()
 
 	return results
 }
 
-func (s *Source) enumerate(ctx context.Context, searchURL string, domainRegexp *regexp.Regexp, headers map[string]string, session *subscraping.Session, results chan subscraping.Result) {
+func (s *Source) enumerate(ctx context.Context, instance *instance, searchURL string, domainRegexp *regexp.Regexp, apiKey *string, session *subscraping.Session, results chan subscraping.Result) {
 	select {
 	case <-ctx.Done():
 		return
 	default:
 	}
 
-	resp, err := session.Get(ctx, searchURL, "", headers)
-	if err != nil && resp == nil {
+	resp, err := instance.keys.Do(ctx, session, s.Name(), apiKey, func(ctx context.Context, apiKey string) (*http.Response, error) {
+		return session.Get(ctx, searchURL, "", map[string]string{"PRIVATE-TOKEN": apiKey})
+	})
+	if err != nil {
 		results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
 		session.DiscardHTTPResponse(resp)
 		return
@@ -72,13 +95,18 @@ func (s *Source) enumerate(ctx context.Context, searchURL string, domainRegexp *
 		return
 	}
 
+	// the files are fetched with the key of the search
+	headers := map[string]string{"PRIVATE-TOKEN": *apiKey}
+
 	var wg sync.WaitGroup
 	wg.Add(len(items))
 
 	for _, it := range items {
 		go func(item item) {
+			defer wg.Done()
+
 			// The original item.Path causes 404 error because the Gitlab API is expecting the url encoded path
-			fileUrl := fmt.Sprintf("https://gitlab.com/api/v4/projects/%d/repository/files/%s/raw?ref=%s", item.ProjectId, url.QueryEscape(item.Path), item.Ref)
+			fileUrl := fmt.Sprintf("%s/projects/%d/repository/files/%s/raw?ref=%s", instance.apiURL, item.ProjectId, url.QueryEscape(item.Path), item.Ref)
 			resp, err := session.Get(ctx, fileUrl, "", headers)
 			if err != nil {
 				if resp == nil || (resp != nil && resp.StatusCode != http.StatusNotFound) {
@@ -101,8 +129,9 @@ func (s *Source) enumerate(ctx context.Context, searchURL string, domainRegexp *
 					}
 				}
 				resp.Body.Close()
+			} else {
+				session.DiscardHTTPResponse(resp)
 			}
-			defer wg.Done()
 		}(it)
 	}
 
@@ -117,7 +146,7 @@ func (s *Source) enumerate(ctx context.Context, searchURL string, domainRegexp *
 				return
 			}
 
-			s.enumerate(ctx, nextURL, domainRegexp, headers, session, results)
+			s.enumerate(ctx, instance, nextURL, domainRegexp, apiKey, session, results)
 		}
 	}
 
@@ -146,6 +175,30 @@ func (s *Source) NeedsKey() bool {
 	return true
 }
 
+// AddApiKeys adds tokens of gitlab.com, or of self-managed instances as
+// https://gitlab.example.com:token
 func (s *Source) AddApiKeys(keys []string) {
-	s.apiKeys = keys
+	s.instances = nil
+	for _, instanceKeys := range subscraping.CreateInstanceKeys(keys) {
+		baseURL := instanceKeys.BaseURL
+		if baseURL == "" {
+			baseURL = publicBaseURL
+		}
+		s.instances = append(s.instances, &instance{
+			apiURL: strings.TrimSuffix(baseURL, "/api/v4") + "/api/v4",
+			keys:   instanceKeys.Keys,
+		})
+	}
+}
+
+// CheckKeys checks the tokens against the user endpoint of their instance,
+// GitLab has no quota to report
+func (s *Source) CheckKeys(ctx context.Context, session *subscraping.Session) []subscraping.KeyCheck {
+	var checks []subscraping.KeyCheck
+	for _, server := range s.instances {
+		checks = append(checks, subscraping.CheckKeys(server.keys, func(token string) (*http.Response, error) {
+			return session.Get(ctx, server.apiURL+"/user", "", map[string]string{"PRIVATE-TOKEN": token})
+		}, nil)...)
+	}
+	return checks
 }
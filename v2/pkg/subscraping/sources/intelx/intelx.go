@@ -7,7 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"time"
+	"net/http"
 
 	jsoniter "github.com/json-iterator/go"
 
@@ -39,11 +39,7 @@ type requestBody struct {
 
 // Source is the passive scraping agent
 type Source struct {
-	apiKeys   []apiKey
-	timeTaken time.Duration
-	errors    int
-	results   int
-	skipped   bool
+	keys *subscraping.KeyPool[apiKey]
 }
 
 type apiKey struct {
@@ -54,22 +50,19 @@ type apiKey struct {
 // Run function returns all subdomains found with the service
 func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Session) <-chan subscraping.Result {
 	results := make(chan subscraping.Result)
-	s.errors = 0
-	s.results = 0
 
 	go func() {
-		defer func(startTime time.Time) {
-			s.timeTaken = time.Since(startTime)
-			close(results)
-		}(time.Now())
+		defer close(results)
 
-		randomApiKey := subscraping.PickRandom(s.apiKeys, s.Name())
+		randomApiKey, ok := subscraping.PickKey(s.keys, s.Name(), session, results)
+		if !ok {
+			return
+		}
 		if randomApiKey.host == "" || randomApiKey.key == "" {
-			s.skipped = true
+			session.Statistics.Skip(s.Name())
 			return
 		}
 
-		searchURL := fmt.Sprintf("https://%s/phonebook/search?k=%s", randomApiKey.host, randomApiKey.key)
 		reqBody := requestBody{
 			Term:       domain,
 			Maxresults: 100000,
@@ -81,14 +74,16 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 		body, err := json.Marshal(reqBody)
 		if err != nil {
 			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-			s.errors++
 			return
 		}
 
-		resp, err := session.SimplePost(ctx, searchURL, "application/json", bytes.NewBuffer(body))
+		// The search is bound to the key it was started with, only its start rotates keys
+		resp, err := s.keys.Do(ctx, session, s.Name(), &randomApiKey, func(ctx context.Context, randomApiKey apiKey) (*http.Response, error) {
+			searchURL := fmt.Sprintf("https://%s/phonebook/search?k=%s", randomApiKey.host, randomApiKey.key)
+			return session.SimplePost(ctx, searchURL, "application/json", bytes.NewBuffer(body))
+		})
 		if err != nil {
 			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-			s.errors++
 			session.DiscardHTTPResponse(resp)
 			return
 		}
@@ -97,7 +92,6 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 		err = jsoniter.NewDecoder(resp.Body).Decode(&response)
 		if err != nil {
 			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-			s.errors++
 			resp.Body.Close()
 			return
 		}
@@ -110,7 +104,6 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 			resp, err = session.Get(ctx, resultsURL, "", nil)
 			if err != nil {
 				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-				s.errors++
 				session.DiscardHTTPResponse(resp)
 				return
 			}
@@ -118,7 +111,6 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 			err = jsoniter.NewDecoder(resp.Body).Decode(&response)
 			if err != nil {
 				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-				s.errors++
 				resp.Body.Close()
 				return
 			}
@@ -126,7 +118,6 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 			_, err = io.ReadAll(resp.Body)
 			if err != nil {
 				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-				s.errors++
 				resp.Body.Close()
 				return
 			}
@@ -137,7 +128,6 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 				results <- subscraping.Result{
 					Source: s.Name(), Type: subscraping.Subdomain, Value: hostname.Selectvalue,
 				}
-				s.results++
 			}
 		}
 	}()
@@ -163,16 +153,27 @@ func (s *Source) NeedsKey() bool {
 }
 
 func (s *Source) AddApiKeys(keys []string) {
-	s.apiKeys = subscraping.CreateApiKeys(keys, func(k, v string) apiKey {
+	s.keys = subscraping.NewKeyPool(subscraping.CreateApiKeys(keys, func(k, v string) apiKey {
 		return apiKey{k, v}
+	}), func(key apiKey) string {
+		return key.host + ":" + key.key
 	})
 }
 
-func (s *Source) Statistics() subscraping.Statistics {
-	return subscraping.Statistics{
-		Errors:    s.errors,
-		Results:   s.results,
-		TimeTaken: s.timeTaken,
-		Skipped:   s.skipped,
-	}
+type authenticateInfoResponse struct {
+	Paths map[string]struct {
+		Credit int `json:"Credit"`
+	} `json:"paths"`
+}
+
+// CheckKeys checks the keys against the authenticate info endpoint of their
+// host, the remaining credits are the phonebook searches left
+func (s *Source) CheckKeys(ctx context.Context, session *subscraping.Session) []subscraping.KeyCheck {
+	return subscraping.CheckKeys(s.keys, func(apiKey apiKey) (*http.Response, error) {
+		return session.Get(ctx, fmt.Sprintf("https://%s/authenticate/info", apiKey.host), "", map[string]string{"x-key": apiKey.key})
+	}, func(body []byte) (int, error) {
+		var info authenticateInfoResponse
+		err := jsoniter.Unmarshal(body, &info)
+		return info.Paths["/phonebook/search"].Credit, err
+	})
 }
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
@@ -12,44 +13,42 @@ import (
 
 // Source is the passive scraping agent
 type Source struct {
-	apiKeys   []string
-	timeTaken time.Duration
-	errors    int
-	results   int
-	skipped   bool
+	keys *subscraping.KeyPool[string]
 }
 
 // Run function returns all subdomains found with the service
 func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Session) <-chan subscraping.Result {
 	results := make(chan subscraping.Result)
-	s.errors = 0
-	s.results = 0
 
 	go func() {
-		defer func(startTime time.Time) {
-			s.timeTaken = time.Since(startTime)
-			close(results)
-		}(time.Now())
-		// Default headers
-		headers := map[string]string{
-			"accept": "application/json",
-		}
-		// Pick an API key
-		randomApiKey := subscraping.PickRandom(s.apiKeys, s.Name())
-		if randomApiKey != "" {
-			headers["api-key"] = randomApiKey
+		defer close(results)
+		// Pick an API key, the API can also be used without one
+		randomApiKey, ok := s.keys.Pick()
+		request := func(ctx context.Context, apiKey string) (*http.Response, error) {
+			// Default headers
+			headers := map[string]string{
+				"accept": "application/json",
+			}
+			if apiKey != "" {
+				headers["api-key"] = apiKey
+			}
+			return session.Get(ctx, "https://leakix.net/api/subdomains/"+domain, "", headers)
 		}
 		// Request
-		resp, err := session.Get(ctx, "https://leakix.net/api/subdomains/"+domain, "", headers)
+		var resp *http.Response
+		var err error
+		if ok {
+			resp, err = s.keys.Do(ctx, session, s.Name(), &randomApiKey, request)
+		} else {
+			resp, err = request(ctx, "")
+		}
 		if err != nil {
 			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-			s.errors++
 			return
 		}
 		defer resp.Body.Close()
 		if resp.StatusCode != 200 {
 			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: fmt.Errorf("request failed with status %d", resp.StatusCode)}
-			s.errors++
 			return
 		}
 		// Parse and return results
@@ -58,14 +57,12 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 		err = decoder.Decode(&subdomains)
 		if err != nil {
 			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-			s.errors++
 			return
 		}
 		for _, result := range subdomains {
 			results <- subscraping.Result{
 				Source: s.Name(), Type: subscraping.Subdomain, Value: result.Subdomain,
 			}
-			s.results++
 		}
 	}()
 	return results
@@ -89,16 +86,7 @@ func (s *Source) NeedsKey() bool {
 }
 
 func (s *Source) AddApiKeys(keys []string) {
-	s.apiKeys = keys
-}
-
-func (s *Source) Statistics() subscraping.Statistics {
-	return subscraping.Statistics{
-		Errors:    s.errors,
-		Results:   s.results,
-		TimeTaken: s.timeTaken,
-		Skipped:   s.skipped,
-	}
+	s.keys = subscraping.NewStringKeyPool(keys)
 }
 
 type subResponse struct {
@@ -106,3 +94,8 @@ type subResponse struct {
 	DistinctIps int       `json:"distinct_ips"`
 	LastSeen    time.Time `json:"last_seen"`
 }
+
+// KeyCheckUnsupported tells why the keys cannot be checked
+func (s *Source) KeyCheckUnsupported() string {
+	return "the LeakIX API has no account or quota endpoint"
+}
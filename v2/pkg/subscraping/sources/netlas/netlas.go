@@ -10,7 +10,6 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
-	"time"
 
 	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
 )
@@ -41,23 +40,28 @@ type DomainsCountResponse struct {
 
 // Source is the passive scraping agent
 type Source struct {
-	apiKeys   []string
-	timeTaken time.Duration
-	errors    int
-	results   int
-	skipped   bool
+	keys *subscraping.KeyPool[string]
 }
 
 func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Session) <-chan subscraping.Result {
 	results := make(chan subscraping.Result)
-	s.errors = 0
-	s.results = 0
 
 	go func() {
-		defer func(startTime time.Time) {
-			s.timeTaken = time.Since(startTime)
-			close(results)
-		}(time.Now())
+		defer close(results)
+
+		// Pick an API key
+		randomApiKey, ok := subscraping.PickKey(s.keys, s.Name(), session, results)
+		if !ok {
+			return
+		}
+		get := func(reqURL string) (*http.Response, error) {
+			return s.keys.Do(ctx, session, s.Name(), &randomApiKey, func(ctx context.Context, apiKey string) (*http.Response, error) {
+				return session.HTTPRequest(ctx, http.MethodGet, reqURL, "", map[string]string{
+					"accept":    "application/json",
+					"X-API-Key": apiKey,
+				}, nil, subscraping.BasicAuth{})
+			})
+		}
 
 		// To get count of domains
 		endpoint := "https://app.netlas.io/api/domains_count/"
@@ -66,20 +70,13 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 		params.Set("q", countQuery)
 		countUrl := endpoint + "?" + params.Encode()
 
-		// Pick an API key
-		randomApiKey := subscraping.PickRandom(s.apiKeys, s.Name())
-		resp, err := session.HTTPRequest(ctx, http.MethodGet, countUrl, "", map[string]string{
-			"accept":    "application/json",
-			"X-API-Key": randomApiKey,
-		}, nil, subscraping.BasicAuth{})
+		resp, err := get(countUrl)
 
 		if err != nil {
 			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-			s.errors++
 			return
 		} else if resp.StatusCode != 200 {
 			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: fmt.Errorf("request rate limited with status code %d", resp.StatusCode)}
-			s.errors++
 			return
 		}
 		defer resp.Body.Close()
@@ -87,7 +84,6 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
 			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: fmt.Errorf("error reading ressponse body")}
-			s.errors++
 			return
 		}
 
@@ -96,7 +92,6 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 		err = json.Unmarshal(body, &domainsCount)
 		if err != nil {
 			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-			s.errors++
 			return
 		}
 
@@ -114,28 +109,20 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 			params.Set("fields", "*")
 			apiUrl := endpoint + "?" + params.Encode()
 
-			// Pick an API key
-			randomApiKey := subscraping.PickRandom(s.apiKeys, s.Name())
-
-			resp, err := session.HTTPRequest(ctx, http.MethodGet, apiUrl, "", map[string]string{
-				"accept":    "application/json",
-				"X-API-Key": randomApiKey}, nil, subscraping.BasicAuth{})
+			resp, err := get(apiUrl)
 			if err != nil {
 				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-				s.errors++
 				return
 			}
 			defer resp.Body.Close()
 			body, err := io.ReadAll(resp.Body)
 			if err != nil {
 				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: fmt.Errorf("error reading ressponse body")}
-				s.errors++
 				return
 			}
 
 			if resp.StatusCode == 429 {
 				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: fmt.Errorf("request rate limited with status code %d", resp.StatusCode)}
-				s.errors++
 				break
 			}
 
@@ -144,7 +131,6 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 			err = json.Unmarshal(body, &data)
 			if err != nil {
 				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-				s.errors++
 				return
 			}
 
@@ -152,7 +138,6 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 				results <- subscraping.Result{
 					Source: s.Name(), Type: subscraping.Subdomain, Value: item.Data.Domain,
 				}
-				s.results++
 			}
 		}
 
@@ -179,14 +164,15 @@ func (s *Source) NeedsKey() bool {
 }
 
 func (s *Source) AddApiKeys(keys []string) {
-	s.apiKeys = keys
+	s.keys = subscraping.NewStringKeyPool(keys)
 }
 
-func (s *Source) Statistics() subscraping.Statistics {
-	return subscraping.Statistics{
-		Errors:    s.errors,
-		Results:   s.results,
-		TimeTaken: s.timeTaken,
-		Skipped:   s.skipped,
-	}
+// CheckKeys checks the keys against the endpoint of the current user
+func (s *Source) CheckKeys(ctx context.Context, session *subscraping.Session) []subscraping.KeyCheck {
+	return subscraping.CheckKeys(s.keys, func(apiKey string) (*http.Response, error) {
+		return session.Get(ctx, "https://app.netlas.io/api/users/current/", "", map[string]string{
+			"accept":    "application/json",
+			"X-API-Key": apiKey,
+		})
+	}, nil)
 }
@@ -4,8 +4,8 @@ package passivetotal
 import (
 	"bytes"
 	"context"
+	"net/http"
 	"regexp"
-	"time"
 
 	jsoniter "github.com/json-iterator/go"
 
@@ -20,11 +20,7 @@ type response struct {
 
 // Source is the passive scraping agent
 type Source struct {
-	apiKeys   []apiKey
-	timeTaken time.Duration
-	errors    int
-	results   int
-	skipped   bool
+	keys *subscraping.KeyPool[apiKey]
 }
 
 type apiKey struct {
@@ -35,36 +31,35 @@ type apiKey struct {
 // Run function returns all subdomains found with the service
 func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Session) <-chan subscraping.Result {
 	results := make(chan subscraping.Result)
-	s.errors = 0
-	s.results = 0
 
 	go func() {
-		defer func(startTime time.Time) {
-			s.timeTaken = time.Since(startTime)
-			close(results)
-		}(time.Now())
+		defer close(results)
 
-		randomApiKey := subscraping.PickRandom(s.apiKeys, s.Name())
+		randomApiKey, ok := subscraping.PickKey(s.keys, s.Name(), session, results)
+		if !ok {
+			return
+		}
 		if randomApiKey.username == "" || randomApiKey.password == "" {
-			s.skipped = true
+			session.Statistics.Skip(s.Name())
 			return
 		}
 
 		// Create JSON Get body
 		var request = []byte(`{"query":"` + domain + `"}`)
 
-		resp, err := session.HTTPRequest(
-			ctx,
-			"GET",
-			"https://api.passivetotal.org/v2/enrichment/subdomains",
-			"",
-			map[string]string{"Content-Type": "application/json"},
-			bytes.NewBuffer(request),
-			subscraping.BasicAuth{Username: randomApiKey.username, Password: randomApiKey.password},
-		)
+		resp, err := s.keys.Do(ctx, session, s.Name(), &randomApiKey, func(ctx context.Context, randomApiKey apiKey) (*http.Response, error) {
+			return session.HTTPRequest(
+				ctx,
+				"GET",
+				"https://api.passivetotal.org/v2/enrichment/subdomains",
+				"",
+				map[string]string{"Content-Type": "application/json"},
+				bytes.NewBuffer(request),
+				subscraping.BasicAuth{Username: randomApiKey.username, Password: randomApiKey.password},
+			)
+		})
 		if err != nil {
 			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-			s.errors++
 			session.DiscardHTTPResponse(resp)
 			return
 		}
@@ -73,7 +68,6 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 		err = jsoniter.NewDecoder(resp.Body).Decode(&data)
 		if err != nil {
 			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-			s.errors++
 			resp.Body.Close()
 			return
 		}
@@ -86,7 +80,6 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 			}
 			finalSubdomain := subdomain + "." + domain
 			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Subdomain, Value: finalSubdomain}
-			s.results++
 		}
 	}()
 
@@ -111,16 +104,32 @@ func (s *Source) NeedsKey() bool {
 }
 
 func (s *Source) AddApiKeys(keys []string) {
-	s.apiKeys = subscraping.CreateApiKeys(keys, func(k, v string) apiKey {
+	s.keys = subscraping.NewKeyPool(subscraping.CreateApiKeys(keys, func(k, v string) apiKey {
 		return apiKey{k, v}
+	}), func(key apiKey) string {
+		return key.username + ":" + key.password
 	})
 }
 
-func (s *Source) Statistics() subscraping.Statistics {
-	return subscraping.Statistics{
-		Errors:    s.errors,
-		Results:   s.results,
-		TimeTaken: s.timeTaken,
-		Skipped:   s.skipped,
-	}
+type quotaResponse struct {
+	User struct {
+		Counts struct {
+			SearchAPI int `json:"search_api"`
+		} `json:"counts"`
+		Limits struct {
+			SearchAPI int `json:"search_api"`
+		} `json:"limits"`
+	} `json:"user"`
+}
+
+// CheckKeys checks the keys against the quota endpoint of the account
+func (s *Source) CheckKeys(ctx context.Context, session *subscraping.Session) []subscraping.KeyCheck {
+	return subscraping.CheckKeys(s.keys, func(apiKey apiKey) (*http.Response, error) {
+		return session.HTTPRequest(ctx, http.MethodGet, "https://api.passivetotal.org/v2/account/quota", "", nil, nil,
+			subscraping.BasicAuth{Username: apiKey.username, Password: apiKey.password})
+	}, func(body []byte) (int, error) {
+		var quota quotaResponse
+		err := jsoniter.Unmarshal(body, &quota)
+		return quota.User.Limits.SearchAPI - quota.User.Counts.SearchAPI, err
+	})
 }
@@ -0,0 +1,81 @@
+package plugin
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
+)
+
+// Inputs through which the domain and API key are passed to a plugin
+const (
+	InputStdin = "stdin"
+	InputEnv   = "env"
+)
+
+// Definition declares a source running an executable for each domain. The
+// executable reads its request on stdin or from the environment and writes
+// JSON lines such as {"type":"subdomain","value":"www.example.com"} or
+// {"type":"error","error":"quota exceeded"} to stdout. An error with a
+// "key-status" of invalid or exhausted tells that the provider refused the
+// key, the plugin is then run again with the next key of the provider config.
+type Definition struct {
+	// Name of the source, used by -s, -es and in the provider config
+	Name string `yaml:"name"`
+	// Default makes the source part of the default sources
+	Default bool `yaml:"default"`
+	// Recursive tells that the plugin accepts subdomains, not just root domains
+	Recursive bool `yaml:"recursive"`
+	// NeedsKey tells that the plugin is only run with a key of the provider config
+	NeedsKey bool `yaml:"needs-key"`
+	// Command is the executable, looked up in PATH unless it is a path,
+	// which is then relative to the directory of the definition
+	Command string `yaml:"command"`
+	// Args are the arguments of the command, where {{domain}} is replaced by the domain
+	Args []string `yaml:"args"`
+	// Env holds variables added to the environment of the command
+	Env map[string]string `yaml:"env"`
+	// Input is stdin to write the domain and key as a JSON line, or env to
+	// pass the key in SUBFINDER_API_KEY. It defaults to stdin, the domain is
+	// always in SUBFINDER_DOMAIN too.
+	Input string `yaml:"input"`
+}
+
+// LoadDefinitions creates the sources defined by the YAML files of a
+// directory, one definition per file. A missing directory has no sources.
+func LoadDefinitions(directory string) ([]*Source, error) {
+	var sources []*Source
+	err := subscraping.LoadDefinitions(directory, "plugin", func(definition Definition) error {
+		// Plugins shipped next to their definition don't need to be in PATH
+		if strings.ContainsRune(definition.Command, filepath.Separator) && !filepath.IsAbs(definition.Command) {
+			definition.Command = filepath.Join(directory, definition.Command)
+		}
+		source, err := New(definition)
+		if err != nil {
+			return err
+		}
+		sources = append(sources, source)
+		return nil
+	})
+	return sources, err
+}
+
+// validate checks a definition and fills its defaults
+func (d *Definition) validate() error {
+	d.Name = strings.ToLower(d.Name)
+	if err := subscraping.ValidateSourceName(d.Name); err != nil {
+		return err
+	}
+	if d.Command == "" {
+		return fmt.Errorf("no command")
+	}
+	switch d.Input {
+	case "":
+		d.Input = InputStdin
+	case InputStdin, InputEnv:
+	default:
+		return fmt.Errorf("unknown input %q, expected stdin or env", d.Input)
+	}
+	return nil
+}
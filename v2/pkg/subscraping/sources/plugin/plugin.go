@@ -0,0 +1,221 @@
+// Package plugin logic
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
+)
+
+const (
+	// maxLineSize is the longest line a plugin can write
+	maxLineSize = 1024 * 1024
+	// maxStderrSize is the part of the stderr of a plugin kept for its errors
+	maxStderrSize = 1024
+	// waitDelay is how long the output of a killed plugin is waited for,
+	// as its own children may keep it open
+	waitDelay = 5 * time.Second
+)
+
+// request is written as a JSON line on the stdin of the plugins
+type request struct {
+	Domain string `json:"domain"`
+	Key    string `json:"key,omitempty"`
+}
+
+// message is a JSON line written by the plugins on their stdout
+type message struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+	Error string `json:"error"`
+	// KeyStatus of an error is invalid or exhausted when the provider refused the key
+	KeyStatus subscraping.KeyStatus `json:"key-status"`
+}
+
+// Source is a passive scraping agent running an external executable
+type Source struct {
+	definition Definition
+	keys       *subscraping.KeyPool[string]
+}
+
+// New creates the source of a definition
+func New(definition Definition) (*Source, error) {
+	if err := definition.validate(); err != nil {
+		return nil, err
+	}
+	return &Source{definition: definition}, nil
+}
+
+// Run function returns all subdomains found with the service
+func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Session) <-chan subscraping.Result {
+	results := make(chan subscraping.Result)
+
+	go func() {
+		defer close(results)
+
+		var err error
+		if s.NeedsKey() {
+			apiKey, ok := subscraping.PickKey(s.keys, s.Name(), session, results)
+			if !ok {
+				return
+			}
+			// The plugin is run again with the next key when it reports its key refused
+			err = s.keys.Run(ctx, session, s.Name(), &apiKey, func(ctx context.Context, apiKey string) (subscraping.KeyStatus, error) {
+				return s.run(ctx, domain, apiKey, results)
+			})
+		} else {
+			_, err = s.run(ctx, domain, "", results)
+		}
+		if err != nil {
+			// A plugin killed by the cancellation of the enumeration didn't fail
+			if ctx.Err() != nil {
+				return
+			}
+			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
+		}
+	}()
+
+	return results
+}
+
+// run executes the plugin for a domain and sends the results it writes. The
+// plugin is killed when the context is done. The status of the key is
+// returned along with its error when the plugin reported it refused.
+func (s *Source) run(ctx context.Context, domain, apiKey string, results chan subscraping.Result) (subscraping.KeyStatus, error) {
+	args := make([]string, len(s.definition.Args))
+	for i, arg := range s.definition.Args {
+		args[i] = strings.ReplaceAll(arg, "{{domain}}", domain)
+	}
+
+	cmd := exec.CommandContext(ctx, s.definition.Command, args...)
+	cmd.WaitDelay = waitDelay
+	cmd.Env = append(os.Environ(), "SUBFINDER_DOMAIN="+domain)
+	for name, value := range s.definition.Env {
+		cmd.Env = append(cmd.Env, name+"="+value)
+	}
+	switch s.definition.Input {
+	case InputStdin:
+		line, err := jsoniter.Marshal(request{Domain: domain, Key: apiKey})
+		if err != nil {
+			return "", err
+		}
+		cmd.Stdin = bytes.NewReader(append(line, '\n'))
+	case InputEnv:
+		if apiKey != "" {
+			cmd.Env = append(cmd.Env, "SUBFINDER_API_KEY="+apiKey)
+		}
+	}
+	stderr := &cappedBuffer{limit: maxStderrSize}
+	cmd.Stderr = stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("could not start plugin: %w", err)
+	}
+
+	var keyStatus subscraping.KeyStatus
+	var keyErr error
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var msg message
+		if err := jsoniter.Unmarshal(line, &msg); err != nil {
+			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: fmt.Errorf("invalid plugin output %q: %w", line, err)}
+			continue
+		}
+		switch msg.Type {
+		case "subdomain":
+			if msg.Value != "" {
+				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Subdomain, Value: msg.Value}
+			}
+		case "error":
+			// A refused key is only reported when no other key is left to run the plugin with
+			if msg.KeyStatus == subscraping.KeyInvalid || msg.KeyStatus == subscraping.KeyExhausted {
+				keyStatus, keyErr = msg.KeyStatus, errors.New(msg.Error)
+				continue
+			}
+			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: errors.New(msg.Error)}
+		default:
+			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: fmt.Errorf("unknown plugin output type %q", msg.Type)}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		// Nothing reads the rest of the output, the plugin would block writing it
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return "", fmt.Errorf("could not read plugin output: %w", err)
+	}
+
+	err = cmd.Wait()
+	if keyStatus != "" {
+		return keyStatus, keyErr
+	}
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("plugin failed: %w: %s", err, message)
+		}
+		return "", fmt.Errorf("plugin failed: %w", err)
+	}
+	return "", nil
+}
+
+// cappedBuffer keeps the first bytes written to it up to its limit
+type cappedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); room > 0 {
+		if len(p) > room {
+			b.Buffer.Write(p[:room])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+// Name returns the name of the source
+func (s *Source) Name() string {
+	return s.definition.Name
+}
+
+func (s *Source) IsDefault() bool {
+	return s.definition.Default
+}
+
+func (s *Source) HasRecursiveSupport() bool {
+	return s.definition.Recursive
+}
+
+func (s *Source) NeedsKey() bool {
+	return s.definition.NeedsKey
+}
+
+func (s *Source) AddApiKeys(keys []string) {
+	s.keys = subscraping.NewStringKeyPool(keys)
+}
+
+// KeyCheckUnsupported tells why the keys cannot be checked
+func (s *Source) KeyCheckUnsupported() string {
+	return "the keys are only passed to the plugin command, the plugin protocol has no key check"
+}
@@ -0,0 +1,203 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
+	"github.com/projectdiscovery/subfinder/v2/pkg/testutils"
+)
+
+// TestHelperPlugin is not a test but the plugin run by the tests, as the test binary
+func TestHelperPlugin(t *testing.T) {
+	mode := os.Getenv("PLUGIN_TEST_MODE")
+	if mode == "" {
+		t.Skip("only run as a plugin")
+	}
+
+	switch mode {
+	case "stdin":
+		line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		var req request
+		if err := jsoniter.UnmarshalFromString(line, &req); err != nil {
+			fmt.Printf(`{"type":"error","error":%q}`+"\n", err.Error())
+			os.Exit(0)
+		}
+		fmt.Printf(`{"type":"subdomain","value":"www.%s"}`+"\n", req.Domain)
+		fmt.Printf(`{"type":"subdomain","value":"%s.%s"}`+"\n\n", req.Key, req.Domain)
+		fmt.Println(`{"type":"error","error":"quota exceeded"}`)
+		fmt.Println(`not json`)
+	case "env":
+		fmt.Printf(`{"type":"subdomain","value":"%s.%s"}`+"\n", os.Getenv("SUBFINDER_API_KEY"), os.Getenv("SUBFINDER_DOMAIN"))
+	case "fail":
+		fmt.Println(`{"type":"subdomain","value":"partial.example.com"}`)
+		fmt.Fprintln(os.Stderr, "unexpected response")
+		os.Exit(3)
+	case "oversized":
+		fmt.Println(`{"type":"subdomain","value":"first.example.com"}`)
+		// A line over the limit followed by more output than a pipe holds
+		fmt.Println(strings.Repeat("a", 2*maxLineSize))
+		for i := 0; i < 10000; i++ {
+			fmt.Printf(`{"type":"subdomain","value":"%d.example.com"}`+"\n", i)
+		}
+	case "rotate":
+		if key := os.Getenv("SUBFINDER_API_KEY"); key != "good" {
+			fmt.Printf(`{"type":"error","error":"key %s refused","key-status":"invalid"}`+"\n", key)
+			os.Exit(1)
+		}
+		fmt.Printf(`{"type":"subdomain","value":"good.%s"}`+"\n", os.Getenv("SUBFINDER_DOMAIN"))
+	case "hang":
+		fmt.Println(`{"type":"subdomain","value":"slow.example.com"}`)
+		time.Sleep(time.Minute)
+	}
+	os.Exit(0)
+}
+
+func newHelperSource(t *testing.T, mode, input string, needsKey bool) *Source {
+	t.Helper()
+	source, err := New(Definition{
+		Name:     "helper",
+		NeedsKey: needsKey,
+		Command:  os.Args[0],
+		Args:     []string{"-test.run=^TestHelperPlugin$"},
+		Env:      map[string]string{"PLUGIN_TEST_MODE": mode},
+		Input:    input,
+	})
+	require.NoError(t, err)
+	return source
+}
+
+func TestPluginStdinInput(t *testing.T) {
+	source := newHelperSource(t, "stdin", "", true)
+	source.AddApiKeys([]string{"secret"})
+
+	subdomains, errs := testutils.RunSource(context.Background(), t, source, "example.com")
+	require.Equal(t, []string{"secret.example.com", "www.example.com"}, subdomains)
+	require.Len(t, errs, 2)
+	require.EqualError(t, errs[0], "quota exceeded")
+	require.ErrorContains(t, errs[1], "invalid plugin output")
+}
+
+func TestPluginEnvInput(t *testing.T) {
+	source := newHelperSource(t, "env", InputEnv, true)
+	source.AddApiKeys([]string{"secret"})
+
+	subdomains, errs := testutils.RunSource(context.Background(), t, source, "example.com")
+	require.Empty(t, errs)
+	require.Equal(t, []string{"secret.example.com"}, subdomains)
+}
+
+func TestPluginRotatesRefusedKeys(t *testing.T) {
+	source := newHelperSource(t, "rotate", InputEnv, true)
+	source.AddApiKeys([]string{"bad", "good"})
+
+	subdomains, errs := testutils.RunSource(context.Background(), t, source, "example.com")
+	require.Empty(t, errs, "the refused key is replaced by the next one")
+	require.Equal(t, []string{"good.example.com"}, subdomains)
+
+	// The refused key stays benched, no key is left for the next run
+	source.AddApiKeys([]string{"bad"})
+	subdomains, errs = testutils.RunSource(context.Background(), t, source, "example.com")
+	require.Empty(t, subdomains)
+	require.Len(t, errs, 1)
+	require.EqualError(t, errs[0], "key bad refused")
+
+	subdomains, errs = testutils.RunSource(context.Background(), t, source, "example.com")
+	require.Empty(t, subdomains)
+	require.Len(t, errs, 1)
+	require.ErrorIs(t, errs[0], subscraping.ErrKeysExhausted)
+}
+
+func TestPluginWithoutKeyIsSkipped(t *testing.T) {
+	source := newHelperSource(t, "env", InputEnv, true)
+
+	subdomains, errs := testutils.RunSource(context.Background(), t, source, "example.com")
+	require.Empty(t, errs)
+	require.Empty(t, subdomains)
+}
+
+func TestPluginFailure(t *testing.T) {
+	source := newHelperSource(t, "fail", "", false)
+
+	subdomains, errs := testutils.RunSource(context.Background(), t, source, "example.com")
+	require.Equal(t, []string{"partial.example.com"}, subdomains)
+	require.Len(t, errs, 1)
+	require.ErrorContains(t, errs[0], "exit status 3")
+	require.ErrorContains(t, errs[0], "unexpected response")
+}
+
+func TestPluginOversizedLine(t *testing.T) {
+	source := newHelperSource(t, "oversized", "", false)
+
+	done := make(chan struct{})
+	var subdomains []string
+	var errs []error
+	go func() {
+		defer close(done)
+		subdomains, errs = testutils.RunSource(context.Background(), t, source, "example.com")
+	}()
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("the plugin writing past an oversized line must not hang")
+	}
+	require.Equal(t, []string{"first.example.com"}, subdomains)
+	require.Len(t, errs, 1)
+	require.ErrorIs(t, errs[0], bufio.ErrTooLong)
+}
+
+func TestPluginKilledOnCancellation(t *testing.T) {
+	source := newHelperSource(t, "hang", "", false)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	start := time.Now()
+	subdomains, errs := testutils.RunSource(ctx, t, source, "example.com")
+	require.Less(t, time.Since(start), 30*time.Second)
+	require.Equal(t, []string{"slow.example.com"}, subdomains)
+	require.Empty(t, errs, "a cancelled plugin must not report an error")
+}
+
+func TestLoadDefinitions(t *testing.T) {
+	directory := t.TempDir()
+	definition := `
+name: Enrich
+default: true
+command: ./enrich.py
+args: ["--domain", "{{domain}}"]
+input: env
+`
+	require.NoError(t, os.WriteFile(filepath.Join(directory, "enrich.yaml"), []byte(definition), 0600))
+
+	sources, err := LoadDefinitions(directory)
+	require.NoError(t, err)
+	require.Len(t, sources, 1)
+	require.Equal(t, "enrich", sources[0].Name())
+	require.True(t, sources[0].IsDefault())
+	require.False(t, sources[0].NeedsKey())
+	require.Equal(t, filepath.Join(directory, "enrich.py"), sources[0].definition.Command)
+
+	sources, err = LoadDefinitions(filepath.Join(directory, "missing"))
+	require.NoError(t, err)
+	require.Empty(t, sources)
+
+	for name, invalid := range map[string]string{
+		"no command": "name: broken\n",
+		"bad input":  "name: broken\ncommand: broken\ninput: file\n",
+		"bad name":   "name: bro ken\ncommand: broken\n",
+		"reserved":   "name: budgets\ncommand: broken\n",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(directory, "enrich.yaml"), []byte(invalid), 0600))
+		_, err := LoadDefinitions(directory)
+		require.Error(t, err, name)
+	}
+}
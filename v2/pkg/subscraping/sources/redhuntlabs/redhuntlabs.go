@@ -4,8 +4,8 @@ package redhuntlabs
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strings"
-	"time"
 
 	jsoniter "github.com/json-iterator/go"
 
@@ -24,43 +24,40 @@ type ResponseMetadata struct {
 }
 
 type Source struct {
-	apiKeys   []string
-	timeTaken time.Duration
-	errors    int
-	results   int
-	skipped   bool
+	keys *subscraping.KeyPool[string]
 }
 
 func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Session) <-chan subscraping.Result {
 	results := make(chan subscraping.Result)
-	s.errors = 0
-	s.results = 0
 	pageSize := 1000
 	go func() {
-		defer func(startTime time.Time) {
-			s.timeTaken = time.Since(startTime)
-			close(results)
-		}(time.Now())
-
-		randomApiKey := subscraping.PickRandom(s.apiKeys, s.Name())
-		if randomApiKey == "" || !strings.Contains(randomApiKey, ":") {
-			s.skipped = true
+		defer close(results)
+
+		randomApiKey, ok := subscraping.PickKey(s.keys, s.Name(), session, results)
+		if !ok {
 			return
 		}
 
-		randomApiInfo := strings.Split(randomApiKey, ":")
-		if len(randomApiInfo) != 3 {
-			s.skipped = true
+		if len(strings.Split(randomApiKey, ":")) != 3 {
+			session.Statistics.Skip(s.Name())
 			return
 		}
-		baseUrl := randomApiInfo[0] + ":" + randomApiInfo[1]
-		requestHeaders := map[string]string{"X-BLOBR-KEY": randomApiInfo[2], "User-Agent": "subfinder"}
-		getUrl := fmt.Sprintf("%s?domain=%s&page=1&page_size=%d", baseUrl, domain, pageSize)
-		resp, err := session.Get(ctx, getUrl, "", requestHeaders)
+		getPage := func(page int) (*http.Response, error) {
+			return s.keys.Do(ctx, session, s.Name(), &randomApiKey, func(ctx context.Context, randomApiKey string) (*http.Response, error) {
+				randomApiInfo := strings.Split(randomApiKey, ":")
+				if len(randomApiInfo) != 3 {
+					return nil, fmt.Errorf("invalid key format")
+				}
+				baseUrl := randomApiInfo[0] + ":" + randomApiInfo[1]
+				requestHeaders := map[string]string{"X-BLOBR-KEY": randomApiInfo[2], "User-Agent": "subfinder"}
+				getUrl := fmt.Sprintf("%s?domain=%s&page=%d&page_size=%d", baseUrl, domain, page, pageSize)
+				return session.Get(ctx, getUrl, "", requestHeaders)
+			})
+		}
+		resp, err := getPage(1)
 		if err != nil {
 			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: fmt.Errorf("encountered error: %v; note: if you get a 'limit has been reached' error, head over to https://devportal.redhuntlabs.com", err)}
 			session.DiscardHTTPResponse(resp)
-			s.errors++
 			return
 		}
 		var response Response
@@ -68,7 +65,6 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 		if err != nil {
 			results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
 			resp.Body.Close()
-			s.errors++
 			return
 		}
 
@@ -76,12 +72,10 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 		if response.Metadata.ResultCount > pageSize {
 			totalPages := (response.Metadata.ResultCount + pageSize - 1) / pageSize
 			for page := 1; page <= totalPages; page++ {
-				getUrl = fmt.Sprintf("%s?domain=%s&page=%d&page_size=%d", baseUrl, domain, page, pageSize)
-				resp, err := session.Get(ctx, getUrl, "", requestHeaders)
+				resp, err := getPage(page)
 				if err != nil {
 					results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: fmt.Errorf("encountered error: %v; note: if you get a 'limit has been reached' error, head over to https://devportal.redhuntlabs.com", err)}
 					session.DiscardHTTPResponse(resp)
-					s.errors++
 					return
 				}
 
@@ -89,7 +83,6 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 				if err != nil {
 					results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
 					resp.Body.Close()
-					s.errors++
 					continue
 				}
 
@@ -97,13 +90,11 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 
 				for _, subdomain := range response.Subdomains {
 					results <- subscraping.Result{Source: s.Name(), Type: subscraping.Subdomain, Value: subdomain}
-					s.results++
 				}
 			}
 		} else {
 			for _, subdomain := range response.Subdomains {
 				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Subdomain, Value: subdomain}
-				s.results++
 			}
 		}
 
@@ -128,14 +119,10 @@ func (s *Source) NeedsKey() bool {
 }
 
 func (s *Source) AddApiKeys(keys []string) {
-	s.apiKeys = keys
+	s.keys = subscraping.NewStringKeyPool(keys)
 }
 
-func (s *Source) Statistics() subscraping.Statistics {
-	return subscraping.Statistics{
-		Errors:    s.errors,
-		Results:   s.results,
-		TimeTaken: s.timeTaken,
-		Skipped:   s.skipped,
-	}
+// KeyCheckUnsupported tells why the keys cannot be checked
+func (s *Source) KeyCheckUnsupported() string {
+	return "the RedHunt Labs API has no account or quota endpoint"
 }
@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
-	"time"
 
 	jsoniter "github.com/json-iterator/go"
 
@@ -27,33 +26,31 @@ type response struct {
 
 // Source is the passive scraping agent
 type Source struct {
-	apiKeys   []string
-	timeTaken time.Duration
-	errors    int
-	results   int
-	skipped   bool
+	keys *subscraping.KeyPool[string]
 }
 
 // Run function returns all subdomains found with the service
 func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Session) <-chan subscraping.Result {
 	results := make(chan subscraping.Result)
-	s.errors = 0
-	s.results = 0
 
 	go func() {
-		defer func(startTime time.Time) {
-			s.timeTaken = time.Since(startTime)
-			close(results)
-		}(time.Now())
-
-		randomApiKey := subscraping.PickRandom(s.apiKeys, s.Name())
-		if randomApiKey == "" {
-			s.skipped = true
+		defer close(results)
+
+		randomApiKey, ok := subscraping.PickKey(s.keys, s.Name(), session, results)
+		if !ok {
 			return
 		}
 
 		var scrollId string
-		headers := map[string]string{"Content-Type": "application/json", "APIKEY": randomApiKey}
+		request := func(reqURL string, body []byte) (*http.Response, error) {
+			return s.keys.Do(ctx, session, s.Name(), &randomApiKey, func(ctx context.Context, apiKey string) (*http.Response, error) {
+				headers := map[string]string{"Content-Type": "application/json", "APIKEY": apiKey}
+				if body == nil {
+					return session.Get(ctx, reqURL, "", headers)
+				}
+				return session.Post(ctx, reqURL, "", headers, bytes.NewReader(body))
+			})
+		}
 
 		for {
 			var resp *http.Response
@@ -61,19 +58,17 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 
 			if scrollId == "" {
 				var requestBody = []byte(fmt.Sprintf(`{"query":"apex_domain='%s'"}`, domain))
-				resp, err = session.Post(ctx, "https://api.securitytrails.com/v1/domains/list?include_ips=false&scroll=true", "",
-					headers, bytes.NewReader(requestBody))
+				resp, err = request("https://api.securitytrails.com/v1/domains/list?include_ips=false&scroll=true", requestBody)
 			} else {
-				resp, err = session.Get(ctx, fmt.Sprintf("https://api.securitytrails.com/v1/scroll/%s", scrollId), "", headers)
+				resp, err = request(fmt.Sprintf("https://api.securitytrails.com/v1/scroll/%s", scrollId), nil)
 			}
 
 			if err != nil && ptr.Safe(resp).StatusCode == 403 {
-				resp, err = session.Get(ctx, fmt.Sprintf("https://api.securitytrails.com/v1/domain/%s/subdomains", domain), "", headers)
+				resp, err = request(fmt.Sprintf("https://api.securitytrails.com/v1/domain/%s/subdomains", domain), nil)
 			}
 
 			if err != nil {
 				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-				s.errors++
 				session.DiscardHTTPResponse(resp)
 				return
 			}
@@ -82,7 +77,6 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 			err = jsoniter.NewDecoder(resp.Body).Decode(&securityTrailsResponse)
 			if err != nil {
 				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-				s.errors++
 				resp.Body.Close()
 				return
 			}
@@ -91,7 +85,6 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 
 			for _, record := range securityTrailsResponse.Records {
 				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Subdomain, Value: record.Hostname}
-				s.results++
 			}
 
 			for _, subdomain := range securityTrailsResponse.Subdomains {
@@ -101,7 +94,6 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 					subdomain = subdomain + "." + domain
 				}
 				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Subdomain, Value: subdomain}
-				s.results++
 			}
 
 			scrollId = securityTrailsResponse.Meta.ScrollID
@@ -133,14 +125,21 @@ func (s *Source) NeedsKey() bool {
 }
 
 func (s *Source) AddApiKeys(keys []string) {
-	s.apiKeys = keys
+	s.keys = subscraping.NewStringKeyPool(keys)
+}
+
+type usageResponse struct {
+	CurrentMonthlyUsage int `json:"current_monthly_usage"`
+	AllowedMonthlyUsage int `json:"allowed_monthly_usage"`
 }
 
-func (s *Source) Statistics() subscraping.Statistics {
-	return subscraping.Statistics{
-		Errors:    s.errors,
-		Results:   s.results,
-		TimeTaken: s.timeTaken,
-		Skipped:   s.skipped,
-	}
+// CheckKeys checks the keys against the usage endpoint of the account
+func (s *Source) CheckKeys(ctx context.Context, session *subscraping.Session) []subscraping.KeyCheck {
+	return subscraping.CheckKeys(s.keys, func(apiKey string) (*http.Response, error) {
+		return session.Get(ctx, "https://api.securitytrails.com/v1/account/usage", "", map[string]string{"APIKEY": apiKey})
+	}, func(body []byte) (int, error) {
+		var usage usageResponse
+		err := jsoniter.Unmarshal(body, &usage)
+		return usage.AllowedMonthlyUsage - usage.CurrentMonthlyUsage, err
+	})
 }
@@ -4,7 +4,7 @@ package virustotal
 import (
 	"context"
 	"fmt"
-	"time"
+	"net/http"
 
 	jsoniter "github.com/json-iterator/go"
 
@@ -26,27 +26,18 @@ type Meta struct {
 
 // Source is the passive scraping agent
 type Source struct {
-	apiKeys   []string
-	timeTaken time.Duration
-	errors    int
-	results   int
-	skipped   bool
+	keys *subscraping.KeyPool[string]
 }
 
 // Run function returns all subdomains found with the service
 func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Session) <-chan subscraping.Result {
 	results := make(chan subscraping.Result)
-	s.errors = 0
-	s.results = 0
 
 	go func() {
-		defer func(startTime time.Time) {
-			s.timeTaken = time.Since(startTime)
-			close(results)
-		}(time.Now())
+		defer close(results)
 
-		randomApiKey := subscraping.PickRandom(s.apiKeys, s.Name())
-		if randomApiKey == "" {
+		randomApiKey, ok := subscraping.PickKey(s.keys, s.Name(), session, results)
+		if !ok {
 			return
 		}
 		var cursor string = ""
@@ -55,10 +46,11 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 			if cursor != "" {
 				url = fmt.Sprintf("%s&cursor=%s", url, cursor)
 			}
-			resp, err := session.Get(ctx, url, "", map[string]string{"x-apikey": randomApiKey})
+			resp, err := s.keys.Do(ctx, session, s.Name(), &randomApiKey, func(ctx context.Context, apiKey string) (*http.Response, error) {
+				return session.Get(ctx, url, "", map[string]string{"x-apikey": apiKey})
+			})
 			if err != nil {
 				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-				s.errors++
 				session.DiscardHTTPResponse(resp)
 				return
 			}
@@ -68,13 +60,11 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 			err = jsoniter.NewDecoder(resp.Body).Decode(&data)
 			if err != nil {
 				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-				s.errors++
 				return
 			}
 
 			for _, subdomain := range data.Data {
 				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Subdomain, Value: subdomain.Id}
-				s.results++
 			}
 			cursor = data.Meta.Cursor
 			if cursor == "" {
@@ -104,14 +94,28 @@ func (s *Source) NeedsKey() bool {
 }
 
 func (s *Source) AddApiKeys(keys []string) {
-	s.apiKeys = keys
+	s.keys = subscraping.NewStringKeyPool(keys)
 }
 
-func (s *Source) Statistics() subscraping.Statistics {
-	return subscraping.Statistics{
-		Errors:    s.errors,
-		Results:   s.results,
-		TimeTaken: s.timeTaken,
-		Skipped:   s.skipped,
-	}
+type quotasResponse struct {
+	Data struct {
+		APIRequestsDaily struct {
+			User struct {
+				Used    int `json:"used"`
+				Allowed int `json:"allowed"`
+			} `json:"user"`
+		} `json:"api_requests_daily"`
+	} `json:"data"`
+}
+
+// CheckKeys checks the keys against the quotas of their user, the remaining
+// credits are the API requests left for the day
+func (s *Source) CheckKeys(ctx context.Context, session *subscraping.Session) []subscraping.KeyCheck {
+	return subscraping.CheckKeys(s.keys, func(apiKey string) (*http.Response, error) {
+		return session.Get(ctx, fmt.Sprintf("https://www.virustotal.com/api/v3/users/%s/overall_quotas", apiKey), "", map[string]string{"x-apikey": apiKey})
+	}, func(body []byte) (int, error) {
+		var quotas quotasResponse
+		err := jsoniter.Unmarshal(body, &quotas)
+		return quotas.Data.APIRequestsDaily.User.Allowed - quotas.Data.APIRequestsDaily.User.Used, err
+	})
 }
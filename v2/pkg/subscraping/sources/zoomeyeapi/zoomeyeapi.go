@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
-	"time"
 
 	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
 )
@@ -23,53 +22,43 @@ type zoomeyeResults struct {
 
 // Source is the passive scraping agent
 type Source struct {
-	apiKeys   []string
-	timeTaken time.Duration
-	errors    int
-	results   int
-	skipped   bool
+	keys *subscraping.KeyPool[string]
 }
 
 // Run function returns all subdomains found with the service
 func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Session) <-chan subscraping.Result {
 	results := make(chan subscraping.Result)
-	s.errors = 0
-	s.results = 0
 
 	go func() {
-		defer func(startTime time.Time) {
-			s.timeTaken = time.Since(startTime)
-			close(results)
-		}(time.Now())
-
-		randomApiKey := subscraping.PickRandom(s.apiKeys, s.Name())
-		if randomApiKey == "" {
-			s.skipped = true
+		defer close(results)
+
+		randomApiKey, ok := subscraping.PickKey(s.keys, s.Name(), session, results)
+		if !ok {
 			return
 		}
 
 		randomApiInfo := strings.Split(randomApiKey, ":")
 		if len(randomApiInfo) != 2 {
-			s.skipped = true
+			session.Statistics.Skip(s.Name())
 			return
 		}
-		host := randomApiInfo[0]
-		apiKey := randomApiInfo[1]
 
-		headers := map[string]string{
-			"API-KEY":      apiKey,
-			"Accept":       "application/json",
-			"Content-Type": "application/json",
-		}
 		var pages = 1
 		for currentPage := 1; currentPage <= pages; currentPage++ {
-			api := fmt.Sprintf("https://api.%s/domain/search?q=%s&type=1&s=1000&page=%d", host, domain, currentPage)
-			resp, err := session.Get(ctx, api, "", headers)
+			resp, err := s.keys.Do(ctx, session, s.Name(), &randomApiKey, func(ctx context.Context, randomApiKey string) (*http.Response, error) {
+				host, apiKey, _ := strings.Cut(randomApiKey, ":")
+				headers := map[string]string{
+					"API-KEY":      apiKey,
+					"Accept":       "application/json",
+					"Content-Type": "application/json",
+				}
+				api := fmt.Sprintf("https://api.%s/domain/search?q=%s&type=1&s=1000&page=%d", host, domain, currentPage)
+				return session.Get(ctx, api, "", headers)
+			})
 			isForbidden := resp != nil && resp.StatusCode == http.StatusForbidden
 			if err != nil {
 				if !isForbidden {
 					results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-					s.errors++
 					session.DiscardHTTPResponse(resp)
 				}
 				return
@@ -80,7 +69,6 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 
 			if err != nil {
 				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Error, Error: err}
-				s.errors++
 				_ = resp.Body.Close()
 				return
 			}
@@ -88,7 +76,6 @@ func (s *Source) Run(ctx context.Context, domain string, session *subscraping.Se
 			pages = int(res.Total/1000) + 1
 			for _, r := range res.List {
 				results <- subscraping.Result{Source: s.Name(), Type: subscraping.Subdomain, Value: r.Name}
-				s.results++
 			}
 		}
 	}()
@@ -114,14 +101,24 @@ func (s *Source) NeedsKey() bool {
 }
 
 func (s *Source) AddApiKeys(keys []string) {
-	s.apiKeys = keys
+	s.keys = subscraping.NewStringKeyPool(keys)
+}
+
+type resourcesInfoResponse struct {
+	QuotaInfo struct {
+		RemainTotalQuota int `json:"remain_total_quota"`
+	} `json:"quota_info"`
 }
 
-func (s *Source) Statistics() subscraping.Statistics {
-	return subscraping.Statistics{
-		Errors:    s.errors,
-		Results:   s.results,
-		TimeTaken: s.timeTaken,
-		Skipped:   s.skipped,
-	}
+// CheckKeys checks the host:key pairs against the resources info endpoint of
+// their host
+func (s *Source) CheckKeys(ctx context.Context, session *subscraping.Session) []subscraping.KeyCheck {
+	return subscraping.CheckKeys(s.keys, func(randomApiKey string) (*http.Response, error) {
+		host, apiKey, _ := strings.Cut(randomApiKey, ":")
+		return session.Get(ctx, fmt.Sprintf("https://api.%s/resources-info", host), "", map[string]string{"API-KEY": apiKey})
+	}, func(body []byte) (int, error) {
+		var resources resourcesInfoResponse
+		err := json.Unmarshal(body, &resources)
+		return resources.QuotaInfo.RemainTotalQuota, err
+	})
 }
@@ -0,0 +1,122 @@
+package subscraping
+
+import (
+	"sync"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// RunStatistics collects the statistics of every source taking part
+// in a single enumeration run against a domain. It is safe for
+// concurrent use by the sources of the run.
+type RunStatistics struct {
+	Domain string
+
+	mutex   sync.Mutex
+	sources map[string]*Statistics
+}
+
+// NewRunStatistics creates an empty statistics collector for a domain
+func NewRunStatistics(domain string) *RunStatistics {
+	return &RunStatistics{
+		Domain:  domain,
+		sources: make(map[string]*Statistics),
+	}
+}
+
+// Update applies the given function to the statistics of a source
+func (r *RunStatistics) Update(source string, update func(stats *Statistics)) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	stats, ok := r.sources[source]
+	if !ok {
+		stats = &Statistics{}
+		r.sources[source] = stats
+	}
+	update(stats)
+}
+
+// Skip marks a source as skipped for the run
+func (r *RunStatistics) Skip(source string) {
+	r.Update(source, func(stats *Statistics) {
+		stats.Skipped = true
+	})
+}
+
+// Get returns a copy of the statistics of a source
+func (r *RunStatistics) Get(source string) (Statistics, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	stats, ok := r.sources[source]
+	if !ok {
+		return Statistics{}, false
+	}
+	return stats.clone(), true
+}
+
+// All returns a copy of the statistics of every source keyed by source name
+func (r *RunStatistics) All() map[string]Statistics {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	all := make(map[string]Statistics, len(r.sources))
+	for source, stats := range r.sources {
+		all[source] = stats.clone()
+	}
+	return all
+}
+
+// clone returns a copy of the statistics that doesn't share the key usage map nor the findings
+func (s *Statistics) clone() Statistics {
+	clone := *s
+	clone.Keys = maps.Clone(s.Keys)
+	clone.Findings = slices.Clone(s.Findings)
+	return clone
+}
+
+// MergeStatistics sums the statistics of several runs keyed by source name.
+// A source is skipped when it was skipped on every run, and the last reason
+// it was disabled, or the last budget it reached, is kept. The findings of
+// every run are kept.
+func MergeStatistics(runs ...map[string]Statistics) map[string]Statistics {
+	merged := make(map[string]Statistics)
+	for _, run := range runs {
+		for source, stats := range run {
+			total, ok := merged[source]
+			if !ok {
+				merged[source] = stats.clone()
+				continue
+			}
+			total.TimeTaken += stats.TimeTaken
+			total.Errors += stats.Errors
+			total.Results += stats.Results
+			total.Skipped = total.Skipped && stats.Skipped
+			total.Retries += stats.Retries
+			total.CacheHits += stats.CacheHits
+			total.CacheMisses += stats.CacheMisses
+			total.TimedOut = total.TimedOut || stats.TimedOut
+			if stats.Disabled != "" {
+				total.Disabled = stats.Disabled
+			}
+			if stats.Budget != "" {
+				total.Budget = stats.Budget
+			}
+			total.Findings = append(total.Findings, stats.Findings...)
+			for label, usage := range stats.Keys {
+				if total.Keys == nil {
+					total.Keys = make(map[string]KeyUsage)
+				}
+				totalUsage := total.Keys[label]
+				totalUsage.Requests += usage.Requests
+				totalUsage.Rejected += usage.Rejected
+				totalUsage.Exhausted = totalUsage.Exhausted || usage.Exhausted
+				total.Keys[label] = totalUsage
+			}
+			merged[source] = total
+		}
+	}
+	return merged
+}
@@ -0,0 +1,61 @@
+package subscraping
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunStatistics(t *testing.T) {
+	stats := NewRunStatistics("example.com")
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stats.Update("crtsh", func(stats *Statistics) {
+				stats.Results++
+			})
+		}()
+	}
+	wg.Wait()
+	stats.Skip("github")
+
+	crtsh, ok := stats.Get("crtsh")
+	require.True(t, ok)
+	require.Equal(t, 50, crtsh.Results)
+
+	all := stats.All()
+	require.Len(t, all, 2)
+	require.True(t, all["github"].Skipped)
+
+	_, ok = stats.Get("shodan")
+	require.False(t, ok)
+}
+
+func TestMergeStatistics(t *testing.T) {
+	merged := MergeStatistics(
+		map[string]Statistics{
+			"crtsh":  {Results: 2, Errors: 1, Keys: map[string]KeyUsage{"****0001": {Requests: 1}}},
+			"github": {Skipped: true},
+			"shodan": {Skipped: true},
+			"axfr":   {Findings: []string{"nameserver ns1.example.com allowed a zone transfer (AXFR) of example.com"}},
+		},
+		map[string]Statistics{
+			"crtsh":  {Results: 3, Disabled: "3 consecutive failures", Keys: map[string]KeyUsage{"****0001": {Requests: 2, Rejected: 1, Exhausted: true}}},
+			"github": {Results: 1},
+			"shodan": {Skipped: true},
+			"axfr":   {Findings: []string{"nameserver ns1.example.org allowed a zone transfer (AXFR) of example.org"}},
+		},
+	)
+
+	require.Equal(t, 5, merged["crtsh"].Results)
+	require.Equal(t, 1, merged["crtsh"].Errors)
+	require.Equal(t, "3 consecutive failures", merged["crtsh"].Disabled)
+	require.Equal(t, KeyUsage{Requests: 3, Rejected: 1, Exhausted: true}, merged["crtsh"].Keys["****0001"])
+	require.False(t, merged["github"].Skipped, "a source run on one domain isn't skipped")
+	require.True(t, merged["shodan"].Skipped)
+	require.Len(t, merged["axfr"].Findings, 2)
+}
@@ -2,10 +2,12 @@ package subscraping
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/projectdiscovery/ratelimit"
+	"github.com/projectdiscovery/subfinder/v2/pkg/resolve"
 	mapsutil "github.com/projectdiscovery/utils/maps"
 )
 
@@ -13,6 +15,8 @@ type CtxArg string
 
 const (
 	CtxSourceArg CtxArg = "source"
+	// ctxKeyArg holds the provider config entry of the key a request is sent with
+	ctxKeyArg CtxArg = "key"
 )
 
 type CustomRateLimit struct {
@@ -25,12 +29,41 @@ type BasicAuth struct {
 	Password string
 }
 
+// StatusCodeError is returned when a request receives an unexpected status code
+type StatusCodeError struct {
+	StatusCode int
+	URL        string
+}
+
+func (e *StatusCodeError) Error() string {
+	return fmt.Sprintf("unexpected status code %d received from %s", e.StatusCode, e.URL)
+}
+
 // Statistics contains statistics about the scraping process
 type Statistics struct {
 	TimeTaken time.Duration
 	Errors    int
 	Results   int
 	Skipped   bool
+	// Retries is the number of retried requests
+	Retries int
+	// CacheHits and CacheMisses count the requests answered, or not, by the response cache
+	CacheHits   int
+	CacheMisses int
+	// Disabled holds the reason the source was disabled for the remaining
+	// domains, it is only set for the domain the source was disabled on
+	Disabled string
+	// TimedOut is set when the source was cancelled by its own deadline,
+	// its results are then partial
+	TimedOut bool
+	// Keys holds the usage of the API keys of the source by masked key
+	Keys map[string]KeyUsage
+	// Budget holds the request budget the source reached, its results
+	// are then partial or, when it was skipped, missing
+	Budget string
+	// Findings holds what the source found about the target besides its
+	// subdomains, such as the nameservers allowing a zone transfer
+	Findings []string
 }
 
 // Source is an interface inherited by each passive source
@@ -56,9 +89,16 @@ type Source interface {
 	NeedsKey() bool
 
 	AddApiKeys([]string)
+}
+
+// ActiveSource is a source sending DNS queries to the infrastructure of the
+// target, such as its nameservers. Active sources are opt-in: they are only
+// used when selected by name, never as part of all the sources.
+type ActiveSource interface {
+	Source
 
-	// Statistics returns the scrapping statistics for the source
-	Statistics() Statistics
+	// SetResolver sets the resolver the source sends its DNS queries with
+	SetResolver(*resolve.Resolver)
 }
 
 // SubdomainExtractor is an interface that defines the contract for subdomain extraction.
@@ -75,6 +115,18 @@ type Session struct {
 	Client *http.Client
 	// Rate limit instance
 	MultiRateLimiter *ratelimit.MultiLimiter
+	// RetryPolicy applies to the sources without their own policy
+	RetryPolicy RetryPolicy
+	// SourceRetryPolicies holds the retry policies set per source name
+	SourceRetryPolicies map[string]RetryPolicy
+	// Cache stores the responses of the sources on disk, it is optional
+	Cache *ResponseCache
+	// Cassette records or replays the HTTP interactions of the sources, it is optional
+	Cassette *Cassette
+	// Budget caps the requests of the sources and of their keys, it is optional
+	Budget *Budgets
+	// Statistics of the enumeration run the session belongs to
+	Statistics *RunStatistics
 }
 
 // Result is a result structure returned by a source
@@ -40,3 +40,69 @@ func createMultiPartKey(key string) (keyPartA, keyPartB string, ok bool) {
 
 	return
 }
+
+// InstanceKeys are the tokens of one instance of a source able to query
+// self-hosted instances
+type InstanceKeys struct {
+	// BaseURL is the URL of the instance, empty for the public one
+	BaseURL string
+	// Keys holds the tokens, with their provider config entry
+	Keys *KeyPool[string]
+}
+
+// CreateInstanceKeys groups the keys of a source by instance, in the order of
+// their first key. A key is either a token of the public instance or the base
+// URL of a self-hosted instance followed by a colon and a token, such as
+// https://git.example.com:token. Keys of an instance without token are ignored.
+func CreateInstanceKeys(keys []string) []InstanceKeys {
+	var baseURLs []string
+	tokens := make(map[string][]string)
+	entries := make(map[string]string)
+	for _, key := range keys {
+		var baseURL, token string
+		if scheme := strings.Index(key, "://"); scheme >= 0 {
+			separator := strings.LastIndex(key, ":")
+			if separator == scheme || isPort(key[scheme+3:]) {
+				gologger.Debug().Msgf("Ignoring the key of %s without token", key)
+				continue
+			}
+			baseURL, token = strings.TrimRight(key[:separator], "/"), key[separator+1:]
+		} else {
+			token = key
+		}
+		if token == "" {
+			continue
+		}
+		if _, ok := tokens[baseURL]; !ok {
+			baseURLs = append(baseURLs, baseURL)
+		}
+		tokens[baseURL] = append(tokens[baseURL], token)
+		entries[baseURL+"\x00"+token] = key
+	}
+
+	instances := make([]InstanceKeys, 0, len(baseURLs))
+	for _, baseURL := range baseURLs {
+		baseURL := baseURL
+		instances = append(instances, InstanceKeys{
+			BaseURL: baseURL,
+			Keys: NewKeyPool(tokens[baseURL], func(token string) string {
+				return entries[baseURL+"\x00"+token]
+			}),
+		})
+	}
+	return instances
+}
+
+// isPort tells whether a host ends with a port rather than a token
+func isPort(host string) bool {
+	_, port, ok := strings.Cut(host, ":")
+	if !ok || port == "" || strings.ContainsAny(host, "/") {
+		return false
+	}
+	for _, r := range port {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
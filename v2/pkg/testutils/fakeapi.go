@@ -0,0 +1,139 @@
+package testutils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+)
+
+// FakeAPI is a local server imitating the APIs of the sources. Requests sent
+// through its Transport are served by the handler registered for their
+// original host and path, so the hard-coded endpoints of the sources can be
+// exercised without network access.
+type FakeAPI struct {
+	server *httptest.Server
+
+	mutex     sync.Mutex
+	routes    map[string]http.Handler
+	hits      map[string]int
+	unmatched []string
+}
+
+// NewFakeAPI starts a fake API server, it must be closed by the caller
+func NewFakeAPI() *FakeAPI {
+	fake := &FakeAPI{routes: make(map[string]http.Handler), hits: make(map[string]int)}
+	fake.server = httptest.NewServer(http.HandlerFunc(fake.serve))
+	return fake
+}
+
+// Close shuts down the server
+func (f *FakeAPI) Close() {
+	f.server.Close()
+}
+
+// Handle registers the handler of an endpoint given as host and path (api.shodan.io/dns/domain/example.com)
+func (f *FakeAPI) Handle(endpoint string, handler http.Handler) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.routes[endpoint] = handler
+}
+
+// Hits returns the number of requests served for an endpoint
+func (f *FakeAPI) Hits(endpoint string) int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.hits[endpoint]
+}
+
+// Unmatched returns the sorted endpoints requested without a registered handler
+func (f *FakeAPI) Unmatched() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	unmatched := append([]string(nil), f.unmatched...)
+	sort.Strings(unmatched)
+	return unmatched
+}
+
+// Transport returns a round tripper sending every request to the fake server
+func (f *FakeAPI) Transport() http.RoundTripper {
+	return roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+		routed := request.Clone(request.Context())
+		routed.URL.Scheme = "http"
+		routed.URL.Host = f.server.Listener.Addr().String()
+		routed.Host = request.URL.Host
+		return http.DefaultTransport.RoundTrip(routed)
+	})
+}
+
+func (f *FakeAPI) serve(w http.ResponseWriter, r *http.Request) {
+	endpoint := r.Host + r.URL.Path
+
+	f.mutex.Lock()
+	handler, ok := f.routes[endpoint]
+	if ok {
+		f.hits[endpoint]++
+	} else {
+		f.unmatched = append(f.unmatched, endpoint)
+	}
+	f.mutex.Unlock()
+
+	if !ok {
+		http.Error(w, `{"error":"no fake for `+endpoint+`"}`, http.StatusInternalServerError)
+		return
+	}
+	handler.ServeHTTP(w, r)
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
+
+// JSON responds with a JSON body and status code
+func JSON(status int, body string) http.Handler {
+	return Body(status, "application/json", body)
+}
+
+// Body responds with a body of the given content type and status code
+func Body(status int, contentType, body string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	})
+}
+
+// RateLimited responds once with 429 Too Many Requests and a Retry-After
+// header of the given seconds before handing the requests to next
+func RateLimited(retryAfter string, next http.Handler) http.Handler {
+	var once sync.Once
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limited := false
+		once.Do(func() { limited = true })
+		if limited {
+			w.Header().Set("Retry-After", retryAfter)
+			http.Error(w, `{"error":"rate limit exceeded"}`, http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Pages serves the handlers in order, one per request, repeating the last one
+func Pages(handlers ...http.Handler) http.Handler {
+	var mutex sync.Mutex
+	var served int
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		handler := handlers[min(served, len(handlers)-1)]
+		served++
+		mutex.Unlock()
+
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,45 @@
+package testutils
+
+import (
+	"context"
+	"math"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/projectdiscovery/ratelimit"
+	"github.com/stretchr/testify/require"
+
+	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
+)
+
+// RunSource runs a source for a domain without rate limit and returns the
+// sorted subdomains and the errors it sent
+func RunSource(ctx context.Context, t testing.TB, source subscraping.Source, domain string) ([]string, []error) {
+	t.Helper()
+
+	multiRateLimiter, err := ratelimit.NewMultiLimiter(context.Background(), &ratelimit.Options{
+		Key:         source.Name(),
+		IsUnlimited: true,
+		MaxCount:    math.MaxUint32,
+		Duration:    time.Millisecond,
+	})
+	require.NoError(t, err)
+	session, err := subscraping.NewSession(domain, "", multiRateLimiter, 10)
+	require.NoError(t, err)
+	defer session.Close()
+
+	ctx = context.WithValue(ctx, subscraping.CtxSourceArg, source.Name())
+	var subdomains []string
+	var errs []error
+	for result := range source.Run(ctx, domain, session) {
+		switch result.Type {
+		case subscraping.Subdomain:
+			subdomains = append(subdomains, result.Value)
+		case subscraping.Error:
+			errs = append(errs, result.Error)
+		}
+	}
+	sort.Strings(subdomains)
+	return subdomains, errs
+}